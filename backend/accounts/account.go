@@ -40,12 +40,19 @@ type Interface interface {
 	// Initialize only starts the initialization, the account is not initialized right afterwards.
 	Initialize() error
 	Initialized() bool
+	// SyncProgress returns the progress of the current sync between 0 and 1. It is 1 if the
+	// account is not syncing.
+	SyncProgress() float64
 	Offline() bool
 	FatalError() bool
+	// Refresh syncs the account right away, e.g. to show a just sent transaction quickly.
+	Refresh() error
 	Close()
 	Notifier() Notifier
 	Transactions() ([]Transaction, error)
 	Balance() (*Balance, error)
+	// BalanceBreakdown returns the spendable, incoming and frozen funds of the account.
+	BalanceBreakdown() (*BalanceBreakdown, error)
 	// Creates, signs and broadcasts a transaction. Returns keystore.ErrSigningAborted on user
 	// abort.
 	SendTx(string, coin.SendAmount, FeeTargetCode, map[wire.OutPoint]struct{}, []byte) error
@@ -55,6 +62,9 @@ type Interface interface {
 	GetUnusedReceiveAddresses() []Address
 	CanVerifyAddresses() (bool, bool, error)
 	VerifyAddress(addressID string) (bool, error)
+	// AddressUsed returns true if the receive address with the given ID already appeared in
+	// transactions, so that the user can be warned about address reuse.
+	AddressUsed(addressID string) (bool, error)
 	Keystores() *keystore.Keystores
 	RateUpdater() *rates.RateUpdater
 
@@ -69,3 +79,14 @@ type Interface interface {
 type Info struct {
 	SigningConfiguration *signing.Configuration `json:"signingConfiguration"`
 }
+
+// AddressInfo describes how an address of an account is derived.
+type AddressInfo struct {
+	// ScriptType is empty for account based coins like Ethereum.
+	ScriptType signing.ScriptType `json:"scriptType"`
+	// RelativeKeypath is the keypath of the address relative to the account keypath.
+	RelativeKeypath string `json:"relativeKeypath"`
+	// Keypath is the full keypath of the address.
+	Keypath string `json:"keypath"`
+	Change  bool   `json:"change"`
+}
@@ -14,7 +14,11 @@
 
 package accounts
 
-import "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+import (
+	"math/big"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+)
 
 // Balance contains the available and incoming balance of an account.
 type Balance struct {
@@ -40,3 +44,31 @@ func (balance *Balance) Available() coin.Amount {
 func (balance *Balance) Incoming() coin.Amount {
 	return balance.incoming
 }
+
+// BalanceBreakdown splits the funds of an account into what can be spent right away, what is still
+// incoming and what the user froze. Total is the sum of all three.
+type BalanceBreakdown struct {
+	// Total is the sum of all funds of the account. The amounts of unconfirmed outgoing
+	// transfers are not included.
+	Total coin.Amount
+	// Spendable is the amount which can be spent right away.
+	Spendable coin.Amount
+	// Incoming is the sum of all unconfirmed transfers coming into the account.
+	Incoming coin.Amount
+	// Frozen is the sum of all outputs the user does not want to spend. Always zero for coins
+	// which are not UTXO-based.
+	Frozen coin.Amount
+}
+
+// NewBalanceBreakdown creates a new balance breakdown with the given amounts. The total is computed
+// from the given amounts.
+func NewBalanceBreakdown(spendable, incoming, frozen coin.Amount) *BalanceBreakdown {
+	total := new(big.Int).Add(spendable.BigInt(), incoming.BigInt())
+	total.Add(total, frozen.BigInt())
+	return &BalanceBreakdown{
+		Total:     coin.NewAmount(total),
+		Spendable: spendable,
+		Incoming:  incoming,
+		Frozen:    frozen,
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package accounts
+
+import (
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// Interval is the time between two points of a balance history.
+type Interval string
+
+const (
+	// IntervalDay is one point per day.
+	IntervalDay Interval = "day"
+	// IntervalWeek is one point per week.
+	IntervalWeek Interval = "week"
+	// IntervalMonth is one point per calendar month.
+	IntervalMonth Interval = "month"
+)
+
+// next returns the time one interval after t.
+func (interval Interval) next(t time.Time) time.Time {
+	switch interval {
+	case IntervalWeek:
+		return t.AddDate(0, 0, 7)
+	case IntervalMonth:
+		return t.AddDate(0, 1, 0)
+	default:
+		return t.AddDate(0, 0, 1)
+	}
+}
+
+// BalancePoint is the balance of an account at a point in time.
+type BalancePoint struct {
+	Time    time.Time
+	Balance coin.Amount
+}
+
+// balanceChange returns by how much the transaction changes the balance of the account. Fees are
+// only deducted if includeFees is true, e.g. not for ERC20 tokens, whose fees are paid in ether.
+func balanceChange(transaction Transaction, includeFees bool) *big.Int {
+	change := new(big.Int)
+	if transaction.Status() != TxStatusFailed {
+		switch transaction.Type() {
+		case TxTypeReceive:
+			change.Add(change, transaction.Amount().BigInt())
+		case TxTypeSend:
+			change.Sub(change, transaction.Amount().BigInt())
+		}
+	}
+	if includeFees && transaction.Type() != TxTypeReceive && transaction.Fee() != nil {
+		change.Sub(change, transaction.Fee().BigInt())
+	}
+	return change
+}
+
+// BalanceHistory reconstructs the balance of an account from its transactions, with one point per
+// interval starting at the day (UTC) of the first confirmed transaction, and a last point at
+// `until`. Intervals without transactions carry the previous balance forward. Unconfirmed
+// transactions are not included. An error is returned if the time of a confirmed transaction is not
+// known yet, e.g. while the headers are still syncing, as the history would be incomplete.
+func BalanceHistory(
+	transactions []Transaction,
+	interval Interval,
+	until time.Time,
+	includeFees bool,
+) ([]BalancePoint, error) {
+	switch interval {
+	case IntervalDay, IntervalWeek, IntervalMonth:
+	default:
+		return nil, errp.Newf("unknown interval %s", interval)
+	}
+	confirmed := []Transaction{}
+	for _, transaction := range transactions {
+		if transaction.NumConfirmations() == 0 {
+			continue
+		}
+		if transaction.Timestamp() == nil {
+			return nil, errp.Newf("the time of transaction %s is not known yet", transaction.TxID())
+		}
+		confirmed = append(confirmed, transaction)
+	}
+	result := []BalancePoint{}
+	if len(confirmed) == 0 {
+		return result, nil
+	}
+	sort.SliceStable(confirmed, func(i, j int) bool {
+		return confirmed[i].Timestamp().Before(*confirmed[j].Timestamp())
+	})
+
+	balance := new(big.Int)
+	index := 0
+	// addUntil adds all transactions up to and including the given time to the balance.
+	addUntil := func(t time.Time) {
+		for ; index < len(confirmed) && !confirmed[index].Timestamp().After(t); index++ {
+			balance.Add(balance, balanceChange(confirmed[index], includeFees))
+		}
+	}
+	first := confirmed[0].Timestamp().UTC()
+	pointTime := time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, time.UTC)
+	for pointTime.Before(until) {
+		addUntil(pointTime)
+		result = append(result, BalancePoint{Time: pointTime, Balance: coin.NewAmount(new(big.Int).Set(balance))})
+		pointTime = interval.next(pointTime)
+	}
+	addUntil(until)
+	result = append(result, BalancePoint{Time: until, Balance: coin.NewAmount(new(big.Int).Set(balance))})
+	return result, nil
+}
@@ -0,0 +1,125 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package accounts_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/stretchr/testify/require"
+)
+
+type historyTransaction struct {
+	accounts.Transaction
+	txID             string
+	timestamp        *time.Time
+	numConfirmations int
+	txType           accounts.TxType
+	status           accounts.TxStatus
+	amount           int64
+	fee              *coin.Amount
+}
+
+func (tx *historyTransaction) TxID() string              { return tx.txID }
+func (tx *historyTransaction) Timestamp() *time.Time     { return tx.timestamp }
+func (tx *historyTransaction) NumConfirmations() int     { return tx.numConfirmations }
+func (tx *historyTransaction) Type() accounts.TxType     { return tx.txType }
+func (tx *historyTransaction) Status() accounts.TxStatus { return tx.status }
+func (tx *historyTransaction) Amount() coin.Amount       { return coin.NewAmountFromInt64(tx.amount) }
+func (tx *historyTransaction) Fee() *coin.Amount         { return tx.fee }
+
+func newHistoryTransaction(
+	t time.Time, txType accounts.TxType, amount int64, fee int64) *historyTransaction {
+	tx := &historyTransaction{
+		timestamp:        &t,
+		numConfirmations: 1,
+		txType:           txType,
+		status:           accounts.TxStatusComplete,
+		amount:           amount,
+	}
+	if txType != accounts.TxTypeReceive {
+		feeAmount := coin.NewAmountFromInt64(fee)
+		tx.fee = &feeAmount
+	}
+	return tx
+}
+
+func balances(points []accounts.BalancePoint) []int64 {
+	result := []int64{}
+	for _, point := range points {
+		balance, err := point.Balance.Int64()
+		if err != nil {
+			panic(err)
+		}
+		result = append(result, balance)
+	}
+	return result
+}
+
+func TestBalanceHistory(t *testing.T) {
+	day := func(day int, hour int) time.Time {
+		return time.Date(2020, 3, day, hour, 0, 0, 0, time.UTC)
+	}
+	failed := newHistoryTransaction(day(4, 12), accounts.TxTypeSend, 5000, 10)
+	failed.status = accounts.TxStatusFailed
+	unconfirmed := newHistoryTransaction(day(5, 12), accounts.TxTypeReceive, 7000, 0)
+	unconfirmed.timestamp = nil
+	unconfirmed.numConfirmations = 0
+	txs := []accounts.Transaction{
+		// Not sorted by time.
+		newHistoryTransaction(day(3, 8), accounts.TxTypeSend, 300, 20),
+		newHistoryTransaction(day(1, 10), accounts.TxTypeReceive, 1000, 0),
+		newHistoryTransaction(day(3, 9), accounts.TxTypeSendSelf, 100, 30),
+		failed,
+		unconfirmed,
+	}
+
+	history, err := accounts.BalanceHistory(txs, accounts.IntervalDay, day(5, 18), true)
+	require.NoError(t, err)
+	require.Len(t, history, 6)
+	for index, point := range history[:5] {
+		require.Equal(t, day(1+index, 0), point.Time)
+	}
+	require.Equal(t, day(5, 18), history[5].Time)
+	// Day 2 carries the balance of day 1 forward.
+	require.Equal(t, []int64{0, 1000, 1000, 650, 640, 640}, balances(history))
+
+	// Without fees, e.g. for ERC20 tokens.
+	history, err = accounts.BalanceHistory(txs, accounts.IntervalDay, day(5, 18), false)
+	require.NoError(t, err)
+	require.Equal(t, []int64{0, 1000, 1000, 700, 700, 700}, balances(history))
+
+	history, err = accounts.BalanceHistory(txs, accounts.IntervalWeek, day(20, 0), true)
+	require.NoError(t, err)
+	require.Equal(t, []time.Time{day(1, 0), day(8, 0), day(15, 0), day(20, 0)},
+		[]time.Time{history[0].Time, history[1].Time, history[2].Time, history[3].Time})
+	require.Equal(t, []int64{0, 640, 640, 640}, balances(history))
+
+	history, err = accounts.BalanceHistory(nil, accounts.IntervalMonth, day(20, 0), true)
+	require.NoError(t, err)
+	require.Empty(t, history)
+
+	_, err = accounts.BalanceHistory(txs, accounts.Interval("year"), day(20, 0), true)
+	require.Error(t, err)
+
+	// Confirmed, but the headers are not synced yet.
+	missingTimestamp := newHistoryTransaction(day(2, 12), accounts.TxTypeReceive, 100, 0)
+	missingTimestamp.timestamp = nil
+	_, err = accounts.BalanceHistory(
+		append(txs, missingTimestamp), accounts.IntervalDay, day(5, 18), true)
+	require.Error(t, err)
+}
@@ -0,0 +1,176 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package accounts
+
+import (
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// CostBasisMethod determines which received coins are considered spent first.
+type CostBasisMethod string
+
+const (
+	// CostBasisFIFO spends the coins received first (first in, first out).
+	CostBasisFIFO CostBasisMethod = "fifo"
+	// CostBasisLIFO spends the coins received last (last in, first out).
+	CostBasisLIFO CostBasisMethod = "lifo"
+)
+
+// Disposal is an outgoing transaction with its realized gain. All fiat values are in the currency
+// of the report.
+type Disposal struct {
+	TxID string
+	Time time.Time
+	// Amount is what left the account, including the fee.
+	Amount coin.Amount
+	// Proceeds is the fiat value of the amount sent, excluding the fee.
+	Proceeds float64
+	// CostBasis is the fiat value of the spent coins at the time they were received.
+	CostBasis float64
+	// Gain is Proceeds minus CostBasis. Negative for a loss.
+	Gain float64
+}
+
+// CostBasisReport lists the realized gains of an account.
+type CostBasisReport struct {
+	Method    CostBasisMethod
+	Disposals []*Disposal
+	Proceeds  float64
+	CostBasis float64
+	Gain      float64
+}
+
+// lot is an amount of received coins which has not been spent yet, with its fiat cost.
+type lot struct {
+	amount *big.Int
+	cost   float64
+}
+
+// CostBasis computes the realized gains of an account from its transactions, matching spent coins
+// to received coins with the given method. toFiat returns the fiat value of an amount at the given
+// time.
+//
+// Fees are spent together with the amount they are paid for, so they add to the cost basis without
+// adding to the proceeds. The fees of self transfers and failed transactions are disposals without
+// proceeds. Fees are only considered if includeFees is true, e.g. not for ERC20 tokens, whose fees
+// are paid in ether. Unconfirmed transactions are not included. An error is returned if the time of
+// a confirmed transaction is not known yet, e.g. while the headers are still syncing. Coins spent
+// without a matching receive, e.g. if the history is incomplete, have a cost basis of zero.
+func CostBasis(
+	transactions []Transaction,
+	method CostBasisMethod,
+	includeFees bool,
+	toFiat func(amount coin.Amount, t time.Time) (float64, error),
+) (*CostBasisReport, error) {
+	switch method {
+	case CostBasisFIFO, CostBasisLIFO:
+	default:
+		return nil, errp.Newf("unknown cost basis method %s", method)
+	}
+	confirmed := []Transaction{}
+	for _, transaction := range transactions {
+		if transaction.NumConfirmations() == 0 {
+			continue
+		}
+		if transaction.Timestamp() == nil {
+			return nil, errp.Newf("the time of transaction %s is not known yet", transaction.TxID())
+		}
+		confirmed = append(confirmed, transaction)
+	}
+	sort.SliceStable(confirmed, func(i, j int) bool {
+		return confirmed[i].Timestamp().Before(*confirmed[j].Timestamp())
+	})
+
+	lots := []*lot{}
+	// spend removes the amount from the lots and returns its cost.
+	spend := func(amount *big.Int) float64 {
+		remaining := new(big.Int).Set(amount)
+		cost := 0.0
+		for remaining.Sign() > 0 && len(lots) > 0 {
+			index := 0
+			if method == CostBasisLIFO {
+				index = len(lots) - 1
+			}
+			current := lots[index]
+			if current.amount.Cmp(remaining) <= 0 {
+				cost += current.cost
+				remaining.Sub(remaining, current.amount)
+				lots = append(lots[:index], lots[index+1:]...)
+				continue
+			}
+			fraction, _ := new(big.Rat).SetFrac(remaining, current.amount).Float64()
+			partialCost := current.cost * fraction
+			cost += partialCost
+			current.cost -= partialCost
+			current.amount.Sub(current.amount, remaining)
+			remaining.SetInt64(0)
+		}
+		return cost
+	}
+
+	report := &CostBasisReport{Method: method, Disposals: []*Disposal{}}
+	for _, transaction := range confirmed {
+		timestamp := *transaction.Timestamp()
+		failed := transaction.Status() == TxStatusFailed
+		if transaction.Type() == TxTypeReceive {
+			if failed {
+				continue
+			}
+			cost, err := toFiat(transaction.Amount(), timestamp)
+			if err != nil {
+				return nil, err
+			}
+			lots = append(lots, &lot{amount: new(big.Int).Set(transaction.Amount().BigInt()), cost: cost})
+			continue
+		}
+		sent := new(big.Int)
+		if transaction.Type() == TxTypeSend && !failed {
+			sent.Set(transaction.Amount().BigInt())
+		}
+		spent := new(big.Int).Set(sent)
+		if includeFees && transaction.Fee() != nil {
+			spent.Add(spent, transaction.Fee().BigInt())
+		}
+		if spent.Sign() == 0 {
+			continue
+		}
+		proceeds := 0.0
+		if sent.Sign() > 0 {
+			var err error
+			proceeds, err = toFiat(coin.NewAmount(sent), timestamp)
+			if err != nil {
+				return nil, err
+			}
+		}
+		costBasis := spend(spent)
+		report.Disposals = append(report.Disposals, &Disposal{
+			TxID:      transaction.TxID(),
+			Time:      timestamp,
+			Amount:    coin.NewAmount(spent),
+			Proceeds:  proceeds,
+			CostBasis: costBasis,
+			Gain:      proceeds - costBasis,
+		})
+		report.Proceeds += proceeds
+		report.CostBasis += costBasis
+		report.Gain += proceeds - costBasis
+	}
+	return report, nil
+}
@@ -0,0 +1,127 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package accounts_test
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/stretchr/testify/require"
+)
+
+type costBasisTransaction struct {
+	*historyTransaction
+	txID             string
+	numConfirmations int
+}
+
+func newCostBasisTransaction(tx *historyTransaction, txID string) *costBasisTransaction {
+	return &costBasisTransaction{historyTransaction: tx, txID: txID, numConfirmations: 1}
+}
+
+func (tx *costBasisTransaction) TxID() string          { return tx.txID }
+func (tx *costBasisTransaction) NumConfirmations() int { return tx.numConfirmations }
+
+func TestCostBasis(t *testing.T) {
+	day := func(day int) time.Time {
+		return time.Date(2020, 3, day, 12, 0, 0, 0, time.UTC)
+	}
+	// The price of one unit on each day.
+	prices := map[int]float64{1: 10, 2: 20, 3: 30, 4: 40}
+	toFiat := func(amount coin.Amount, t time.Time) (float64, error) {
+		return float64(amount.BigInt().Int64()) * prices[t.Day()], nil
+	}
+	unconfirmed := newCostBasisTransaction(
+		newHistoryTransaction(day(2), accounts.TxTypeReceive, 1000, 0), "unconfirmed")
+	unconfirmed.timestamp = nil
+	unconfirmed.numConfirmations = 0
+	txs := []accounts.Transaction{
+		newCostBasisTransaction(newHistoryTransaction(day(3), accounts.TxTypeSend, 150, 10), "sell"),
+		newCostBasisTransaction(newHistoryTransaction(day(1), accounts.TxTypeReceive, 100, 0), "buy1"),
+		newCostBasisTransaction(newHistoryTransaction(day(2), accounts.TxTypeReceive, 100, 0), "buy2"),
+		newCostBasisTransaction(newHistoryTransaction(day(4), accounts.TxTypeSendSelf, 30, 5), "self"),
+		unconfirmed,
+	}
+
+	// FIFO: the sale spends buy1 and 60 of buy2. The fee of the self transfer spends 5 of buy2.
+	report, err := accounts.CostBasis(txs, accounts.CostBasisFIFO, true, toFiat)
+	require.NoError(t, err)
+	require.Len(t, report.Disposals, 2)
+	sale := report.Disposals[0]
+	require.Equal(t, "sell", sale.TxID)
+	require.Equal(t, day(3), sale.Time)
+	require.Equal(t, big.NewInt(160), sale.Amount.BigInt())
+	require.InDelta(t, 4500, sale.Proceeds, 1e-9)
+	require.InDelta(t, 2200, sale.CostBasis, 1e-9)
+	require.InDelta(t, 2300, sale.Gain, 1e-9)
+	selfTransfer := report.Disposals[1]
+	require.Equal(t, "self", selfTransfer.TxID)
+	require.Equal(t, big.NewInt(5), selfTransfer.Amount.BigInt())
+	require.InDelta(t, 0, selfTransfer.Proceeds, 1e-9)
+	require.InDelta(t, -100, selfTransfer.Gain, 1e-9)
+	require.InDelta(t, 4500, report.Proceeds, 1e-9)
+	require.InDelta(t, 2300, report.CostBasis, 1e-9)
+	require.InDelta(t, 2200, report.Gain, 1e-9)
+
+	// LIFO: the sale spends buy2 and 60 of buy1. The fee of the self transfer spends 5 of buy1.
+	report, err = accounts.CostBasis(txs, accounts.CostBasisLIFO, true, toFiat)
+	require.NoError(t, err)
+	require.Len(t, report.Disposals, 2)
+	require.InDelta(t, 2600, report.Disposals[0].CostBasis, 1e-9)
+	require.InDelta(t, 50, report.Disposals[1].CostBasis, 1e-9)
+	require.InDelta(t, 1850, report.Gain, 1e-9)
+
+	// Without fees, e.g. for ERC20 tokens, the self transfer does not spend anything.
+	report, err = accounts.CostBasis(txs, accounts.CostBasisFIFO, false, toFiat)
+	require.NoError(t, err)
+	require.Len(t, report.Disposals, 1)
+	require.InDelta(t, 2000, report.Disposals[0].CostBasis, 1e-9)
+	require.InDelta(t, 2500, report.Gain, 1e-9)
+
+	_, err = accounts.CostBasis(txs, "average", true, toFiat)
+	require.Error(t, err)
+}
+
+func TestCostBasisIncompleteHistory(t *testing.T) {
+	toFiat := func(amount coin.Amount, _ time.Time) (float64, error) {
+		return float64(amount.BigInt().Int64()), nil
+	}
+	timestamp := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	txs := []accounts.Transaction{
+		newCostBasisTransaction(newHistoryTransaction(timestamp, accounts.TxTypeReceive, 100, 0), "buy"),
+		newCostBasisTransaction(
+			newHistoryTransaction(timestamp.Add(time.Hour), accounts.TxTypeSend, 300, 0), "sell"),
+	}
+	// Coins without a matching receive have no cost.
+	report, err := accounts.CostBasis(txs, accounts.CostBasisFIFO, true, toFiat)
+	require.NoError(t, err)
+	require.InDelta(t, 100, report.CostBasis, 1e-9)
+	require.InDelta(t, 200, report.Gain, 1e-9)
+}
+
+func TestCostBasisMissingTimestamp(t *testing.T) {
+	toFiat := func(amount coin.Amount, _ time.Time) (float64, error) {
+		return float64(amount.BigInt().Int64()), nil
+	}
+	// Confirmed, but the headers are not synced yet.
+	confirmed := newHistoryTransaction(time.Time{}, accounts.TxTypeReceive, 100, 0)
+	confirmed.timestamp = nil
+	txs := []accounts.Transaction{newCostBasisTransaction(confirmed, "buy")}
+	_, err := accounts.CostBasis(txs, accounts.CostBasisFIFO, true, toFiat)
+	require.Error(t, err)
+}
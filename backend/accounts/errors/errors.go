@@ -25,6 +25,9 @@ var (
 	// ErrInvalidAddress is used when the recipient address is invalid or does not match the correct
 	// network.
 	ErrInvalidAddress = TxValidationError("invalidAddress")
+	// ErrMWEBNotSupported is used when the recipient address is a valid Litecoin MWEB address,
+	// which can't be sent to yet.
+	ErrMWEBNotSupported = TxValidationError("mwebNotSupported")
 	// ErrInvalidAmount is used when the user entered amount is malformatted or not positive.
 	ErrInvalidAmount = TxValidationError("invalidAmount")
 	// ErrInvalidData is used when the user entered data is not hexadecimal.
@@ -32,4 +35,7 @@ var (
 	// ErrInsufficientFunds is returned when there are not enough funds to cover the target amount
 	// and fee.
 	ErrInsufficientFunds = TxValidationError("insufficientFunds")
+	// ErrReplacementUnderpriced is returned when the fee of a replacement tx is not raised enough
+	// over the fee of the tx it replaces for nodes to accept it.
+	ErrReplacementUnderpriced = TxValidationError("replacementUnderpriced")
 )
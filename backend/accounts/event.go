@@ -29,6 +29,10 @@ const (
 	// EventSyncDone follows EventSyncStarted.
 	EventSyncDone Event = "syncdone"
 
+	// EventSyncProgress is fired when the sync progress changes. Check the progress using
+	// SyncProgress().
+	EventSyncProgress Event = "syncProgress"
+
 	// EventHeadersSynced is fired when the headers finished syncing.
 	EventHeadersSynced Event = "headersSynced"
 
@@ -54,6 +54,17 @@ type AddressAndAmount struct {
 	Ours bool
 }
 
+// TransactionInput is an input of a transaction of a UTXO based coin.
+type TransactionInput struct {
+	// PreviousOutput is the spent output, "<txid>:<index>".
+	PreviousOutput string
+	// Address and Amount are only set if the spent output is ours, as other outputs are not known.
+	Address string
+	Amount  coin.Amount
+	// Ours is true if the spent output belongs to our account.
+	Ours bool
+}
+
 // Transaction models a transaction with common transaction info.
 type Transaction interface {
 	// Fee is nil for a receiving tx. The fee is only displayed (and relevant) when sending funds
@@ -91,3 +102,62 @@ type Transaction interface {
 	// Addresses money was sent to / received on.
 	Addresses() []AddressAndAmount
 }
+
+// UTXOTransaction is implemented by transactions of UTXO based coins, which can list all their
+// inputs and outputs.
+type UTXOTransaction interface {
+	Transaction
+
+	// Inputs are all inputs of the transaction.
+	Inputs() []TransactionInput
+
+	// Outputs are all outputs of the transaction, including our change outputs, in order.
+	Outputs() []AddressAndAmount
+}
+
+// TransactionDetail is a transaction with all its inputs and outputs.
+type TransactionDetail struct {
+	Transaction Transaction
+	// Inputs is empty for account based coins like Ethereum.
+	Inputs []TransactionInput
+	// Outputs are all outputs for UTXO based coins, and the recipients for account based coins.
+	Outputs []AddressAndAmount
+}
+
+// NewTransactionDetail collects the inputs and outputs of the transaction.
+func NewTransactionDetail(transaction Transaction) *TransactionDetail {
+	if utxoTransaction, ok := transaction.(UTXOTransaction); ok {
+		return &TransactionDetail{
+			Transaction: transaction,
+			Inputs:      utxoTransaction.Inputs(),
+			Outputs:     utxoTransaction.Outputs(),
+		}
+	}
+	return &TransactionDetail{
+		Transaction: transaction,
+		Inputs:      []TransactionInput{},
+		Outputs:     transaction.Addresses(),
+	}
+}
+
+// TransactionByInternalID returns the transaction with the given internal ID, or nil if there is
+// none.
+func TransactionByInternalID(transactions []Transaction, internalID string) Transaction {
+	for _, transaction := range transactions {
+		if transaction.InternalID() == internalID {
+			return transaction
+		}
+	}
+	return nil
+}
+
+// PendingTransactions returns the transactions which are not confirmed yet, in the given order.
+func PendingTransactions(transactions []Transaction) []Transaction {
+	pending := []Transaction{}
+	for _, transaction := range transactions {
+		if transaction.NumConfirmations() == 0 {
+			pending = append(pending, transaction)
+		}
+	}
+	return pending
+}
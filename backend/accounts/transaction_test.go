@@ -0,0 +1,93 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package accounts_test
+
+import (
+	"testing"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/stretchr/testify/require"
+)
+
+type testTransaction struct {
+	accounts.Transaction
+	internalID       string
+	numConfirmations int
+	addresses        []accounts.AddressAndAmount
+}
+
+func (tx *testTransaction) InternalID() string                     { return tx.internalID }
+func (tx *testTransaction) NumConfirmations() int                  { return tx.numConfirmations }
+func (tx *testTransaction) Addresses() []accounts.AddressAndAmount { return tx.addresses }
+
+type testUTXOTransaction struct {
+	testTransaction
+	inputs  []accounts.TransactionInput
+	outputs []accounts.AddressAndAmount
+}
+
+func (tx *testUTXOTransaction) Inputs() []accounts.TransactionInput  { return tx.inputs }
+func (tx *testUTXOTransaction) Outputs() []accounts.AddressAndAmount { return tx.outputs }
+
+func TestTransactionByInternalID(t *testing.T) {
+	tx1 := &testTransaction{internalID: "txid1"}
+	tx2 := &testTransaction{internalID: "txid1-internal"}
+	txs := []accounts.Transaction{tx1, tx2}
+	require.Equal(t, tx1, accounts.TransactionByInternalID(txs, "txid1"))
+	require.Equal(t, tx2, accounts.TransactionByInternalID(txs, "txid1-internal"))
+	require.Nil(t, accounts.TransactionByInternalID(txs, "unknown"))
+	require.Nil(t, accounts.TransactionByInternalID(nil, "txid1"))
+}
+
+func TestPendingTransactions(t *testing.T) {
+	tx1 := &testTransaction{internalID: "txid1", numConfirmations: 0}
+	tx2 := &testTransaction{internalID: "txid2", numConfirmations: 3}
+	tx3 := &testTransaction{internalID: "txid3", numConfirmations: 0}
+	require.Equal(t,
+		[]accounts.Transaction{tx1, tx3},
+		accounts.PendingTransactions([]accounts.Transaction{tx1, tx2, tx3}))
+	require.Empty(t, accounts.PendingTransactions([]accounts.Transaction{tx2}))
+	require.Empty(t, accounts.PendingTransactions(nil))
+}
+
+func TestNewTransactionDetail(t *testing.T) {
+	recipient := accounts.AddressAndAmount{Address: "recipient", Amount: coin.NewAmountFromInt64(1)}
+	change := accounts.AddressAndAmount{Address: "change", Amount: coin.NewAmountFromInt64(2), Ours: true}
+	input := accounts.TransactionInput{PreviousOutput: "txid:0"}
+
+	// Account based coins have no inputs, the outputs are the recipients.
+	tx := &testTransaction{addresses: []accounts.AddressAndAmount{recipient}}
+	require.Equal(t,
+		&accounts.TransactionDetail{
+			Transaction: tx,
+			Inputs:      []accounts.TransactionInput{},
+			Outputs:     []accounts.AddressAndAmount{recipient},
+		},
+		accounts.NewTransactionDetail(tx))
+
+	utxoTx := &testUTXOTransaction{
+		testTransaction: testTransaction{addresses: []accounts.AddressAndAmount{recipient}},
+		inputs:          []accounts.TransactionInput{input},
+		outputs:         []accounts.AddressAndAmount{recipient, change},
+	}
+	require.Equal(t,
+		&accounts.TransactionDetail{
+			Transaction: utxoTx,
+			Inputs:      []accounts.TransactionInput{input},
+			Outputs:     []accounts.AddressAndAmount{recipient, change},
+		},
+		accounts.NewTransactionDetail(utxoTx))
+}
@@ -0,0 +1,126 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/observable"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/observable/action"
+)
+
+// snapshotFilename is the name of the file in the account files folder in which the account
+// snapshot is persisted.
+const snapshotFilename = "snapshot.json"
+
+// AccountSnapshot is the last known state of an account. It is persisted after every completed
+// sync, so that it can be shown right away on the next start while the account is syncing.
+type AccountSnapshot struct {
+	// Available and Incoming are the balances in the smallest unit of the coin.
+	Available string `json:"available"`
+	Incoming  string `json:"incoming"`
+	// LastTxID is the ID of the newest transaction, or empty if there are no transactions.
+	LastTxID string    `json:"lastTxID"`
+	TxCount  int       `json:"txCount"`
+	Time     time.Time `json:"time"`
+	// ConfigurationHash is the hash of the signing configuration of the account. A snapshot taken
+	// with a different keystore is not used.
+	ConfigurationHash string `json:"configurationHash"`
+}
+
+// AccountSnapshot returns the last known state of the account with the given code, or nil if
+// there is none. The snapshot of the previous session is available as soon as the account starts
+// syncing, and is replaced once the sync is complete.
+func (backend *Backend) AccountSnapshot(code string) *AccountSnapshot {
+	defer backend.snapshotsLock.RLock()()
+	return backend.snapshots[code]
+}
+
+func (backend *Backend) setAccountSnapshot(account accounts.Interface, snapshot *AccountSnapshot) {
+	func() {
+		defer backend.snapshotsLock.Lock()()
+		backend.snapshots[account.Code()] = snapshot
+	}()
+	backend.Notify(observable.Event{
+		Subject: fmt.Sprintf("account/%s/snapshot", account.Code()),
+		Action:  action.Replace,
+		Object:  snapshot,
+	})
+}
+
+// loadAccountSnapshot loads the persisted snapshot, unless it belongs to a different signing
+// configuration. Must be called after the account has been initialized.
+func (backend *Backend) loadAccountSnapshot(account accounts.Interface) {
+	data, err := ioutil.ReadFile(filepath.Join(account.FilesFolder(), snapshotFilename))
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		backend.log.WithError(err).Error("Could not read the account snapshot")
+		return
+	}
+	var snapshot AccountSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		backend.log.WithError(err).Error("Could not parse the account snapshot")
+		return
+	}
+	if snapshot.ConfigurationHash != account.Info().SigningConfiguration.Hash() {
+		backend.log.Info("Ignoring the account snapshot of a different keystore")
+		return
+	}
+	backend.setAccountSnapshot(account, &snapshot)
+}
+
+// storeAccountSnapshot takes, sets and persists a snapshot of the account. Must be called after
+// the account has been synced.
+func (backend *Backend) storeAccountSnapshot(account accounts.Interface, now time.Time) error {
+	balance, err := account.Balance()
+	if err != nil {
+		return err
+	}
+	transactions, err := account.Transactions()
+	if err != nil {
+		return err
+	}
+	snapshot := &AccountSnapshot{
+		Available:         balance.Available().BigInt().String(),
+		Incoming:          balance.Incoming().BigInt().String(),
+		TxCount:           len(transactions),
+		Time:              now,
+		ConfigurationHash: account.Info().SigningConfiguration.Hash(),
+	}
+	if len(transactions) > 0 {
+		snapshot.LastTxID = transactions[0].TxID()
+	}
+	backend.setAccountSnapshot(account, snapshot)
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return errp.WithStack(err)
+	}
+	return errp.WithStack(ioutil.WriteFile(
+		filepath.Join(account.FilesFolder(), snapshotFilename), data, 0600))
+}
+
+func (backend *Backend) clearAccountSnapshot(code string) {
+	defer backend.snapshotsLock.Lock()()
+	delete(backend.snapshots, code)
+}
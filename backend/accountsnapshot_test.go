@@ -0,0 +1,129 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/addresses"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/blockchain"
+	blockchainMock "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/blockchain/mocks"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/socksproxy"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/test"
+	"github.com/stretchr/testify/require"
+)
+
+// waitForSnapshot waits until the snapshot of the account satisfies the condition. It fails the
+// test if that does not happen, e.g. because taking the snapshot blocks.
+func waitForSnapshot(
+	t *testing.T, backend *Backend, code string, condition func(*AccountSnapshot) bool) *AccountSnapshot {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		if snapshot := backend.AccountSnapshot(code); snapshot != nil && condition(snapshot) {
+			return snapshot
+		}
+		select {
+		case <-timeout:
+			require.FailNow(t, "no snapshot was taken")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// TestAccountSnapshot syncs a btc account and checks that a snapshot is taken after each completed
+// sync.
+func TestAccountSnapshot(t *testing.T) {
+	backend := newTestBackend(t)
+	defer func() { require.NoError(t, backend.Close()) }()
+	backend.OnAccountInit(func(accounts.Interface) {})
+	backend.OnAccountUninit(func(accounts.Interface) {})
+
+	subscriptions := map[blockchain.ScriptHashHex]func(string){}
+	var subscriptionsLock sync.Mutex
+	var history blockchain.TxHistory
+	var tx *wire.MsgTx
+	coin := btc.NewCoin(coinTBTC, "TBTC", &chaincfg.TestNet3Params, test.TstTempDir("snapshot"),
+		nil, "", socksproxy.NewSocksProxy(false, ""))
+	coin.TstSetMakeBlockchain(func() blockchain.Interface {
+		return &blockchainMock.BlockchainMock{
+			MockRegisterOnConnectionStatusChangedEvent: func(func(blockchain.Status)) {},
+			MockScriptHashSubscribe: func(
+				setupAndTeardown func() func(error),
+				scriptHashHex blockchain.ScriptHashHex,
+				success func(string)) {
+				subscriptionsLock.Lock()
+				defer subscriptionsLock.Unlock()
+				subscriptions[scriptHashHex] = success
+			},
+			MockScriptHashGetHistory: func(
+				scriptHashHex blockchain.ScriptHashHex,
+				success func(blockchain.TxHistory) error,
+				cleanup func(error)) {
+				cleanup(success(history))
+			},
+			MockTransactionGet: func(
+				txHash chainhash.Hash, success func(*wire.MsgTx) error, cleanup func(error)) {
+				go func() { cleanup(success(tx)) }()
+			},
+		}
+	})
+	defer func() { require.NoError(t, coin.Close()) }()
+
+	configuration := testConfiguration(t, 1)
+	require.NoError(t, backend.CreateAndAddAccount(
+		coin, "tbtc-account", "Account", func() (*signing.Configuration, error) { return configuration, nil },
+		false, false))
+	account := backend.Accounts()[0]
+	require.Nil(t, backend.AccountSnapshot(account.Code()))
+	// The first sync is completed during the initialization.
+	require.NoError(t, account.Initialize())
+	snapshot := waitForSnapshot(t, backend, account.Code(), func(*AccountSnapshot) bool { return true })
+	require.Equal(t, "0", snapshot.Available)
+	require.Equal(t, "0", snapshot.Incoming)
+	require.Equal(t, 0, snapshot.TxCount)
+	require.Equal(t, "", snapshot.LastTxID)
+	require.Equal(t, account.Info().SigningConfiguration.Hash(), snapshot.ConfigurationHash)
+
+	// An address receives funds, which triggers another sync.
+	address := account.GetUnusedReceiveAddresses()[0].(*addresses.AccountAddress)
+	tx = wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{}, nil, nil))
+	tx.AddTxOut(wire.NewTxOut(1000, address.PubkeyScript()))
+	history = blockchain.TxHistory{{TXHash: blockchain.TXHash(tx.TxHash())}}
+	subscriptionsLock.Lock()
+	onStatus := subscriptions[address.PubkeyScriptHashHex()]
+	subscriptionsLock.Unlock()
+	onStatus(history.Status())
+	snapshot = waitForSnapshot(t, backend, account.Code(), func(snapshot *AccountSnapshot) bool {
+		return snapshot.TxCount == 1
+	})
+	require.Equal(t, "1000", snapshot.Incoming)
+	require.Equal(t, tx.TxHash().String(), snapshot.LastTxID)
+
+	// Simulate a restart: the persisted snapshot is loaded.
+	backend.clearAccountSnapshot(account.Code())
+	backend.loadAccountSnapshot(account)
+	require.Equal(t, snapshot.LastTxID, backend.AccountSnapshot(account.Code()).LastTxID)
+	require.True(t, snapshot.Time.Equal(backend.AccountSnapshot(account.Code()).Time))
+}
@@ -17,6 +17,7 @@ package arguments
 import (
 	"os"
 	"path"
+	"sync"
 
 	btctypes "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/types"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/logging"
@@ -43,7 +44,8 @@ type Arguments struct {
 	accountsConfigFilename string
 
 	// Testing stores whether the application is for testing only.
-	testing bool
+	testing     bool
+	testingLock sync.RWMutex
 
 	// Testing stores whether the application is for regtest.
 	regtest bool
@@ -151,9 +153,18 @@ func (arguments *Arguments) CacheDirectoryPath() string {
 
 // Testing returns whether the backend is for testing only.
 func (arguments *Arguments) Testing() bool {
+	arguments.testingLock.RLock()
+	defer arguments.testingLock.RUnlock()
 	return arguments.testing
 }
 
+// SetTesting switches between testing and production mode.
+func (arguments *Arguments) SetTesting(testing bool) {
+	arguments.testingLock.Lock()
+	defer arguments.testingLock.Unlock()
+	arguments.testing = testing
+}
+
 // DevMode returns whether the backend is in developer mode.
 func (arguments *Arguments) DevMode() bool {
 	return arguments.devmode
@@ -15,14 +15,21 @@
 package backend
 
 import (
+	"bytes"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"math/big"
 	"path/filepath"
 	"regexp"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
 	"github.com/cloudfoundry-attic/jibber_jabber"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/arguments"
@@ -31,6 +38,7 @@ import (
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/bitboxbase/mdns"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/electrum"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/psbt"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/erc20"
@@ -77,6 +85,17 @@ var testnetCoins = map[string]struct{}{
 	coinERC20TEST: {},
 }
 
+// coinCodes are the coins of each network, in the order in which they are listed.
+var coinCodes = struct {
+	mainnet []string
+	testnet []string
+	regtest []string
+}{
+	mainnet: []string{coinBTC, coinLTC, coinETH},
+	testnet: []string{coinTBTC, coinTLTC, coinTETH, coinRETH},
+	regtest: []string{coinRBTC},
+}
+
 type backendEvent struct {
 	Type string      `json:"type"`
 	Data string      `json:"data"`
@@ -133,6 +152,7 @@ type Backend struct {
 	notifier *Notifier
 
 	devices            map[string]device.Interface
+	devicesLock        locker.Locker
 	bitboxBases        map[string]*bitboxbase.BitBoxBase
 	keystores          *keystore.Keystores
 	onAccountInit      func(accounts.Interface)
@@ -145,10 +165,27 @@ type Backend struct {
 	coins     map[string]coin.Coin
 	coinsLock locker.Locker
 
-	accounts     []accounts.Interface
-	accountsLock locker.Locker
+	accounts []accounts.Interface
+	// accountsMetadata maps the codes of the loaded accounts to their metadata at the time they
+	// were added, so the account can be removed from the frontend even if its config is gone.
+	accountsMetadata map[string]map[string]string
+	// accountsHidden maps the codes of the loaded accounts to whether they are listed as hidden,
+	// see updateAccountsHidden().
+	accountsHidden map[string]bool
+	accountsLock   locker.Locker
+
+	// lastSynced maps account codes to the time the account last completed syncing.
+	lastSynced     map[string]time.Time
+	lastSyncedLock locker.Locker
+	// snapshots maps account codes to the last known state of the account.
+	snapshots     map[string]*AccountSnapshot
+	snapshotsLock locker.Locker
+
+	keystoreStatus     KeystoreStatus
+	keystoreStatusLock locker.Locker
 
 	baseManager *mdns.Manager
+	usbManager  *usb.Manager
 
 	log *logrus.Entry
 
@@ -175,7 +212,12 @@ func NewBackend(arguments *arguments.Arguments, environment Environment) (*Backe
 		keystores:   keystore.NewKeystores(),
 		coins:       map[string]coin.Coin{},
 		accounts:    []accounts.Interface{},
+		lastSynced:  map[string]time.Time{},
+		snapshots:   map[string]*AccountSnapshot{},
 		log:         log,
+
+		accountsMetadata: map[string]map[string]string{},
+		accountsHidden:   map[string]bool{},
 	}
 	notifier, err := NewNotifier(filepath.Join(arguments.MainDirectoryPath(), "notifier.db"))
 	if err != nil {
@@ -193,7 +235,10 @@ func NewBackend(arguments *arguments.Arguments, environment Environment) (*Backe
 		backend.arguments.BitBoxBaseDirectoryPath(), backend.socksProxy)
 
 	backend.ratesUpdater = rates.NewRateUpdater(backend.socksProxy)
-	backend.ratesUpdater.Observe(backend.Notify)
+	backend.ratesUpdater.Observe(func(event observable.Event) {
+		backend.Notify(event)
+		go backend.updateAccountsHidden(backend.ratesUpdater.Last())
+	})
 
 	backend.banners = banners.NewBanners()
 	backend.banners.Observe(backend.Notify)
@@ -205,6 +250,7 @@ func NewBackend(arguments *arguments.Arguments, environment Environment) (*Backe
 func (backend *Backend) addAccount(account accounts.Interface) {
 	defer backend.accountsLock.Lock()()
 	backend.accounts = append(backend.accounts, account)
+	backend.accountsMetadata[account.Code()] = backend.AccountMetadata(account.Code())
 	backend.onAccountInit(account)
 }
 
@@ -231,6 +277,35 @@ func (backend *Backend) notifyNewTxs(account accounts.Interface) {
 	}
 }
 
+// AccountJSON is the summary of an account as listed by the frontend.
+type AccountJSON struct {
+	CoinCode              string            `json:"coinCode"`
+	CoinUnit              string            `json:"coinUnit"`
+	Code                  string            `json:"code"`
+	Name                  string            `json:"name"`
+	BlockExplorerTxPrefix string            `json:"blockExplorerTxPrefix"`
+	Metadata              map[string]string `json:"metadata,omitempty"`
+	// Hidden is true if the account has a small balance and should not be listed in the sidebar,
+	// see Backend.AccountHidden().
+	Hidden bool `json:"hidden"`
+}
+
+// NewAccountJSON returns the summary of the account. metadata is the metadata persisted with the
+// account, see AccountMetadata(), and hidden is the result of AccountHidden().
+func NewAccountJSON(account accounts.Interface, metadata map[string]string, hidden bool) *AccountJSON {
+	return &AccountJSON{
+		CoinCode:              account.Coin().Code(),
+		CoinUnit:              account.Coin().Unit(false),
+		Code:                  account.Code(),
+		Name:                  account.Name(),
+		BlockExplorerTxPrefix: account.Coin().BlockExplorerTransactionURLPrefix(),
+		Metadata:              metadata,
+		Hidden:                hidden,
+	}
+}
+
+// emitAccountsStatusChanged tells the frontend to reload all accounts. Use it when the whole set
+// of accounts changes, e.g. when a keystore is registered.
 func (backend *Backend) emitAccountsStatusChanged() {
 	backend.Notify(observable.Event{
 		Subject: "accounts",
@@ -238,6 +313,39 @@ func (backend *Backend) emitAccountsStatusChanged() {
 	})
 }
 
+// emitAccountAdded appends the account to the accounts listed by the frontend. New accounts are
+// not synced yet, so they are never hidden.
+func (backend *Backend) emitAccountAdded(account accounts.Interface) {
+	backend.Notify(observable.Event{
+		Subject: "accounts",
+		Action:  action.Append,
+		Object:  NewAccountJSON(account, backend.AccountMetadata(account.Code()), false),
+	})
+}
+
+// emitAccountRemoved removes the account from the accounts listed by the frontend. The frontend
+// removes the entry equal to the event object, so metadata must be the metadata the account was
+// listed with.
+func (backend *Backend) emitAccountRemoved(
+	account accounts.Interface, metadata map[string]string, hidden bool) {
+	backend.Notify(observable.Event{
+		Subject: "accounts",
+		Action:  action.Remove,
+		Object:  NewAccountJSON(account, metadata, hidden),
+	})
+}
+
+// AccountMetadata returns the metadata persisted with the account, or nil if the account is not
+// persisted or has no metadata.
+func (backend *Backend) AccountMetadata(accountCode string) map[string]string {
+	for _, account := range backend.config.AccountsConfig().Accounts {
+		if account.Code == accountCode {
+			return account.Metadata
+		}
+	}
+	return nil
+}
+
 // CreateAndAddAccount creates an account with the given parameters and adds it to the backend. If
 // persist is true, the configuration is fetched and saved in the accounts configuration.
 func (backend *Backend) CreateAndAddAccount(
@@ -248,6 +356,24 @@ func (backend *Backend) CreateAndAddAccount(
 	persist bool,
 	emitEvent bool,
 ) error {
+	return backend.CreateAndAddAccountWithMetadata(
+		coin, code, name, getSigningConfiguration, nil, persist, emitEvent)
+}
+
+// CreateAndAddAccountWithMetadata is like CreateAndAddAccount, but also stores the given metadata
+// with the account if persist is true. The metadata is returned in the account summaries.
+func (backend *Backend) CreateAndAddAccountWithMetadata(
+	coin coin.Coin,
+	code string,
+	name string,
+	getSigningConfiguration func() (*signing.Configuration, error),
+	metadata map[string]string,
+	persist bool,
+	emitEvent bool,
+) error {
+	if !backend.coinEnabled(coin.Code()) {
+		return errp.Newf("coin %s is disabled", coin.Code())
+	}
 	if persist {
 		configuration, err := getSigningConfiguration()
 		if err != nil {
@@ -264,6 +390,7 @@ func (backend *Backend) CreateAndAddAccount(
 			Code:          code,
 			Name:          name,
 			Configuration: configuration,
+			Metadata:      metadata,
 		})
 		if err := backend.config.SetAccountsConfig(accountsConfig); err != nil {
 			return err
@@ -273,7 +400,36 @@ func (backend *Backend) CreateAndAddAccount(
 	var account accounts.Interface
 	onEvent := func(event accounts.Event) {
 		backend.events <- AccountEvent{Type: "account", Code: code, Data: string(event)}
-		if account != nil && event == accounts.EventSyncDone {
+		if account == nil {
+			return
+		}
+		switch event {
+		case accounts.EventSyncStarted:
+			if backend.AccountLastSynced(code) == nil {
+				backend.loadAccountLastSynced(account)
+			}
+			if backend.AccountSnapshot(code) == nil {
+				backend.loadAccountSnapshot(account)
+			}
+		case accounts.EventSyncProgress:
+			backend.Notify(observable.Event{
+				Subject: fmt.Sprintf("account/%s/sync-progress", code),
+				Action:  action.Replace,
+				Object:  account.SyncProgress(),
+			})
+		case accounts.EventSyncDone:
+			if err := backend.storeAccountLastSynced(account, time.Now()); err != nil {
+				backend.log.WithError(err).Error("Could not persist the last synced time")
+			}
+			// The event is fired while the synchronizer still holds its lock, so the snapshot
+			// can't be taken here: Balance() and Transactions() wait for the sync to finish.
+			syncDone := time.Now()
+			go func() {
+				if err := backend.storeAccountSnapshot(account, syncDone); err != nil {
+					backend.log.WithError(err).Error("Could not persist the account snapshot")
+				}
+				backend.updateAccountsHidden(backend.ratesUpdater.Last())
+			}()
 			backend.notifyNewTxs(account)
 		}
 	}
@@ -293,6 +449,14 @@ func (backend *Backend) CreateAndAddAccount(
 			getSigningConfiguration,
 			backend.keystores,
 			getNotifier,
+			func() int {
+				return backend.config.AppConfig().Backend.MinSpendConfirmationsForAccount(
+					code, specificCoin.Code())
+			},
+			func() int {
+				return backend.config.AppConfig().Backend.BtcConfirmationsCompleteForCoin(
+					specificCoin.Code())
+			},
 			onEvent,
 			backend.log,
 			backend.ratesUpdater,
@@ -301,14 +465,25 @@ func (backend *Backend) CreateAndAddAccount(
 		accountAdded = true
 	case *eth.Coin:
 		account = eth.NewAccount(specificCoin, backend.arguments.CacheDirectoryPath(), code, name,
-			getSigningConfiguration, backend.keystores, getNotifier, onEvent, backend.log, backend.ratesUpdater)
+			getSigningConfiguration, backend.keystores, getNotifier,
+			func() time.Duration {
+				return backend.config.AppConfig().Backend.EthPollIntervalForAccount(code)
+			},
+			func() int {
+				return backend.config.AppConfig().Backend.EthConfirmationsCompleteForAccount(
+					code, networkCoinCode(specificCoin.Code()))
+			},
+			func() float64 {
+				return backend.config.AppConfig().Backend.EthGasLimitMultiplierOrDefault()
+			},
+			onEvent, backend.log, backend.ratesUpdater)
 		backend.addAccount(account)
 		accountAdded = true
 	default:
 		panic("unknown coin type")
 	}
 	if emitEvent && accountAdded {
-		backend.emitAccountsStatusChanged()
+		backend.emitAccountAdded(account)
 	}
 	return nil
 }
@@ -321,9 +496,12 @@ func (backend *Backend) createAndAddAccount(
 	scriptType signing.ScriptType,
 ) {
 	log := backend.log.WithField("code", code).WithField("name", name)
-	prefix := "eth-erc20-"
-	if strings.HasPrefix(code, prefix) {
-		if !backend.config.AppConfig().Backend.ETH.ERC20TokenActive(code[len(prefix):]) {
+	if !backend.coinEnabled(coin.Code()) {
+		log.Info("skipping account of disabled coin")
+		return
+	}
+	if strings.HasPrefix(code, erc20CodePrefix) {
+		if !backend.config.AppConfig().Backend.ETH.ERC20TokenActive(code[len(erc20CodePrefix):]) {
 			log.WithField("name", name).Info("skipping inactive erc20 token")
 			return
 		}
@@ -564,6 +742,43 @@ func (backend *Backend) Coin(code string) (coin.Coin, error) {
 	return coin, nil
 }
 
+// SupportedCoins returns the codes of the coins of the current network for which accounts can be
+// loaded, i.e. which are not disabled in the config (see config.Backend.EnabledCoins).
+func (backend *Backend) SupportedCoins() []string {
+	codes := coinCodes.mainnet
+	switch {
+	case backend.arguments.Regtest():
+		codes = coinCodes.regtest
+	case backend.Testing():
+		codes = coinCodes.testnet
+	}
+	result := []string{}
+	for _, code := range codes {
+		if backend.coinEnabled(code) {
+			result = append(result, code)
+		}
+	}
+	return result
+}
+
+// coinEnabled returns true if accounts of the given coin can be loaded. ERC20 tokens are enabled
+// together with their Ethereum network.
+func (backend *Backend) coinEnabled(code string) bool {
+	return backend.config.AppConfig().Backend.CoinEnabled(networkCoinCode(code))
+}
+
+// networkCoinCode returns the code of the network of an ERC20 token, and the code itself for all
+// other coins.
+func networkCoinCode(code string) string {
+	switch {
+	case strings.HasPrefix(code, erc20CodePrefix):
+		return coinETH
+	case code == coinERC20TEST:
+		return coinTETH
+	}
+	return code
+}
+
 func (backend *Backend) initPersistedAccounts() {
 	for _, account := range backend.config.AccountsConfig().Accounts {
 		account := account
@@ -572,6 +787,11 @@ func (backend *Backend) initPersistedAccounts() {
 			// in testing mode
 			continue
 		}
+		if !backend.coinEnabled(account.CoinCode) {
+			backend.log.Infof("skipping persisted account %s/%s, coin disabled",
+				account.CoinCode, account.Code)
+			continue
+		}
 		coin, err := backend.Coin(account.CoinCode)
 		if err != nil {
 			backend.log.Errorf("skipping persisted account %s/%s, could not find coin",
@@ -594,7 +814,7 @@ func (backend *Backend) initDefaultAccounts() {
 	if backend.keystores.Count() == 0 {
 		return
 	}
-	if backend.arguments.Testing() {
+	if backend.Testing() {
 		switch {
 		case backend.arguments.Multisig():
 			TBTC, _ := backend.Coin(coinTBTC)
@@ -687,6 +907,31 @@ func (backend *Backend) ReinitializeAccounts() {
 	backend.initAccounts()
 }
 
+// SetTesting switches between testnet and mainnet at runtime. The loaded accounts are closed and
+// the accounts of the new network are loaded. Switching is refused while a device or keystore is
+// registered, as the device was initialized for the previous network and could be signing.
+func (backend *Backend) SetTesting(testing bool) error {
+	if testing == backend.Testing() {
+		return nil
+	}
+	if backend.arguments.Regtest() {
+		return errp.New("cannot switch networks in regtest mode")
+	}
+	if len(backend.DevicesRegistered()) != 0 || backend.keystores.Count() != 0 {
+		return errp.New("cannot switch networks while a device is connected")
+	}
+	backend.log.WithField("testing", testing).Info("Switching networks")
+	backend.uninitAccounts()
+	backend.arguments.SetTesting(testing)
+	backend.Notify(observable.Event{
+		Subject: "testing",
+		Action:  action.Replace,
+		Object:  testing,
+	})
+	backend.initAccounts()
+	return nil
+}
+
 // Testing returns whether this backend is for testing only.
 func (backend *Backend) Testing() bool {
 	return backend.arguments.Testing()
@@ -747,13 +992,14 @@ func (backend *Backend) OnBitBoxBaseUninit(f func(string)) {
 func (backend *Backend) Start() <-chan interface{} {
 	// We support only one device at a time at the moment.
 	onlyOne := !backend.arguments.Multisig()
-	usb.NewManager(
+	backend.usbManager = usb.NewManager(
 		backend.arguments.MainDirectoryPath(),
 		backend.arguments.BitBox02DirectoryPath(),
 		backend.socksProxy,
 		backend.environment.DeviceInfos,
 		backend.Register,
-		backend.Deregister, onlyOne).Start()
+		backend.Deregister, onlyOne)
+	backend.usbManager.Start()
 
 	httpClient, err := backend.socksProxy.GetHTTPClient()
 	if err != nil {
@@ -775,9 +1021,15 @@ func (backend *Backend) TryMakeNewBase(ip string) (bool, error) {
 	return backend.baseManager.TryMakeNewBase(ip)
 }
 
-// DevicesRegistered returns a map of device IDs to device of registered devices.
+// DevicesRegistered returns a map of device IDs to device of registered devices. The map is a copy,
+// as devices are registered and deregistered concurrently.
 func (backend *Backend) DevicesRegistered() map[string]device.Interface {
-	return backend.devices
+	defer backend.devicesLock.RLock()()
+	devices := make(map[string]device.Interface, len(backend.devices))
+	for deviceID, theDevice := range backend.devices {
+		devices[deviceID] = theDevice
+	}
+	return devices
 }
 
 // BitBoxBasesRegistered returns a map of bitboxBaseIDs and registered bitbox bases.
@@ -841,14 +1093,342 @@ func (backend *Backend) BitBoxBaseDeregister(bitboxBaseID string) {
 	backend.events <- backendEvent{Type: "bitboxbases", Data: "registeredChanged"}
 }
 
+// removeAccount closes the loaded account with the given code and removes it from the backend. It
+// is a no-op if no such account is loaded.
+func (backend *Backend) removeAccount(code string) {
+	defer backend.accountsLock.Lock()()
+	for index, account := range backend.accounts {
+		if account.Code() != code {
+			continue
+		}
+		backend.onAccountUninit(account)
+		account.Close()
+		backend.clearAccountLastSynced(code)
+		backend.clearAccountSnapshot(code)
+		backend.accounts = append(backend.accounts[:index], backend.accounts[index+1:]...)
+		metadata := backend.accountsMetadata[code]
+		hidden := backend.accountsHidden[code]
+		delete(backend.accountsMetadata, code)
+		delete(backend.accountsHidden, code)
+		backend.emitAccountRemoved(account, metadata, hidden)
+		return
+	}
+}
+
 func (backend *Backend) uninitAccounts() {
 	defer backend.accountsLock.Lock()()
 	for _, account := range backend.accounts {
 		account := account
 		backend.onAccountUninit(account)
 		account.Close()
+		backend.clearAccountLastSynced(account.Code())
+		backend.clearAccountSnapshot(account.Code())
 	}
 	backend.accounts = []accounts.Interface{}
+	backend.accountsMetadata = map[string]map[string]string{}
+	backend.accountsHidden = map[string]bool{}
+}
+
+// SetUTXOFrozen freezes or unfreezes an output of the BTC/LTC account with the given code. See
+// btc.Account.SetUTXOFrozen().
+func (backend *Backend) SetUTXOFrozen(accountCode string, outPoint wire.OutPoint, frozen bool) error {
+	defer backend.accountsLock.RLock()()
+	for _, account := range backend.accounts {
+		if account.Code() != accountCode {
+			continue
+		}
+		btcAccount, ok := account.(*btc.Account)
+		if !ok {
+			return errp.Newf("account %s does not have unspent outputs", accountCode)
+		}
+		return btcAccount.SetUTXOFrozen(outPoint, frozen)
+	}
+	return errp.Newf("unknown account %s", accountCode)
+}
+
+// RefreshAccount syncs the account with the given code right away. Other accounts are not
+// affected.
+func (backend *Backend) RefreshAccount(accountCode string) error {
+	var account accounts.Interface
+	func() {
+		defer backend.accountsLock.RLock()()
+		for _, acct := range backend.accounts {
+			if acct.Code() == accountCode {
+				account = acct
+				return
+			}
+		}
+	}()
+	if account == nil {
+		return errp.Newf("unknown account %s", accountCode)
+	}
+	// Refresh() can block until the account picks up the request, so the accounts lock must not
+	// be held.
+	return account.Refresh()
+}
+
+// PendingTransactions returns the unconfirmed transactions of the account with the given code.
+func (backend *Backend) PendingTransactions(accountCode string) ([]accounts.Transaction, error) {
+	var account accounts.Interface
+	func() {
+		defer backend.accountsLock.RLock()()
+		for _, acct := range backend.accounts {
+			if acct.Code() == accountCode {
+				account = acct
+				return
+			}
+		}
+	}()
+	if account == nil {
+		return nil, errp.Newf("unknown account %s", accountCode)
+	}
+	// Transactions() waits for the account to be synced, so the accounts lock must not be held.
+	transactions, err := account.Transactions()
+	if err != nil {
+		return nil, err
+	}
+	return accounts.PendingTransactions(transactions), nil
+}
+
+// TransactionDetails returns the transaction with the given internal ID of the account with the
+// given code.
+func (backend *Backend) TransactionDetails(
+	accountCode, txID string) (*accounts.TransactionDetail, error) {
+	var account accounts.Interface
+	func() {
+		defer backend.accountsLock.RLock()()
+		for _, acct := range backend.accounts {
+			if acct.Code() == accountCode {
+				account = acct
+				return
+			}
+		}
+	}()
+	if account == nil {
+		return nil, errp.Newf("unknown account %s", accountCode)
+	}
+	// Transactions() waits for the account to be synced, so the accounts lock must not be held.
+	transactions, err := account.Transactions()
+	if err != nil {
+		return nil, err
+	}
+	transaction := accounts.TransactionByInternalID(transactions, txID)
+	if transaction == nil {
+		return nil, errp.Newf("unknown transaction %s", txID)
+	}
+	return accounts.NewTransactionDetail(transaction), nil
+}
+
+// BalanceHistory returns the balance of the account with the given code over time, with one point
+// per interval, see accounts.BalanceHistory().
+func (backend *Backend) BalanceHistory(
+	accountCode string, interval accounts.Interval) ([]accounts.BalancePoint, error) {
+	var account accounts.Interface
+	func() {
+		defer backend.accountsLock.RLock()()
+		for _, acct := range backend.accounts {
+			if acct.Code() == accountCode {
+				account = acct
+				return
+			}
+		}
+	}()
+	if account == nil {
+		return nil, errp.Newf("unknown account %s", accountCode)
+	}
+	// Transactions() waits for the account to be synced, so the accounts lock must not be held.
+	transactions, err := account.Transactions()
+	if err != nil {
+		return nil, err
+	}
+	// The fees of ERC20 token transactions are paid in ether.
+	includeFees := true
+	if ethCoin, ok := account.Coin().(*eth.Coin); ok && ethCoin.ERC20Token() != nil {
+		includeFees = false
+	}
+	return accounts.BalanceHistory(transactions, interval, time.Now(), includeFees)
+}
+
+// CostBasisReport computes the realized gains of the account with the given code in the given fiat
+// currency, matching spent coins to received coins with the given method (see
+// accounts.CostBasisMethod). The historical exchange rates are fetched as needed.
+func (backend *Backend) CostBasisReport(
+	accountCode string, fiat string, method string) (*accounts.CostBasisReport, error) {
+	var account accounts.Interface
+	func() {
+		defer backend.accountsLock.RLock()()
+		for _, acct := range backend.accounts {
+			if acct.Code() == accountCode {
+				account = acct
+				return
+			}
+		}
+	}()
+	if account == nil {
+		return nil, errp.Newf("unknown account %s", accountCode)
+	}
+	transactions, err := account.Transactions()
+	if err != nil {
+		return nil, err
+	}
+	accountCoin := account.Coin()
+	// The fees of ERC20 token transactions are paid in ether.
+	includeFees := true
+	if ethCoin, ok := accountCoin.(*eth.Coin); ok && ethCoin.ERC20Token() != nil {
+		includeFees = false
+	}
+	unit := coin.RatesUnit(accountCoin, false)
+	return accounts.CostBasis(transactions, accounts.CostBasisMethod(method), includeFees,
+		func(amount coin.Amount, t time.Time) (float64, error) {
+			rate, err := backend.ratesUpdater.HistoricalRate(unit, fiat, t)
+			if err != nil {
+				return 0, err
+			}
+			return accountCoin.ToUnit(amount, false) * rate, nil
+		})
+}
+
+// AddressInfo returns how the given address of the account with the given code is derived. An
+// error is returned if the address does not belong to the account.
+func (backend *Backend) AddressInfo(accountCode string, address string) (*accounts.AddressInfo, error) {
+	defer backend.accountsLock.RLock()()
+	for _, account := range backend.accounts {
+		if account.Code() != accountCode {
+			continue
+		}
+		switch specificAccount := account.(type) {
+		case *btc.Account:
+			return specificAccount.AddressInfo(address)
+		case *eth.Account:
+			return specificAccount.AddressInfo(address)
+		default:
+			return nil, errp.Newf("account %s does not support address infos", accountCode)
+		}
+	}
+	return nil, errp.Newf("unknown account %s", accountCode)
+}
+
+// BumpFee proposes a tx replacing the unconfirmed tx with the given hash of the BTC or LTC account
+// with the given code, paying the given fee rate instead. It returns the amount sent to the
+// recipient and the new fee. The replacement is signed and sent with the account's
+// SendBumpFeeTx().
+func (backend *Backend) BumpFee(accountCode string, txHash chainhash.Hash, feeRatePerKb btcutil.Amount) (
+	coin.Amount, coin.Amount, error) {
+	var account accounts.Interface
+	func() {
+		defer backend.accountsLock.RLock()()
+		for _, acct := range backend.accounts {
+			if acct.Code() == accountCode {
+				account = acct
+				return
+			}
+		}
+	}()
+	if account == nil {
+		return coin.Amount{}, coin.Amount{}, errp.Newf("unknown account %s", accountCode)
+	}
+	btcAccount, ok := account.(*btc.Account)
+	if !ok {
+		return coin.Amount{}, coin.Amount{}, errp.Newf("account %s does not support replace-by-fee", accountCode)
+	}
+	// Proposing the replacement waits for the account's locks, so the accounts lock must not be
+	// held.
+	return btcAccount.BumpFeeTxProposal(txHash, feeRatePerKb)
+}
+
+// SweepPrivateKey sends all funds of the given private key (WIF) to the BTC or LTC account with the
+// given code, paying the given fee rate. The tx is signed with the private key, the device is not
+// involved.
+func (backend *Backend) SweepPrivateKey(accountCode string, wif string, feeRatePerKb btcutil.Amount) error {
+	var account accounts.Interface
+	func() {
+		defer backend.accountsLock.RLock()()
+		for _, acct := range backend.accounts {
+			if acct.Code() == accountCode {
+				account = acct
+				return
+			}
+		}
+	}()
+	if account == nil {
+		return errp.Newf("unknown account %s", accountCode)
+	}
+	btcAccount, ok := account.(*btc.Account)
+	if !ok {
+		return errp.Newf("account %s does not support sweeping private keys", accountCode)
+	}
+	// Sweeping scans the outputs of the key and broadcasts the tx over the network, so the accounts
+	// lock must not be held.
+	return btcAccount.SweepPrivateKey(wif, feeRatePerKb)
+}
+
+// ExportPSBT creates a tx of the BTC or LTC account with the given code and returns it as a base64
+// encoded unsigned PSBT (BIP174).
+func (backend *Backend) ExportPSBT(
+	accountCode string,
+	recipientAddress string,
+	amount coin.SendAmount,
+	feeTargetCode accounts.FeeTargetCode,
+	selectedUTXOs map[wire.OutPoint]struct{},
+) (string, error) {
+	var account accounts.Interface
+	func() {
+		defer backend.accountsLock.RLock()()
+		for _, acct := range backend.accounts {
+			if acct.Code() == accountCode {
+				account = acct
+				return
+			}
+		}
+	}()
+	if account == nil {
+		return "", errp.Newf("unknown account %s", accountCode)
+	}
+	btcAccount, ok := account.(*btc.Account)
+	if !ok {
+		return "", errp.Newf("account %s does not support PSBTs", accountCode)
+	}
+	// Creating the tx waits for the account's locks, so the accounts lock must not be held.
+	return btcAccount.ExportPSBT(recipientAddress, amount, feeTargetCode, selectedUTXOs)
+}
+
+// CombinePSBT merges the given base64 encoded PSBTs of the same tx, e.g. signed by different
+// cosigners, and returns the result base64 encoded.
+func (backend *Backend) CombinePSBT(psbts ...string) (string, error) {
+	packets := make([]*psbt.Packet, len(psbts))
+	for index, encoded := range psbts {
+		packet, err := psbt.Decode(encoded)
+		if err != nil {
+			return "", err
+		}
+		packets[index] = packet
+	}
+	combined, err := psbt.Combine(packets...)
+	if err != nil {
+		return "", err
+	}
+	return combined.Encode()
+}
+
+// FinalizePSBT finalizes the given base64 encoded, fully signed PSBT and returns the signed tx in
+// hex, ready to be broadcast.
+func (backend *Backend) FinalizePSBT(encoded string) (string, error) {
+	packet, err := psbt.Decode(encoded)
+	if err != nil {
+		return "", err
+	}
+	if err := packet.Finalize(); err != nil {
+		return "", err
+	}
+	tx, err := packet.Extract()
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tx.Serialize(&buf); err != nil {
+		return "", errp.WithStack(err)
+	}
+	return hex.EncodeToString(buf.Bytes()), nil
 }
 
 // Keystores returns the keystores registered at this backend.
@@ -858,6 +1438,12 @@ func (backend *Backend) Keystores() *keystore.Keystores {
 
 // RegisterKeystore registers the given keystore at this backend.
 func (backend *Backend) RegisterKeystore(keystore keystore.Keystore) {
+	backend.registerKeystore(keystore, KeystoreStatus{})
+}
+
+// registerKeystore registers the given keystore at this backend. status describes the device
+// providing the keystore, if any. The connected flag and the type are set from the keystore.
+func (backend *Backend) registerKeystore(keystore keystore.Keystore, status KeystoreStatus) {
 	backend.log.Info("registering keystore")
 	if err := backend.keystores.Add(keystore); err != nil {
 		backend.log.Panic("Failed to add a keystore.", err)
@@ -866,6 +1452,9 @@ func (backend *Backend) RegisterKeystore(keystore keystore.Keystore) {
 		Subject: "keystores",
 		Action:  action.Reload,
 	})
+	status.Connected = true
+	status.Type = keystore.Type()
+	backend.setKeystoreStatus(status)
 	if backend.arguments.Multisig() && backend.keystores.Count() != 2 {
 		return
 	}
@@ -880,6 +1469,7 @@ func (backend *Backend) DeregisterKeystore() {
 		Subject: "keystores",
 		Action:  action.Reload,
 	})
+	backend.setKeystoreStatus(KeystoreStatus{})
 	backend.uninitAccounts()
 	// TODO: classify accounts by keystore, remove only the ones belonging to the deregistered
 	// keystore. For now we just remove all, then re-add the rest.
@@ -889,9 +1479,12 @@ func (backend *Backend) DeregisterKeystore() {
 
 // Register registers the given device at this backend.
 func (backend *Backend) Register(theDevice device.Interface) error {
-	backend.devices[theDevice.Identifier()] = theDevice
-
-	mainKeystore := len(backend.devices) == 1
+	var mainKeystore bool
+	func() {
+		defer backend.devicesLock.Lock()()
+		backend.devices[theDevice.Identifier()] = theDevice
+		mainKeystore = len(backend.devices) == 1
+	}()
 	theDevice.SetOnEvent(func(event deviceevent.Event, data interface{}) {
 		switch event {
 		case deviceevent.EventKeystoreGone:
@@ -904,15 +1497,19 @@ func (backend *Backend) Register(theDevice device.Interface) error {
 			// }
 			// configuration := signing.NewConfiguration(absoluteKeypath,
 			// 	[]*hdkeychain.ExtendedKey{extendedPublicKey}, 1)
+			status := KeystoreStatus{
+				ProductName: theDevice.ProductName(),
+				DeviceID:    theDevice.Identifier(),
+			}
 			if backend.arguments.Multisig() {
-				backend.RegisterKeystore(
-					theDevice.KeystoreForConfiguration(nil, backend.keystores.Count()))
+				backend.registerKeystore(
+					theDevice.KeystoreForConfiguration(nil, backend.keystores.Count()), status)
 			} else if mainKeystore {
 				// HACK: for device based, only one is supported at the moment.
 				backend.keystores = keystore.NewKeystores()
 
-				backend.RegisterKeystore(
-					theDevice.KeystoreForConfiguration(nil, backend.keystores.Count()))
+				backend.registerKeystore(
+					theDevice.KeystoreForConfiguration(nil, backend.keystores.Count()), status)
 			}
 		}
 		backend.events <- deviceEvent{
@@ -953,9 +1550,17 @@ func (backend *Backend) Register(theDevice device.Interface) error {
 
 // Deregister deregisters the device with the given ID from this backend.
 func (backend *Backend) Deregister(deviceID string) {
-	if _, ok := backend.devices[deviceID]; ok {
+	var registered bool
+	func() {
+		defer backend.devicesLock.RLock()()
+		_, registered = backend.devices[deviceID]
+	}()
+	if registered {
 		backend.onDeviceUninit(deviceID)
-		delete(backend.devices, deviceID)
+		func() {
+			defer backend.devicesLock.Lock()()
+			delete(backend.devices, deviceID)
+		}()
 		backend.DeregisterKeystore()
 
 		// Old-school
@@ -973,6 +1578,50 @@ func (backend *Backend) RatesUpdater() *rates.RateUpdater {
 	return backend.ratesUpdater
 }
 
+// ValidateAddress returns true if the address is a valid recipient address for the coin with the
+// given code. BTC/LTC addresses must belong to the coin's network. Ethereum addresses are the same
+// across networks and are checked for their format and, if mixed-case, their EIP-55 checksum.
+func (backend *Backend) ValidateAddress(coinCode string, address string) (bool, error) {
+	theCoin, err := backend.Coin(coinCode)
+	if err != nil {
+		return false, err
+	}
+	switch specificCoin := theCoin.(type) {
+	case *btc.Coin:
+		_, err := specificCoin.DecodeAddress(address)
+		return err == nil, nil
+	case *eth.Coin:
+		return eth.IsValidAddress(address), nil
+	default:
+		return false, errp.Newf("cannot validate addresses of coin %s", coinCode)
+	}
+}
+
+// SupportedFiats returns the fiat currencies amounts can be converted to.
+func (backend *Backend) SupportedFiats() []string {
+	return rates.SupportedFiats()
+}
+
+// ConvertToFiat converts an amount, given in the smallest unit of the coin (e.g. satoshi), to the
+// given fiat currency using the latest exchange rates. The result is formatted for display.
+func (backend *Backend) ConvertToFiat(coinCode string, amount *big.Int, fiat string) (string, error) {
+	supported := false
+	for _, supportedFiat := range rates.SupportedFiats() {
+		if fiat == supportedFiat {
+			supported = true
+			break
+		}
+	}
+	if !supported {
+		return "", errp.Newf("unsupported fiat currency %s", fiat)
+	}
+	theCoin, err := backend.Coin(coinCode)
+	if err != nil {
+		return "", err
+	}
+	return coin.ConvertToFiat(coin.NewAmount(amount), theCoin, false, fiat, backend.ratesUpdater.Last())
+}
+
 // DownloadCert downloads the first element of the remote certificate chain.
 func (backend *Backend) DownloadCert(server string) (string, error) {
 	return electrum.DownloadCert(server, backend.socksProxy)
@@ -985,6 +1634,52 @@ func (backend *Backend) CheckElectrumServer(serverInfo *config.ServerInfo) error
 		serverInfo, backend.log, backend.socksProxy.GetTCPProxyDialer())
 }
 
+// BroadcastRaw broadcasts a transaction which was signed elsewhere, given as hex, on the network of
+// the coin with the given code. It returns the ID of the broadcasted transaction.
+func (backend *Backend) BroadcastRaw(coinCode string, rawTxHex string) (string, error) {
+	theCoin, err := backend.Coin(coinCode)
+	if err != nil {
+		return "", err
+	}
+	theCoin.Initialize()
+	switch specificCoin := theCoin.(type) {
+	case *btc.Coin:
+		return specificCoin.BroadcastRawTransaction(rawTxHex)
+	case *eth.Coin:
+		return specificCoin.BroadcastRawTransaction(rawTxHex)
+	default:
+		return "", errp.Newf("broadcasting is not supported for %s", coinCode)
+	}
+}
+
+// AddressBalance looks up the balance of an arbitrary address on the network of the coin with the
+// given code, in the smallest unit of the coin. No account is created and nothing is persisted.
+func (backend *Backend) AddressBalance(coinCode string, address string) (*big.Int, error) {
+	theCoin, err := backend.Coin(coinCode)
+	if err != nil {
+		return nil, err
+	}
+	theCoin.Initialize()
+	switch specificCoin := theCoin.(type) {
+	case *btc.Coin:
+		return specificCoin.AddressBalance(address)
+	case *eth.Coin:
+		return specificCoin.AddressBalance(address)
+	default:
+		return nil, errp.Newf("address balances are not supported for %s", coinCode)
+	}
+}
+
+// CheckProxy checks if the SOCKS5 proxy at the given address can be used. It should be called
+// before enabling the proxy or changing its address, as all connections go through it afterwards.
+func (backend *Backend) CheckProxy(proxyAddress string) error {
+	// An empty address means the default proxy, as in the proxy config.
+	if proxyAddress == "" {
+		proxyAddress = config.DefaultProxyAddress
+	}
+	return socksproxy.CheckProxy(proxyAddress)
+}
+
 // RegisterTestKeystore adds a keystore derived deterministically from a PIN, for convenience in
 // devmode.
 func (backend *Backend) RegisterTestKeystore(pin string) {
@@ -993,6 +1688,19 @@ func (backend *Backend) RegisterTestKeystore(pin string) {
 	backend.RegisterKeystore(softwareBasedKeystore)
 }
 
+// ImportMnemonic registers a software keystore derived from a BIP39 mnemonic and optional
+// passphrase, for which the existing accounts are loaded like for a device. The keys are hot, i.e.
+// kept in memory on this computer. Invalid mnemonics are rejected.
+func (backend *Backend) ImportMnemonic(mnemonic string, passphrase string) error {
+	softwareBasedKeystore, err := software.NewKeystoreFromMnemonic(
+		backend.keystores.Count(), mnemonic, passphrase)
+	if err != nil {
+		return err
+	}
+	backend.RegisterKeystore(softwareBasedKeystore)
+	return nil
+}
+
 // NotifyUser creates a desktop notification.
 func (backend *Backend) NotifyUser(text string) {
 	backend.environment.NotifyUser(text)
@@ -1061,6 +1769,12 @@ func (backend *Backend) Environment() Environment {
 func (backend *Backend) Close() error {
 	errors := []string{}
 
+	// Closes all devices and deregisters their keystores.
+	if backend.usbManager != nil {
+		backend.usbManager.Close()
+	}
+	backend.ratesUpdater.Stop()
+
 	backend.uninitAccounts()
 
 	for _, coin := range backend.coins {
@@ -0,0 +1,542 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/arguments"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/config"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/devices/usb"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/observable"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/observable/action"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/test"
+	"github.com/stretchr/testify/require"
+)
+
+type testEnvironment struct{}
+
+func (testEnvironment) NotifyUser(string)             {}
+func (testEnvironment) DeviceInfos() []usb.DeviceInfo { return nil }
+func (testEnvironment) SystemOpen(string) error       { return nil }
+func (testEnvironment) UsingMobileData() bool         { return false }
+
+func newTestBackend(t *testing.T) *Backend {
+	t.Helper()
+	backend, err := NewBackend(
+		arguments.NewArguments(test.TstTempDir("backend-test"), true, false, false, false, false, nil),
+		testEnvironment{},
+	)
+	require.NoError(t, err)
+	return backend
+}
+
+// TestCloseTwice checks that closing the backend again does not panic.
+func TestCloseTwice(t *testing.T) {
+	backend := newTestBackend(t)
+	require.NoError(t, backend.Close())
+	require.NoError(t, backend.Close())
+}
+
+func TestValidateAddress(t *testing.T) {
+	backend := newTestBackend(t)
+	defer func() { require.NoError(t, backend.Close()) }()
+	tests := []struct {
+		coinCode string
+		address  string
+		valid    bool
+	}{
+		{coinBTC, "1GM1Wp6t3hJf6U5aq6dG62Pg3c9ePbiUQ9", true},
+		{coinBTC, "3GZFjFASPoYh3zuLoJLapYpKHw7ikiH63z", true},
+		{coinBTC, "bc1qwqdg6squsna38e46795at95yu9atm8azzmyvckulcc7kytlcckxswvvzej", true},
+		{coinBTC, "tb1qp4p8rtxsg3ddz62pntl64s2ddctgtjudkdsg27", false},
+		{coinBTC, "myY3Bbvj5mjwqqvubtu5Hfy2nuCeBfvNXL", false},
+		{coinBTC, "ltc1qzr0n0a4xs0404fy5l7pl7pj8yj8q34ml27rlcs", false},
+		{coinBTC, "", false},
+		{coinTBTC, "tb1qp4p8rtxsg3ddz62pntl64s2ddctgtjudkdsg27", true},
+		{coinTBTC, "2NBecb6J3HmBBC8RDB9PC2h7EgT9iyza1N3", true},
+		{coinTBTC, "bc1qwqdg6squsna38e46795at95yu9atm8azzmyvckulcc7kytlcckxswvvzej", false},
+		{coinTBTC, "1GM1Wp6t3hJf6U5aq6dG62Pg3c9ePbiUQ9", false},
+		{coinRBTC, "tb1qp4p8rtxsg3ddz62pntl64s2ddctgtjudkdsg27", false},
+		{coinRBTC, "bc1qwqdg6squsna38e46795at95yu9atm8azzmyvckulcc7kytlcckxswvvzej", false},
+		{coinLTC, "ltc1qzr0n0a4xs0404fy5l7pl7pj8yj8q34ml27rlcs", true},
+		{coinLTC, "tltc1q2n65aaawmc94xsyznyr5939uztwjdz3rhvveq0", false},
+		{coinLTC, "1GM1Wp6t3hJf6U5aq6dG62Pg3c9ePbiUQ9", false},
+		{coinTLTC, "tltc1q2n65aaawmc94xsyznyr5939uztwjdz3rhvveq0", true},
+		{coinTLTC, "ltc1qzr0n0a4xs0404fy5l7pl7pj8yj8q34ml27rlcs", false},
+		{coinETH, "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", true},
+		{coinETH, "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", true},
+		{coinETH, "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD", false},
+		{coinETH, "0x5aaeb6053f3e94c9b9a09f33669435e7ef1bea", false},
+		{coinETH, "1GM1Wp6t3hJf6U5aq6dG62Pg3c9ePbiUQ9", false},
+		{coinTETH, "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", true},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.coinCode+"/"+test.address, func(t *testing.T) {
+			valid, err := backend.ValidateAddress(test.coinCode, test.address)
+			require.NoError(t, err)
+			require.Equal(t, test.valid, valid)
+		})
+	}
+
+	_, err := backend.ValidateAddress("unknown", "1GM1Wp6t3hJf6U5aq6dG62Pg3c9ePbiUQ9")
+	require.Error(t, err)
+}
+
+type testAccount struct {
+	accounts.Interface
+	code        string
+	filesFolder string
+}
+
+func (account *testAccount) Code() string {
+	return account.code
+}
+
+func (account *testAccount) FilesFolder() string {
+	return account.filesFolder
+}
+
+func TestAccountLastSynced(t *testing.T) {
+	backend := newTestBackend(t)
+	defer func() { require.NoError(t, backend.Close()) }()
+
+	var events []observable.Event
+	backend.Observe(func(event observable.Event) { events = append(events, event) })
+
+	account := &testAccount{code: "btc-p2wpkh", filesFolder: test.TstTempDir("last-synced")}
+	require.Nil(t, backend.AccountLastSynced(account.code))
+	// Nothing persisted yet.
+	backend.loadAccountLastSynced(account)
+	require.Nil(t, backend.AccountLastSynced(account.code))
+
+	syncDone := time.Date(2020, 5, 4, 12, 30, 0, 0, time.UTC)
+	require.NoError(t, backend.storeAccountLastSynced(account, syncDone))
+	require.Equal(t, syncDone, *backend.AccountLastSynced(account.code))
+	require.Len(t, events, 1)
+	require.Equal(t, "account/btc-p2wpkh/last-synced", events[0].Subject)
+	require.Equal(t, syncDone, events[0].Object)
+
+	syncDone = syncDone.Add(time.Minute)
+	require.NoError(t, backend.storeAccountLastSynced(account, syncDone))
+	require.Equal(t, syncDone, *backend.AccountLastSynced(account.code))
+
+	// Simulate a restart.
+	backend.clearAccountLastSynced(account.code)
+	require.Nil(t, backend.AccountLastSynced(account.code))
+	backend.loadAccountLastSynced(account)
+	require.True(t, syncDone.Equal(*backend.AccountLastSynced(account.code)))
+}
+
+type refreshTestAccount struct {
+	testAccount
+	refreshed int
+}
+
+func (account *refreshTestAccount) Refresh() error {
+	account.refreshed++
+	return nil
+}
+
+func TestRefreshAccount(t *testing.T) {
+	backend := newTestBackend(t)
+	defer func() { require.NoError(t, backend.Close()) }()
+
+	account1 := &refreshTestAccount{testAccount: testAccount{code: "account-1"}}
+	account2 := &refreshTestAccount{testAccount: testAccount{code: "account-2"}}
+	backend.accounts = []accounts.Interface{account1, account2}
+	defer func() { backend.accounts = []accounts.Interface{} }()
+
+	require.NoError(t, backend.RefreshAccount("account-2"))
+	require.Equal(t, 0, account1.refreshed)
+	require.Equal(t, 1, account2.refreshed)
+	require.Error(t, backend.RefreshAccount("unknown"))
+}
+
+type pendingTestTransaction struct {
+	accounts.Transaction
+	txID             string
+	numConfirmations int
+	addresses        []accounts.AddressAndAmount
+}
+
+func (tx *pendingTestTransaction) TxID() string                           { return tx.txID }
+func (tx *pendingTestTransaction) InternalID() string                     { return tx.txID }
+func (tx *pendingTestTransaction) NumConfirmations() int                  { return tx.numConfirmations }
+func (tx *pendingTestTransaction) Addresses() []accounts.AddressAndAmount { return tx.addresses }
+
+type pendingTestAccount struct {
+	testAccount
+	transactions []accounts.Transaction
+}
+
+func (account *pendingTestAccount) Transactions() ([]accounts.Transaction, error) {
+	return account.transactions, nil
+}
+
+func TestPendingTransactions(t *testing.T) {
+	backend := newTestBackend(t)
+	defer func() { require.NoError(t, backend.Close()) }()
+
+	pendingTx := &pendingTestTransaction{txID: "pending", numConfirmations: 0}
+	confirmedTx := &pendingTestTransaction{txID: "confirmed", numConfirmations: 2}
+	withPending := &pendingTestAccount{
+		testAccount:  testAccount{code: "with-pending"},
+		transactions: []accounts.Transaction{pendingTx, confirmedTx},
+	}
+	withoutPending := &pendingTestAccount{
+		testAccount:  testAccount{code: "without-pending"},
+		transactions: []accounts.Transaction{confirmedTx},
+	}
+	backend.accounts = []accounts.Interface{withPending, withoutPending}
+	defer func() { backend.accounts = []accounts.Interface{} }()
+
+	transactions, err := backend.PendingTransactions("with-pending")
+	require.NoError(t, err)
+	require.Equal(t, []accounts.Transaction{pendingTx}, transactions)
+
+	transactions, err = backend.PendingTransactions("without-pending")
+	require.NoError(t, err)
+	require.Empty(t, transactions)
+
+	_, err = backend.PendingTransactions("unknown")
+	require.Error(t, err)
+}
+
+type balanceHistoryTestTransaction struct {
+	accounts.Transaction
+	timestamp        *time.Time
+	numConfirmations int
+	amount           int64
+}
+
+func (tx *balanceHistoryTestTransaction) TxID() string              { return "txid" }
+func (tx *balanceHistoryTestTransaction) Timestamp() *time.Time     { return tx.timestamp }
+func (tx *balanceHistoryTestTransaction) NumConfirmations() int     { return tx.numConfirmations }
+func (tx *balanceHistoryTestTransaction) Type() accounts.TxType     { return accounts.TxTypeReceive }
+func (tx *balanceHistoryTestTransaction) Status() accounts.TxStatus { return accounts.TxStatusComplete }
+func (tx *balanceHistoryTestTransaction) Amount() coin.Amount {
+	return coin.NewAmountFromInt64(tx.amount)
+}
+func (tx *balanceHistoryTestTransaction) Fee() *coin.Amount { return nil }
+
+type balanceHistoryTestAccount struct {
+	pendingTestAccount
+	coin coin.Coin
+}
+
+func (account *balanceHistoryTestAccount) Coin() coin.Coin { return account.coin }
+
+func TestBalanceHistory(t *testing.T) {
+	backend := newTestBackend(t)
+	defer func() { require.NoError(t, backend.Close()) }()
+	btcCoin, err := backend.Coin(coinTBTC)
+	require.NoError(t, err)
+
+	timestamp := time.Now().Add(-time.Hour)
+	tx := &balanceHistoryTestTransaction{timestamp: &timestamp, numConfirmations: 1, amount: 1000}
+	account := &balanceHistoryTestAccount{
+		pendingTestAccount: pendingTestAccount{
+			testAccount:  testAccount{code: "account"},
+			transactions: []accounts.Transaction{tx},
+		},
+		coin: btcCoin,
+	}
+	backend.accounts = []accounts.Interface{account}
+	defer func() { backend.accounts = []accounts.Interface{} }()
+
+	history, err := backend.BalanceHistory("account", accounts.IntervalDay)
+	require.NoError(t, err)
+	require.NotEmpty(t, history)
+	require.Equal(t, coin.NewAmountFromInt64(1000), history[len(history)-1].Balance)
+
+	// Not ready while the time of a confirmed transaction is not known.
+	tx.timestamp = nil
+	_, err = backend.BalanceHistory("account", accounts.IntervalDay)
+	require.Error(t, err)
+
+	_, err = backend.BalanceHistory("unknown", accounts.IntervalDay)
+	require.Error(t, err)
+}
+
+func TestTransactionDetails(t *testing.T) {
+	backend := newTestBackend(t)
+	defer func() { require.NoError(t, backend.Close()) }()
+
+	recipient := accounts.AddressAndAmount{Address: "recipient", Amount: coin.NewAmountFromInt64(1)}
+	tx := &pendingTestTransaction{
+		txID:      "txid",
+		addresses: []accounts.AddressAndAmount{recipient},
+	}
+	account := &pendingTestAccount{
+		testAccount:  testAccount{code: "account"},
+		transactions: []accounts.Transaction{tx},
+	}
+	backend.accounts = []accounts.Interface{account}
+	defer func() { backend.accounts = []accounts.Interface{} }()
+
+	detail, err := backend.TransactionDetails("account", "txid")
+	require.NoError(t, err)
+	require.Equal(t,
+		&accounts.TransactionDetail{
+			Transaction: tx,
+			Inputs:      []accounts.TransactionInput{},
+			Outputs:     []accounts.AddressAndAmount{recipient},
+		},
+		detail)
+
+	_, err = backend.TransactionDetails("account", "unknown")
+	require.Error(t, err)
+	_, err = backend.TransactionDetails("unknown", "txid")
+	require.Error(t, err)
+}
+
+func TestAccountEvents(t *testing.T) {
+	backend := newTestBackend(t)
+	defer func() { require.NoError(t, backend.Close()) }()
+	backend.OnAccountInit(func(accounts.Interface) {})
+	backend.OnAccountUninit(func(accounts.Interface) {})
+
+	var events []observable.Event
+	backend.Observe(func(event observable.Event) {
+		if event.Subject == "accounts" {
+			events = append(events, event)
+		}
+	})
+
+	btcCoin, err := backend.Coin(coinBTC)
+	require.NoError(t, err)
+	configuration := testConfiguration(t, 1)
+	getSigningConfiguration := func() (*signing.Configuration, error) { return configuration, nil }
+	require.NoError(t, backend.CreateAndAddAccount(
+		btcCoin, "account-1", "Account 1", getSigningConfiguration, true, true))
+	require.Len(t, events, 1)
+	require.Equal(t, action.Append, events[0].Action)
+	account1JSON := &AccountJSON{
+		CoinCode:              coinBTC,
+		CoinUnit:              "BTC",
+		Code:                  "account-1",
+		Name:                  "Account 1",
+		BlockExplorerTxPrefix: btcCoin.BlockExplorerTransactionURLPrefix(),
+	}
+	require.Equal(t, account1JSON, events[0].Object)
+
+	// A duplicate, which is removed again by merging it into the first account.
+	accountsConfig := backend.config.AccountsConfig()
+	accountsConfig.Accounts = append(accountsConfig.Accounts, config.Account{
+		CoinCode: coinBTC, Code: "account-2", Name: "Account 2", Configuration: configuration,
+		Metadata: map[string]string{"key": "value"}})
+	require.NoError(t, backend.config.SetAccountsConfig(accountsConfig))
+	require.NoError(t, backend.CreateAndAddAccount(
+		btcCoin, "account-2", "Account 2", getSigningConfiguration, false, true))
+	require.Len(t, backend.Accounts(), 2)
+	account2JSON := events[len(events)-1].Object
+	events = nil
+
+	require.NoError(t, backend.MergeAccounts("account-1", "account-2"))
+	require.Len(t, backend.Accounts(), 1)
+	require.Equal(t, "account-1", backend.Accounts()[0].Code())
+	require.Len(t, events, 1)
+	require.Equal(t, action.Remove, events[0].Action)
+	// The frontend removes the entry equal to the one it was given, although the merge removed
+	// the metadata from the config.
+	require.Equal(t, account2JSON, events[0].Object)
+
+	// Structural changes reload all accounts.
+	events = nil
+	backend.ReinitializeAccounts()
+	require.Len(t, events, 1)
+	require.Equal(t, action.Reload, events[0].Action)
+}
+
+func TestAccountMetadata(t *testing.T) {
+	backend := newTestBackend(t)
+	defer func() { require.NoError(t, backend.Close()) }()
+	backend.OnAccountInit(func(accounts.Interface) {})
+	backend.OnAccountUninit(func(accounts.Interface) {})
+
+	var events []observable.Event
+	backend.Observe(func(event observable.Event) {
+		if event.Subject == "accounts" {
+			events = append(events, event)
+		}
+	})
+
+	btcCoin, err := backend.Coin(coinBTC)
+	require.NoError(t, err)
+	getSigningConfiguration := func() (*signing.Configuration, error) {
+		return testConfiguration(t, 1), nil
+	}
+	metadata := map[string]string{"source": "import", "label": "cold storage"}
+	require.NoError(t, backend.CreateAndAddAccountWithMetadata(
+		btcCoin, "account-1", "Account 1", getSigningConfiguration, metadata, true, true))
+	require.Equal(t, metadata, backend.config.AccountsConfig().Accounts[0].Metadata)
+	require.Equal(t, metadata, backend.AccountMetadata("account-1"))
+	require.Nil(t, backend.AccountMetadata("unknown"))
+	require.Len(t, events, 1)
+	require.Equal(t, metadata, events[0].Object.(*AccountJSON).Metadata)
+
+	// Reinitializing loads the accounts from the persisted config, keeping the metadata.
+	backend.ReinitializeAccounts()
+	require.Equal(t, metadata, backend.AccountMetadata("account-1"))
+}
+
+func TestSetTokensActive(t *testing.T) {
+	backend := newTestBackend(t)
+	defer func() { require.NoError(t, backend.Close()) }()
+	backend.OnAccountInit(func(accounts.Interface) {})
+	backend.OnAccountUninit(func(accounts.Interface) {})
+
+	reloads := 0
+	backend.Observe(func(event observable.Event) {
+		if event.Subject == "accounts" && event.Action == action.Reload {
+			reloads++
+		}
+	})
+	activeTokens := func() []string {
+		return backend.config.AppConfig().Backend.ETH.ActiveERC20Tokens
+	}
+
+	require.NoError(t, backend.SetTokensActive(map[string]bool{
+		"eth-erc20-usdt": true,
+		"eth-erc20-link": true,
+		"eth-erc20-bat":  true,
+	}))
+	require.Equal(t, []string{"bat", "link", "usdt"}, activeTokens())
+	require.Equal(t, 1, reloads)
+
+	require.NoError(t, backend.SetTokensActive(map[string]bool{
+		"eth-erc20-link": false,
+		"eth-erc20-usdt": true,
+		"eth-erc20-zrx":  true,
+	}))
+	require.Equal(t, []string{"bat", "usdt", "zrx"}, activeTokens())
+	require.Equal(t, 2, reloads)
+
+	// Nothing changed, no reinit.
+	require.NoError(t, backend.SetTokensActive(map[string]bool{
+		"eth-erc20-usdt": true,
+		"eth-erc20-mkr":  false,
+	}))
+	require.Equal(t, 2, reloads)
+
+	// Unknown tokens are rejected without applying the other changes.
+	require.Error(t, backend.SetTokensActive(map[string]bool{
+		"eth-erc20-bat":     false,
+		"eth-erc20-unknown": true,
+	}))
+	require.Equal(t, []string{"bat", "usdt", "zrx"}, activeTokens())
+	require.Equal(t, 2, reloads)
+}
+
+func TestEnabledCoins(t *testing.T) {
+	backend := newTestBackend(t)
+	defer func() { require.NoError(t, backend.Close()) }()
+	backend.OnAccountInit(func(accounts.Interface) {})
+	backend.OnAccountUninit(func(accounts.Interface) {})
+	accountCoinCodes := func() map[string]struct{} {
+		codes := map[string]struct{}{}
+		for _, account := range backend.Accounts() {
+			codes[account.Coin().Code()] = struct{}{}
+		}
+		return codes
+	}
+
+	require.Equal(t, []string{coinTBTC, coinTLTC, coinTETH, coinRETH}, backend.SupportedCoins())
+	backend.RegisterTestKeystore("1234")
+	require.Contains(t, accountCoinCodes(), coinTLTC)
+
+	appConfig := backend.config.AppConfig()
+	appConfig.Backend.EnabledCoins = []string{coinTBTC, coinTETH}
+	require.NoError(t, backend.config.SetAppConfig(appConfig))
+	require.Equal(t, []string{coinTBTC, coinTETH}, backend.SupportedCoins())
+
+	// Neither default nor persisted accounts of disabled coins are loaded.
+	accountsConfig := backend.config.AccountsConfig()
+	accountsConfig.Accounts = []config.Account{
+		{CoinCode: coinTLTC, Code: "tltc-account", Name: "Litecoin",
+			Configuration: testConfiguration(t, 1)},
+	}
+	require.NoError(t, backend.config.SetAccountsConfig(accountsConfig))
+	backend.ReinitializeAccounts()
+	require.Contains(t, accountCoinCodes(), coinTBTC)
+	require.NotContains(t, accountCoinCodes(), coinTLTC)
+
+	tltcCoin, err := backend.Coin(coinTLTC)
+	require.NoError(t, err)
+	require.Error(t, backend.CreateAndAddAccount(
+		tltcCoin, "tltc-account-2", "Litecoin", func() (*signing.Configuration, error) {
+			return testConfiguration(t, 2), nil
+		}, true, true))
+	require.Len(t, backend.config.AccountsConfig().Accounts, 1)
+}
+
+func TestSetTesting(t *testing.T) {
+	backend := newTestBackend(t)
+	defer func() { require.NoError(t, backend.Close()) }()
+	backend.OnAccountInit(func(accounts.Interface) {})
+	backend.OnAccountUninit(func(accounts.Interface) {})
+
+	accountsConfig := backend.config.AccountsConfig()
+	accountsConfig.Accounts = []config.Account{
+		{CoinCode: coinBTC, Code: "mainnet-account", Name: "Mainnet",
+			Configuration: testConfiguration(t, 1)},
+		{CoinCode: coinTBTC, Code: "testnet-account", Name: "Testnet",
+			Configuration: testConfiguration(t, 2)},
+	}
+	require.NoError(t, backend.config.SetAccountsConfig(accountsConfig))
+	accountCodes := func() []string {
+		codes := []string{}
+		for _, account := range backend.Accounts() {
+			codes = append(codes, account.Code())
+		}
+		return codes
+	}
+
+	var events []observable.Event
+	backend.Observe(func(event observable.Event) {
+		if event.Subject == "testing" {
+			events = append(events, event)
+		}
+	})
+
+	backend.ReinitializeAccounts()
+	require.True(t, backend.Testing())
+	require.Equal(t, []string{"testnet-account"}, accountCodes())
+
+	require.NoError(t, backend.SetTesting(false))
+	require.False(t, backend.Testing())
+	require.Equal(t, []string{"mainnet-account"}, accountCodes())
+	require.Len(t, events, 1)
+	require.Equal(t, false, events[0].Object)
+
+	// No-op.
+	require.NoError(t, backend.SetTesting(false))
+	require.Len(t, events, 1)
+
+	require.NoError(t, backend.SetTesting(true))
+	require.True(t, backend.Testing())
+	require.Equal(t, []string{"testnet-account"}, accountCodes())
+
+	// Refused while a keystore is registered.
+	backend.RegisterKeystore(testKeystore{})
+	require.Error(t, backend.SetTesting(false))
+	require.True(t, backend.Testing())
+}
@@ -79,4 +79,29 @@ func TestServeShutdownServe(t *testing.T) {
 	case <-time.After(time.Second):
 		require.Fail(t, "could not Serve twice")
 	}
+	bridgecommon.Shutdown()
+}
+
+// TestShutdownReturns checks that Shutdown after Serve stops the backend without blocking.
+func TestShutdownReturns(t *testing.T) {
+	bridgecommon.Serve(
+		false,
+		nil,
+		communication{},
+		environment{},
+	)
+
+	done := make(chan struct{})
+	go func() {
+		bridgecommon.Shutdown()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "Shutdown blocked")
+	}
+	// Calling it again is a no-op.
+	bridgecommon.Shutdown()
 }
@@ -16,12 +16,14 @@ package btc
 
 import (
 	"fmt"
+	"math/big"
 	"os"
 	"path"
 	"sort"
 
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/btcsuite/btcutil"
 	"github.com/btcsuite/btcutil/hdkeychain"
@@ -82,6 +84,16 @@ type Account struct {
 
 	feeTargets []*FeeTarget
 
+	// frozenUTXOs are the outputs the user does not want to spend. Loaded in Initialize().
+	frozenUTXOs map[wire.OutPoint]struct{}
+
+	// getMinSpendConfirmations returns the number of confirmations an incoming output needs before
+	// it can be spent.
+	getMinSpendConfirmations func() int
+	// getNumConfirmationsComplete returns the number of confirmations after which a transaction is
+	// considered complete.
+	getNumConfirmationsComplete func() int
+
 	initialized bool
 	offline     bool
 	fatalError  bool
@@ -125,6 +137,8 @@ func NewAccount(
 	getSigningConfiguration func() (*signing.Configuration, error),
 	keystores *keystore.Keystores,
 	getNotifier func(*signing.Configuration) accounts.Notifier,
+	getMinSpendConfirmations func() int,
+	getNumConfirmationsComplete func() int,
 	onEvent func(accounts.Event),
 	log *logrus.Entry,
 	rateUpdater *rates.RateUpdater,
@@ -134,24 +148,21 @@ func NewAccount(
 	log.Debug("Creating new account")
 
 	account := &Account{
-		coin:                    coin,
-		dbFolder:                dbFolder,
-		dbSubfolder:             "", // set in Initialize()
-		code:                    code,
-		name:                    name,
-		forceGapLimits:          forceGapLimits,
-		getSigningConfiguration: getSigningConfiguration,
-		signingConfiguration:    nil,
-		keystores:               keystores,
-		getNotifier:             getNotifier,
+		coin:                        coin,
+		dbFolder:                    dbFolder,
+		dbSubfolder:                 "", // set in Initialize()
+		code:                        code,
+		name:                        name,
+		forceGapLimits:              forceGapLimits,
+		getSigningConfiguration:     getSigningConfiguration,
+		signingConfiguration:        nil,
+		keystores:                   keystores,
+		getNotifier:                 getNotifier,
+		getMinSpendConfirmations:    getMinSpendConfirmations,
+		getNumConfirmationsComplete: getNumConfirmationsComplete,
 
 		// feeTargets must be sorted by ascending priority.
-		feeTargets: []*FeeTarget{
-			{blocks: 24, code: accounts.FeeTargetCodeEconomy},
-			{blocks: 12, code: accounts.FeeTargetCodeLow},
-			{blocks: 6, code: accounts.FeeTargetCodeNormal},
-			{blocks: 2, code: accounts.FeeTargetCodeHigh},
-		},
+		feeTargets: newFeeTargets(),
 		// initializing to false, to prevent flashing of offline notification in the frontend
 		offline:     false,
 		initialized: false,
@@ -168,6 +179,7 @@ func NewAccount(
 			}
 			onEvent(accounts.EventSyncDone)
 		},
+		func(float64) { onEvent(accounts.EventSyncProgress) },
 		log,
 	)
 	return account
@@ -317,6 +329,14 @@ func (account *Account) Initialize() error {
 	if err := os.MkdirAll(account.dbSubfolder, 0700); err != nil {
 		return errp.WithStack(err)
 	}
+	frozenUTXOs, err := loadFrozenUTXOs(path.Join(account.dbSubfolder, frozenUTXOsFilename))
+	if err != nil {
+		return err
+	}
+	func() {
+		defer account.Lock()()
+		account.frozenUTXOs = frozenUTXOs
+	}()
 
 	dbName := fmt.Sprintf("%s.db", accountIdentifier)
 	account.log.Debugf("Opening the database '%s' to persist the transactions.", dbName)
@@ -458,6 +478,11 @@ func (account *Account) onNewHeader(header *blockchain.Header) error {
 	return nil
 }
 
+// SyncProgress implements accounts.Interface.
+func (account *Account) SyncProgress() float64 {
+	return account.synchronizer.Progress()
+}
+
 // Offline returns true if the account is disconnected from the blockchain.
 func (account *Account) Offline() bool {
 	return account.offline
@@ -583,6 +608,29 @@ func (account *Account) Balance() (*accounts.Balance, error) {
 	return account.transactions.Balance(), nil
 }
 
+// BalanceBreakdown implements accounts.Interface. Frozen outputs are part of the available balance,
+// but are reported separately, as they can't be spent.
+func (account *Account) BalanceBreakdown() (*accounts.BalanceBreakdown, error) {
+	balance, err := account.Balance()
+	if err != nil {
+		return nil, err
+	}
+	frozenOutPoints := account.frozenOutPoints()
+	var frozen int64
+	// With zero min confirmations, these are exactly the outputs counted as available.
+	for outPoint, output := range account.transactions.SpendableOutputs(0) {
+		if _, ok := frozenOutPoints[outPoint]; ok {
+			frozen += output.Value
+		}
+	}
+	spendable := new(big.Int).Sub(balance.Available().BigInt(), big.NewInt(frozen))
+	return accounts.NewBalanceBreakdown(
+		coin.NewAmount(spendable),
+		balance.Incoming(),
+		coin.NewAmountFromInt64(frozen),
+	), nil
+}
+
 func (account *Account) addresses(change bool) AddressChain {
 	if change {
 		return account.changeAddresses
@@ -637,6 +685,45 @@ func (account *Account) onAddressStatus(address *addresses.AccountAddress, statu
 	)
 }
 
+// Refresh fetches the tx history of all addresses of the account right away, instead of waiting
+// for the backend to notify about changes. Addresses whose history changed are synced as usual.
+func (account *Account) Refresh() error {
+	if account.signingConfiguration == nil {
+		return errp.New("account must be initialized")
+	}
+	if account.fatalError {
+		return errp.New("can't call Refresh() after a fatal error")
+	}
+	accountAddresses := map[blockchain.ScriptHashHex]*addresses.AccountAddress{}
+	func() {
+		defer account.RLock()()
+		for _, change := range []bool{false, true} {
+			for _, address := range account.addresses(change).Addresses() {
+				// Receive and change address are the same for address based accounts.
+				accountAddresses[address.PubkeyScriptHashHex()] = address
+			}
+		}
+	}()
+	for _, address := range accountAddresses {
+		address := address
+		done := account.synchronizer.IncRequestsCounter()
+		account.blockchain.ScriptHashGetHistory(
+			address.PubkeyScriptHashHex(),
+			func(history blockchain.TxHistory) error {
+				account.onAddressStatus(address, history.Status())
+				return nil
+			},
+			func(err error) {
+				done()
+				if err != nil {
+					account.log.WithError(err).Error("Could not refresh the address history")
+				}
+			},
+		)
+	}
+	return nil
+}
+
 // ensureAddresses is the entry point of syncing up the account. It extends the receive and change
 // address chains to discover all funds, with respect to the gap limit. In the end, there are
 // `gapLimit` unused addresses in the tail. It is also called whenever the status (tx history) of
@@ -710,6 +797,7 @@ func (account *Account) Transactions() ([]accounts.Transaction, error) {
 		return nil, errp.New("can't call Transactions() after a fatal error")
 	}
 	transactions := account.transactions.Transactions(
+		account.getNumConfirmationsComplete(),
 		func(scriptHashHex blockchain.ScriptHashHex) bool {
 			return account.changeAddresses.LookupByScriptHashHex(scriptHashHex) != nil
 		})
@@ -760,6 +848,51 @@ func (account *Account) VerifyAddress(addressID string) (bool, error) {
 	return false, nil
 }
 
+// AddressUsed implements accounts.Interface.
+func (account *Account) AddressUsed(addressID string) (bool, error) {
+	if account.signingConfiguration == nil {
+		return false, errp.New("account must be initialized")
+	}
+	account.synchronizer.WaitSynchronized()
+	defer account.RLock()()
+	address := account.receiveAddresses.LookupByScriptHashHex(blockchain.ScriptHashHex(addressID))
+	if address == nil {
+		return false, errp.New("unknown address not found")
+	}
+	return address.IsUsed(), nil
+}
+
+// AddressInfo returns how the given receive or change address of the account is derived. An error
+// is returned if the address does not belong to the account.
+func (account *Account) AddressInfo(address string) (*accounts.AddressInfo, error) {
+	if account.signingConfiguration == nil {
+		return nil, errp.New("account must be initialized")
+	}
+	btcAddress, err := account.coin.DecodeAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	pkScript, err := txscript.PayToAddrScript(btcAddress)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	scriptHashHex := blockchain.ScriptHashHex(chainhash.HashH(pkScript).String())
+	defer account.RLock()()
+	for _, change := range []bool{false, true} {
+		accountAddress := account.addresses(change).LookupByScriptHashHex(scriptHashHex)
+		if accountAddress == nil {
+			continue
+		}
+		return &accounts.AddressInfo{
+			ScriptType:      accountAddress.Configuration.ScriptType(),
+			RelativeKeypath: accountAddress.RelativeKeypath.Encode(),
+			Keypath:         accountAddress.Configuration.AbsoluteKeypath().Encode(),
+			Change:          change,
+		}, nil
+	}
+	return nil, errp.New("unknown address not found")
+}
+
 // CanVerifyAddresses wraps Keystores().CanVerifyAddresses(), see that function for documentation.
 func (account *Account) CanVerifyAddresses() (bool, bool, error) {
 	if account.signingConfiguration == nil {
@@ -791,6 +924,8 @@ func (p *byValue) Swap(i, j int) { p.outputs[i], p.outputs[j] = p.outputs[j], p.
 type SpendableOutput struct {
 	*transactions.SpendableOutput
 	OutPoint wire.OutPoint
+	// Frozen is true if the user froze the output, so that it is not spent.
+	Frozen bool
 }
 
 // SpendableOutputs returns the utxo set, sorted by the value descending.
@@ -798,8 +933,13 @@ func (account *Account) SpendableOutputs() []*SpendableOutput {
 	account.synchronizer.WaitSynchronized()
 	defer account.RLock()()
 	result := []*SpendableOutput{}
-	for outPoint, txOut := range account.transactions.SpendableOutputs() {
-		result = append(result, &SpendableOutput{OutPoint: outPoint, SpendableOutput: txOut})
+	for outPoint, txOut := range account.transactions.SpendableOutputs(account.getMinSpendConfirmations()) {
+		_, frozen := account.frozenUTXOs[outPoint]
+		result = append(result, &SpendableOutput{
+			OutPoint:        outPoint,
+			SpendableOutput: txOut,
+			Frozen:          frozen,
+		})
 	}
 	sort.Sort(sort.Reverse(&byValue{result}))
 	return result
@@ -17,21 +17,88 @@ package btc_test
 import (
 	"math/big"
 	"os"
+	"sync"
 	"testing"
 
 	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
 	"github.com/btcsuite/btcutil/hdkeychain"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/addresses"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/blockchain"
 	blockchainMock "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/blockchain/mocks"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/maketx"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/keystore"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/logging"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/socksproxy"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/test"
 	"github.com/stretchr/testify/require"
 )
 
+// nopNotifier is an accounts.Notifier which does not notify about anything.
+type nopNotifier struct{}
+
+func (nopNotifier) Put([]byte) error              { return nil }
+func (nopNotifier) Delete([]byte) error           { return nil }
+func (nopNotifier) UnnotifiedCount() (int, error) { return 0, nil }
+func (nopNotifier) MarkAllNotified() error        { return nil }
+
+// newTestAccount returns an initialized tbtc account with a p2wpkh-p2sh signing configuration,
+// using the given blockchain. No keystore is registered, like for an account loaded while no
+// device is connected. The returned function closes the account and removes its files.
+func newTestAccount(t *testing.T, blockchainMock *blockchainMock.BlockchainMock) (*btc.Account, func()) {
+	t.Helper()
+	code := "tbtc"
+	unit := "TBTC"
+	net := &chaincfg.TestNet3Params
+
+	dbFolder := test.TstTempDir("btc-dbfolder")
+
+	coin := btc.NewCoin(
+		code, unit, net, dbFolder, nil, explorer, socksproxy.NewSocksProxy(false, ""))
+
+	blockchainMock.MockRegisterOnConnectionStatusChangedEvent = func(onConnectionStatusChanged func(blockchain.Status)) {
+	}
+
+	coin.TstSetMakeBlockchain(func() blockchain.Interface { return blockchainMock })
+
+	getSigningConfiguration := func() (*signing.Configuration, error) {
+		keypath, err := signing.NewAbsoluteKeypath("m/49'/1'/0'")
+		require.NoError(t, err)
+		xpub, err := hdkeychain.NewMaster(make([]byte, 32), net)
+		require.NoError(t, err)
+		xpub, err = xpub.Neuter()
+		require.NoError(t, err)
+
+		return signing.NewSinglesigConfiguration(
+			signing.ScriptTypeP2WPKHP2SH,
+			keypath,
+			xpub,
+		), nil
+	}
+	account := btc.NewAccount(
+		coin, dbFolder, "accountcode", "accountname", nil, getSigningConfiguration,
+		keystore.NewKeystores(),
+		func(*signing.Configuration) accounts.Notifier { return nopNotifier{} },
+		func() int { return 1 },
+		func() int { return 6 },
+		func(accounts.Event) {},
+		logging.Get().WithGroup("account_test"),
+		nil,
+	)
+	require.False(t, account.Initialized())
+	require.NoError(t, account.Initialize())
+	require.True(t, account.Initialized())
+	return account, func() {
+		account.Close()
+		_ = os.RemoveAll(dbFolder)
+	}
+}
+
 func TestAccount(t *testing.T) {
 	code := "tbtc"
 	unit := "TBTC"
@@ -66,6 +133,8 @@ func TestAccount(t *testing.T) {
 	account := btc.NewAccount(
 		coin, dbFolder, "accountcode", "accountname", nil, getSigningConfiguration, nil,
 		func(*signing.Configuration) accounts.Notifier { return nil },
+		func() int { return 1 },
+		func() int { return 6 },
 		func(accounts.Event) {},
 		logging.Get().WithGroup("account_test"),
 		nil,
@@ -85,3 +154,213 @@ func TestAccount(t *testing.T) {
 
 	require.Equal(t, []*btc.SpendableOutput{}, account.SpendableOutputs())
 }
+
+func TestAccountWithoutKeystore(t *testing.T) {
+	// Syncing works without a keystore, see TestAccount(). Signing requires one.
+	account, cleanup := newTestAccount(t, &blockchainMock.BlockchainMock{})
+	defer cleanup()
+	require.Equal(t, 0, account.Keystores().Count())
+
+	err := btc.SignTransaction(
+		account.Keystores(),
+		&maketx.TxProposal{Transaction: wire.NewMsgTx(wire.TxVersion)},
+		nil, nil, logging.Get().WithGroup("account_test"))
+	require.Equal(t, keystore.ErrNoKeystore, errp.Cause(err))
+}
+
+func TestAccountAddressUsed(t *testing.T) {
+	subscriptions := map[blockchain.ScriptHashHex]func(string){}
+	var subscriptionsLock sync.Mutex
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{}, nil, nil))
+	history := blockchain.TxHistory{{TXHash: blockchain.TXHash(tx.TxHash())}}
+	account, cleanup := newTestAccount(t, &blockchainMock.BlockchainMock{
+		MockScriptHashSubscribe: func(
+			setupAndTeardown func() func(error),
+			scriptHashHex blockchain.ScriptHashHex,
+			success func(string)) {
+			subscriptionsLock.Lock()
+			defer subscriptionsLock.Unlock()
+			subscriptions[scriptHashHex] = success
+		},
+		MockScriptHashGetHistory: func(
+			scriptHashHex blockchain.ScriptHashHex,
+			success func(blockchain.TxHistory) error,
+			cleanup func(error)) {
+			require.NoError(t, success(history))
+			cleanup(nil)
+		},
+		MockTransactionGet: func(txHash chainhash.Hash, success func(*wire.MsgTx) error, cleanup func(error)) {
+			go func() {
+				_ = success(tx)
+				cleanup(nil)
+			}()
+		},
+	})
+	defer cleanup()
+
+	receiveAddresses := account.GetUnusedReceiveAddresses()
+	fresh, used := receiveAddresses[0], receiveAddresses[1]
+	// The address receives funds.
+	subscriptionsLock.Lock()
+	onStatus := subscriptions[blockchain.ScriptHashHex(used.ID())]
+	subscriptionsLock.Unlock()
+	onStatus(history.Status())
+
+	isUsed, err := account.AddressUsed(fresh.ID())
+	require.NoError(t, err)
+	require.False(t, isUsed)
+	isUsed, err = account.AddressUsed(used.ID())
+	require.NoError(t, err)
+	require.True(t, isUsed)
+
+	_, err = account.AddressUsed("unknown")
+	require.Error(t, err)
+}
+
+func TestAccountBalanceBreakdown(t *testing.T) {
+	subscriptions := map[blockchain.ScriptHashHex]func(string){}
+	histories := map[blockchain.ScriptHashHex]blockchain.TxHistory{}
+	txs := map[chainhash.Hash]*wire.MsgTx{}
+	var lock sync.Mutex
+	account, cleanup := newTestAccount(t, &blockchainMock.BlockchainMock{
+		MockScriptHashSubscribe: func(
+			setupAndTeardown func() func(error),
+			scriptHashHex blockchain.ScriptHashHex,
+			success func(string)) {
+			lock.Lock()
+			defer lock.Unlock()
+			subscriptions[scriptHashHex] = success
+		},
+		MockScriptHashGetHistory: func(
+			scriptHashHex blockchain.ScriptHashHex,
+			success func(blockchain.TxHistory) error,
+			cleanup func(error)) {
+			lock.Lock()
+			history := histories[scriptHashHex]
+			lock.Unlock()
+			require.NoError(t, success(history))
+			cleanup(nil)
+		},
+		MockTransactionGet: func(txHash chainhash.Hash, success func(*wire.MsgTx) error, cleanup func(error)) {
+			lock.Lock()
+			tx := txs[txHash]
+			lock.Unlock()
+			go func() {
+				_ = success(tx)
+				cleanup(nil)
+			}()
+		},
+	})
+	defer cleanup()
+
+	receiveAddresses := account.GetUnusedReceiveAddresses()
+	confirmedAddress := receiveAddresses[0].(*addresses.AccountAddress)
+	unconfirmedAddress := receiveAddresses[1].(*addresses.AccountAddress)
+
+	// A confirmed tx paying twice to us and an unconfirmed one from someone else.
+	confirmedTx := wire.NewMsgTx(wire.TxVersion)
+	confirmedTx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 1}, nil, nil))
+	confirmedTx.AddTxOut(wire.NewTxOut(1000, confirmedAddress.PubkeyScript()))
+	confirmedTx.AddTxOut(wire.NewTxOut(2000, confirmedAddress.PubkeyScript()))
+	unconfirmedTx := wire.NewMsgTx(wire.TxVersion)
+	unconfirmedTx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 2}, nil, nil))
+	unconfirmedTx.AddTxOut(wire.NewTxOut(500, unconfirmedAddress.PubkeyScript()))
+
+	lock.Lock()
+	txs[confirmedTx.TxHash()] = confirmedTx
+	txs[unconfirmedTx.TxHash()] = unconfirmedTx
+	histories[confirmedAddress.PubkeyScriptHashHex()] = blockchain.TxHistory{
+		{Height: 10, TXHash: blockchain.TXHash(confirmedTx.TxHash())}}
+	histories[unconfirmedAddress.PubkeyScriptHashHex()] = blockchain.TxHistory{
+		{Height: 0, TXHash: blockchain.TXHash(unconfirmedTx.TxHash())}}
+	onConfirmedStatus := subscriptions[confirmedAddress.PubkeyScriptHashHex()]
+	onUnconfirmedStatus := subscriptions[unconfirmedAddress.PubkeyScriptHashHex()]
+	lock.Unlock()
+	onConfirmedStatus(histories[confirmedAddress.PubkeyScriptHashHex()].Status())
+	onUnconfirmedStatus(histories[unconfirmedAddress.PubkeyScriptHashHex()].Status())
+
+	breakdown, err := account.BalanceBreakdown()
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(3500), breakdown.Total.BigInt())
+	require.Equal(t, big.NewInt(3000), breakdown.Spendable.BigInt())
+	require.Equal(t, big.NewInt(500), breakdown.Incoming.BigInt())
+	require.Equal(t, big.NewInt(0), breakdown.Frozen.BigInt())
+
+	require.NoError(t, account.SetUTXOFrozen(wire.OutPoint{Hash: confirmedTx.TxHash(), Index: 1}, true))
+	breakdown, err = account.BalanceBreakdown()
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(3500), breakdown.Total.BigInt())
+	require.Equal(t, big.NewInt(1000), breakdown.Spendable.BigInt())
+	require.Equal(t, big.NewInt(500), breakdown.Incoming.BigInt())
+	require.Equal(t, big.NewInt(2000), breakdown.Frozen.BigInt())
+}
+
+func TestAccountAddressInfo(t *testing.T) {
+	account, cleanup := newTestAccount(t, &blockchainMock.BlockchainMock{
+		MockScriptHashSubscribe: func(func() func(error), blockchain.ScriptHashHex, func(string)) {},
+	})
+	defer cleanup()
+
+	receiveAddress := account.GetUnusedReceiveAddresses()[1].EncodeForHumans()
+	addressInfo, err := account.AddressInfo(receiveAddress)
+	require.NoError(t, err)
+	require.Equal(t, &accounts.AddressInfo{
+		ScriptType:      signing.ScriptTypeP2WPKHP2SH,
+		RelativeKeypath: "0/1",
+		Keypath:         "m/49'/1'/0'/0/1",
+		Change:          false,
+	}, addressInfo)
+
+	relativeKeypath, err := signing.NewRelativeKeypath("1/0")
+	require.NoError(t, err)
+	changeAddress := addresses.NewAccountAddress(
+		account.Info().SigningConfiguration, relativeKeypath, &chaincfg.TestNet3Params,
+		logging.Get().WithGroup("account_test"),
+	).EncodeForHumans()
+	addressInfo, err = account.AddressInfo(changeAddress)
+	require.NoError(t, err)
+	require.Equal(t, &accounts.AddressInfo{
+		ScriptType:      signing.ScriptTypeP2WPKHP2SH,
+		RelativeKeypath: "1/0",
+		Keypath:         "m/49'/1'/0'/1/0",
+		Change:          true,
+	}, addressInfo)
+
+	// Addresses beyond the gap limit are not known to the account.
+	relativeKeypath, err = signing.NewRelativeKeypath("0/100")
+	require.NoError(t, err)
+	_, err = account.AddressInfo(addresses.NewAccountAddress(
+		account.Info().SigningConfiguration, relativeKeypath, &chaincfg.TestNet3Params,
+		logging.Get().WithGroup("account_test"),
+	).EncodeForHumans())
+	require.Error(t, err)
+	_, err = account.AddressInfo("invalid")
+	require.Error(t, err)
+}
+
+func TestAccountRefresh(t *testing.T) {
+	var historyRequestsLock sync.Mutex
+	historyRequests := map[blockchain.ScriptHashHex]int{}
+	account, cleanup := newTestAccount(t, &blockchainMock.BlockchainMock{
+		MockScriptHashSubscribe: func(func() func(error), blockchain.ScriptHashHex, func(string)) {},
+		MockScriptHashGetHistory: func(
+			scriptHashHex blockchain.ScriptHashHex,
+			success func(blockchain.TxHistory) error,
+			cleanup func(error)) {
+			historyRequestsLock.Lock()
+			historyRequests[scriptHashHex]++
+			historyRequestsLock.Unlock()
+			require.NoError(t, success(blockchain.TxHistory{}))
+			cleanup(nil)
+		},
+	})
+	defer cleanup()
+
+	require.NoError(t, account.Refresh())
+	// The history of each of the 20 receive and 6 change addresses is fetched once.
+	require.Len(t, historyRequests, 26)
+	for _, count := range historyRequests {
+		require.Equal(t, 1, count)
+	}
+}
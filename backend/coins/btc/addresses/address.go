@@ -146,7 +146,8 @@ func (address *AccountAddress) EncodeForHumans() string {
 	return address.EncodeAddress()
 }
 
-func (address *AccountAddress) isUsed() bool {
+// IsUsed returns true if the address has a transaction history.
+func (address *AccountAddress) IsUsed() bool {
 	return address.HistoryStatus != ""
 }
 
@@ -165,6 +166,11 @@ func (address *AccountAddress) PubkeyScriptHashHex() blockchain.ScriptHashHex {
 	return blockchain.ScriptHashHex(chainhash.HashH(address.PubkeyScript()).String())
 }
 
+// RedeemScript returns the redeem script of a P2SH address, or nil if this is not a P2SH address.
+func (address *AccountAddress) RedeemScript() []byte {
+	return address.redeemScript
+}
+
 // ScriptForHashToSign returns whether this address is a segwit output and the script used when
 // calculating the hash to be signed in a transaction. This info is needed when trying to spend
 // from this address.
@@ -79,7 +79,7 @@ func (addresses *AddressChain) addAddress() *AccountAddress {
 func (addresses *AddressChain) unusedTailCount() int {
 	count := 0
 	for i := len(addresses.addresses) - 1; i >= 0; i-- {
-		if addresses.addresses[i].isUsed() {
+		if addresses.addresses[i].IsUsed() {
 			break
 		}
 		count++
@@ -100,6 +100,11 @@ func (addresses *AddressChain) LookupByScriptHashHex(hashHex blockchain.ScriptHa
 	return nil
 }
 
+// Addresses returns all addresses of the chain.
+func (addresses *AddressChain) Addresses() []*AccountAddress {
+	return append([]*AccountAddress{}, addresses.addresses...)
+}
+
 // EnsureAddresses appends addresses to the address chain until there are `gapLimit` unused unused
 // ones, and returns the new addresses.
 func (addresses *AddressChain) EnsureAddresses() []*AccountAddress {
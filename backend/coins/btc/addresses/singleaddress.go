@@ -61,6 +61,14 @@ func (addresses *SingleAddress) LookupByScriptHashHex(hashHex blockchain.ScriptH
 	return addresses.address
 }
 
+// Addresses returns the address, if it was created by EnsureAddresses() already.
+func (addresses *SingleAddress) Addresses() []*AccountAddress {
+	if addresses.address == nil {
+		return []*AccountAddress{}
+	}
+	return []*AccountAddress{addresses.address}
+}
+
 // EnsureAddresses returns the address
 func (addresses *SingleAddress) EnsureAddresses() []*AccountAddress {
 	if addresses.address == nil {
@@ -15,6 +15,8 @@
 package btc
 
 import (
+	"bytes"
+	"encoding/hex"
 	"fmt"
 	"math/big"
 	"os"
@@ -23,6 +25,9 @@ import (
 	"sync"
 
 	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
 	"github.com/btcsuite/btcutil"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/errors"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/blockchain"
@@ -30,6 +35,7 @@ import (
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/electrum"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/headers"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/ltc"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/config"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/logging"
@@ -86,6 +92,12 @@ func NewCoin(
 	return coin
 }
 
+// TstSetMakeBlockchain sets the function which creates the blockchain backend when the coin is
+// initialized. Only to be used in tests, e.g. to use a mock blockchain in the backend tests.
+func (coin *Coin) TstSetMakeBlockchain(f func() blockchain.Interface) {
+	coin.makeBlockchain = f
+}
+
 // Initialize implements coin.Coin.
 func (coin *Coin) Initialize() {
 	coin.initOnce.Do(func() {
@@ -190,10 +202,13 @@ func (coin *Coin) SmallestUnit() string {
 }
 
 // DecodeAddress decodes a btc/ltc address, checking that that the format matches the account coin
-// type.
+// type. errors.ErrMWEBNotSupported is returned for Litecoin MWEB addresses.
 func (coin *Coin) DecodeAddress(address string) (btcutil.Address, error) {
 	btcAddress, err := btcutil.DecodeAddress(address, coin.Net())
 	if err != nil {
+		if ltc.IsMWEBAddress(address, coin.Net()) {
+			return nil, errp.WithStack(errors.ErrMWEBNotSupported)
+		}
 		return nil, errp.WithStack(errors.ErrInvalidAddress)
 	}
 	if !btcAddress.IsForNet(coin.Net()) {
@@ -202,6 +217,103 @@ func (coin *Coin) DecodeAddress(address string) (btcutil.Address, error) {
 	return btcAddress, nil
 }
 
+// BroadcastRawTransaction broadcasts a signed transaction given as serialized hex and returns its
+// transaction ID. errors.ErrInvalidData is returned if the transaction can't be decoded. The coin
+// must be initialized.
+func (coin *Coin) BroadcastRawTransaction(rawTxHex string) (string, error) {
+	rawTx, err := hex.DecodeString(strings.TrimSpace(rawTxHex))
+	if err != nil {
+		return "", errp.WithStack(errors.ErrInvalidData)
+	}
+	tx := wire.NewMsgTx(wire.TxVersion)
+	if err := tx.Deserialize(bytes.NewReader(rawTx)); err != nil || tx.SerializeSize() != len(rawTx) {
+		return "", errp.WithStack(errors.ErrInvalidData)
+	}
+	if coin.blockchain == nil {
+		return "", errp.New("coin not initialized")
+	}
+	if err := coin.blockchain.TransactionBroadcast(tx); err != nil {
+		return "", err
+	}
+	return tx.TxHash().String(), nil
+}
+
+// AddressBalance returns the balance of an arbitrary address in satoshi, including unconfirmed
+// transactions. It is computed from the history of the address as reported by the blockchain
+// backend, without persisting anything. errors.ErrInvalidAddress is returned if the address is not
+// valid for this coin. The coin must be initialized.
+func (coin *Coin) AddressBalance(address string) (*big.Int, error) {
+	btcAddress, err := coin.DecodeAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	pkScript, err := txscript.PayToAddrScript(btcAddress)
+	if err != nil {
+		return nil, errp.WithStack(errors.ErrInvalidAddress)
+	}
+	if coin.blockchain == nil {
+		return nil, errp.New("coin not initialized")
+	}
+
+	var history blockchain.TxHistory
+	historyDone := make(chan error, 1)
+	coin.blockchain.ScriptHashGetHistory(
+		blockchain.ScriptHashHex(chainhash.HashH(pkScript).String()),
+		func(txHistory blockchain.TxHistory) error {
+			history = txHistory
+			return nil
+		},
+		func(err error) { historyDone <- err },
+	)
+	if err := <-historyDone; err != nil {
+		return nil, err
+	}
+
+	txs := make([]*wire.MsgTx, len(history))
+	txsDone := make(chan error, len(history))
+	for index, entry := range history {
+		index := index
+		txHash := chainhash.Hash(entry.TXHash)
+		coin.blockchain.TransactionGet(
+			txHash,
+			func(tx *wire.MsgTx) error {
+				if tx.TxHash() != txHash {
+					return errp.Newf("received tx %s, expected %s", tx.TxHash(), txHash)
+				}
+				txs[index] = tx
+				return nil
+			},
+			func(err error) { txsDone <- err },
+		)
+	}
+	for range history {
+		if err := <-txsDone; err != nil {
+			return nil, err
+		}
+	}
+
+	// The history contains all txs paying to or spending from the address, so the outputs to the
+	// address which are not spent by any of them are unspent.
+	unspent := map[wire.OutPoint]int64{}
+	for _, tx := range txs {
+		for index, txOut := range tx.TxOut {
+			if bytes.Equal(txOut.PkScript, pkScript) {
+				unspent[wire.OutPoint{Hash: tx.TxHash(), Index: uint32(index)}] = txOut.Value
+			}
+		}
+	}
+	for _, tx := range txs {
+		for _, txIn := range tx.TxIn {
+			delete(unspent, txIn.PreviousOutPoint)
+		}
+	}
+	balance := new(big.Int)
+	for _, value := range unspent {
+		balance.Add(balance, big.NewInt(value))
+	}
+	return balance, nil
+}
+
 // Close implements coin.Coin.
 func (coin *Coin) Close() error {
 	coin.log.Info("closing coin")
@@ -15,10 +15,17 @@
 package btc_test
 
 import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
 	"os"
 	"testing"
 
 	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/errors"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/blockchain"
@@ -47,8 +54,9 @@ type testSuite struct {
 	code, unit string
 	net        *chaincfg.Params
 
-	dbFolder string
-	coin     *btc.Coin
+	dbFolder       string
+	coin           *btc.Coin
+	blockchainMock *blockchainMock.BlockchainMock
 }
 
 func (s *testSuite) SetupTest() {
@@ -56,13 +64,13 @@ func (s *testSuite) SetupTest() {
 
 	s.coin = btc.NewCoin(s.code, s.unit, s.net, s.dbFolder, nil,
 		explorer, socksproxy.NewSocksProxy(false, ""))
-	blockchainMock := &blockchainMock.BlockchainMock{}
-	blockchainMock.MockHeadersSubscribe = func(
+	s.blockchainMock = &blockchainMock.BlockchainMock{}
+	s.blockchainMock.MockHeadersSubscribe = func(
 		setup func() func(error),
 		result func(*blockchain.Header) error) {
 
 	}
-	s.coin.TstSetMakeBlockchain(func() blockchain.Interface { return blockchainMock })
+	s.coin.TstSetMakeBlockchain(func() blockchain.Interface { return s.blockchainMock })
 	s.coin.Initialize()
 }
 
@@ -167,4 +175,109 @@ func (s *testSuite) TestDecodeAddress() {
 		require.Equal(s.T(), errors.ErrInvalidAddress, errp.Cause(err), invalidAddress)
 	}
 
+	// MWEB addresses are recognized, but not supported.
+	mwebAddresses := map[string]string{
+		"ltc":  "ltcmweb1qqgrsu9guyv4rzwplgex4gkmzd9c8wl593jfe4gdg47mtm3xt6tv7qqcdrgnngs2wtd58tq50nj5mds7smh40wpq3rc4ns32jtak8np5n5qyxnzv5",
+		"tltc": "tmweb1qqgrsu9guyv4rzwplgex4gkmzd9c8wl593jfe4gdg47mtm3xt6tv7qqcdrgnngs2wtd58tq50nj5mds7smh40wpq3rc4ns32jtak8np5n5q90j4sr",
+	}
+	for code, mwebAddress := range mwebAddresses {
+		expectedErr := errors.ErrInvalidAddress
+		if code == s.code {
+			expectedErr = errors.ErrMWEBNotSupported
+		}
+		_, err := s.coin.DecodeAddress(mwebAddress)
+		require.Equal(s.T(), expectedErr, errp.Cause(err), mwebAddress)
+	}
+}
+
+func (s *testSuite) TestBroadcastRawTransaction() {
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{1}, 0), nil, [][]byte{{1, 2, 3}}))
+	tx.AddTxOut(wire.NewTxOut(1000, []byte{0x00, 0x14}))
+	var rawTx bytes.Buffer
+	require.NoError(s.T(), tx.Serialize(&rawTx))
+	rawTxHex := hex.EncodeToString(rawTx.Bytes())
+
+	var broadcasted *wire.MsgTx
+	s.blockchainMock.MockTransactionBroadcast = func(tx *wire.MsgTx) error {
+		broadcasted = tx
+		return nil
+	}
+	txID, err := s.coin.BroadcastRawTransaction(rawTxHex)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), tx.TxHash().String(), txID)
+	require.Equal(s.T(), tx.TxHash(), broadcasted.TxHash())
+
+	// Malformed.
+	broadcasted = nil
+	for _, malformed := range []string{"", "not hex", "0100", rawTxHex[:len(rawTxHex)-2], rawTxHex + "00"} {
+		_, err := s.coin.BroadcastRawTransaction(malformed)
+		require.Equal(s.T(), errors.ErrInvalidData, errp.Cause(err))
+	}
+	require.Nil(s.T(), broadcasted)
+
+	// Rejected by the server.
+	s.blockchainMock.MockTransactionBroadcast = func(*wire.MsgTx) error {
+		return errp.New("missing inputs")
+	}
+	_, err = s.coin.BroadcastRawTransaction(rawTxHex)
+	require.EqualError(s.T(), err, "missing inputs")
+}
+
+func (s *testSuite) TestAddressBalance() {
+	address, err := btcutil.NewAddressWitnessPubKeyHash(make([]byte, 20), s.net)
+	require.NoError(s.T(), err)
+	pkScript, err := txscript.PayToAddrScript(address)
+	require.NoError(s.T(), err)
+
+	// tx1 pays 1000 to the address and 5000 elsewhere, tx2 pays 2000 to the address and tx3 spends
+	// the output of tx1.
+	tx1 := wire.NewMsgTx(wire.TxVersion)
+	tx1.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{1}, 0), nil, nil))
+	tx1.AddTxOut(wire.NewTxOut(1000, pkScript))
+	tx1.AddTxOut(wire.NewTxOut(5000, []byte{0x00, 0x14}))
+	tx2 := wire.NewMsgTx(wire.TxVersion)
+	tx2.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{2}, 0), nil, nil))
+	tx2.AddTxOut(wire.NewTxOut(2000, pkScript))
+	tx3 := wire.NewMsgTx(wire.TxVersion)
+	tx3.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: tx1.TxHash(), Index: 0}, nil, nil))
+	tx3.AddTxOut(wire.NewTxOut(900, []byte{0x00, 0x14}))
+	txs := map[chainhash.Hash]*wire.MsgTx{
+		tx1.TxHash(): tx1,
+		tx2.TxHash(): tx2,
+		tx3.TxHash(): tx3,
+	}
+
+	expectedScriptHashHex := blockchain.ScriptHashHex(chainhash.HashH(pkScript).String())
+	s.blockchainMock.MockScriptHashGetHistory = func(
+		scriptHashHex blockchain.ScriptHashHex,
+		success func(blockchain.TxHistory) error,
+		cleanup func(error)) {
+		require.Equal(s.T(), expectedScriptHashHex, scriptHashHex)
+		history := blockchain.TxHistory{}
+		for _, tx := range []*wire.MsgTx{tx1, tx2, tx3} {
+			history = append(history, &blockchain.TxInfo{TXHash: blockchain.TXHash(tx.TxHash())})
+		}
+		cleanup(success(history))
+	}
+	s.blockchainMock.MockTransactionGet = func(
+		txHash chainhash.Hash, success func(*wire.MsgTx) error, cleanup func(error)) {
+		go func() { cleanup(success(txs[txHash])) }()
+	}
+	balance, err := s.coin.AddressBalance(address.EncodeAddress())
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), big.NewInt(2000), balance)
+
+	// Server errors are returned.
+	s.blockchainMock.MockScriptHashGetHistory = func(
+		_ blockchain.ScriptHashHex, _ func(blockchain.TxHistory) error, cleanup func(error)) {
+		cleanup(errp.New("server error"))
+	}
+	_, err = s.coin.AddressBalance(address.EncodeAddress())
+	require.EqualError(s.T(), err, "server error")
+
+	// Invalid addresses are rejected before querying the server.
+	s.blockchainMock.MockScriptHashGetHistory = nil
+	_, err = s.coin.AddressBalance("invalid")
+	require.Equal(s.T(), errors.ErrInvalidAddress, errp.Cause(err))
 }
@@ -16,10 +16,42 @@
 package btc
 
 import (
+	"sync"
+	"time"
+
 	"github.com/btcsuite/btcutil"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/blockchain"
 )
 
+// feeEstimateTimeout is how long to wait for a response of the blockchain backend when estimating
+// fee rates on demand.
+const feeEstimateTimeout = 10 * time.Second
+
+// defaultRelayFeePerKb is used as the minimum fee rate if the blockchain backend does not provide
+// it.
+const defaultRelayFeePerKb = btcutil.Amount(1000)
+
+// feeTargetMultipliers are the fee rates of the fee targets relative to the normal fee target. They
+// are used to derive the fee rates of all targets if the blockchain backend provides only one
+// estimate. The economy fee target is never derived, as a fraction of another estimate might not
+// confirm at all. Without its own estimate, it takes the rate of the low fee target.
+var feeTargetMultipliers = map[accounts.FeeTargetCode]float64{
+	accounts.FeeTargetCodeLow:    0.75,
+	accounts.FeeTargetCodeNormal: 1,
+	accounts.FeeTargetCodeHigh:   1.5,
+}
+
+// newFeeTargets returns the fee targets, sorted by ascending priority, without fee rates.
+func newFeeTargets() []*FeeTarget {
+	return []*FeeTarget{
+		{blocks: 24, code: accounts.FeeTargetCodeEconomy},
+		{blocks: 12, code: accounts.FeeTargetCodeLow},
+		{blocks: 6, code: accounts.FeeTargetCodeNormal},
+		{blocks: 2, code: accounts.FeeTargetCodeHigh},
+	}
+}
+
 // FeeTarget contains the fee rate for a specific fee target.
 type FeeTarget struct {
 	// Blocks is the target number of blocks in which the transaction should be confirmed.
@@ -36,3 +68,139 @@ type FeeTarget struct {
 func (feeTarget *FeeTarget) Code() accounts.FeeTargetCode {
 	return feeTarget.code
 }
+
+// FeeRateEstimate is the estimated fee rate of a fee target.
+type FeeRateEstimate struct {
+	Code accounts.FeeTargetCode
+	// Blocks is the target number of blocks in which the transaction should be confirmed.
+	Blocks       int
+	FeeRatePerKb btcutil.Amount
+}
+
+// estimateFee returns the fee rate estimate of the blockchain backend for the given number of
+// blocks, or nil if it could not be estimated in time.
+func estimateFee(blockchain blockchain.Interface, blocks int) *btcutil.Amount {
+	result := make(chan *btcutil.Amount, 1)
+	blockchain.EstimateFee(
+		blocks,
+		func(feeRatePerKb *btcutil.Amount) error {
+			result <- feeRatePerKb
+			return nil
+		},
+		func(err error) {
+			if err != nil {
+				select {
+				case result <- nil:
+				default:
+				}
+			}
+		},
+	)
+	select {
+	case feeRatePerKb := <-result:
+		return feeRatePerKb
+	case <-time.After(feeEstimateTimeout):
+		return nil
+	}
+}
+
+// relayFee returns the minimum relay fee rate of the blockchain backend, or defaultRelayFeePerKb
+// if it could not be retrieved in time.
+func relayFee(blockchain blockchain.Interface) btcutil.Amount {
+	result := make(chan btcutil.Amount, 1)
+	blockchain.RelayFee(
+		func(feeRatePerKb btcutil.Amount) { result <- feeRatePerKb },
+		func(err error) {
+			if err != nil {
+				select {
+				case result <- defaultRelayFeePerKb:
+				default:
+				}
+			}
+		},
+	)
+	select {
+	case feeRatePerKb := <-result:
+		return feeRatePerKb
+	case <-time.After(feeEstimateTimeout):
+		return defaultRelayFeePerKb
+	}
+}
+
+// EstimateFeeRates queries the blockchain backend for the fee rates of all fee targets at once. It
+// returns the estimates sorted by ascending priority and the minimum relay fee rate, which is the
+// lowest rate a custom fee rate can have. No rate is below the minimum relay fee rate, and no rate
+// is below the rate of a fee target with lower priority. If the backend provides one distinct
+// estimate at most, the rates of the low, normal and high fee targets are derived from it using
+// feeTargetMultipliers, falling back to the minimum relay fee rate if there is no estimate at all.
+func EstimateFeeRates(blockchain blockchain.Interface) ([]*FeeRateEstimate, btcutil.Amount) {
+	feeTargets := newFeeTargets()
+	var minFeeRatePerKb btcutil.Amount
+	var wg sync.WaitGroup
+	wg.Add(len(feeTargets) + 1)
+	go func() {
+		defer wg.Done()
+		minFeeRatePerKb = relayFee(blockchain)
+	}()
+	for _, feeTarget := range feeTargets {
+		feeTarget := feeTarget
+		go func() {
+			defer wg.Done()
+			feeTarget.feeRatePerKb = estimateFee(blockchain, feeTarget.blocks)
+		}()
+	}
+	wg.Wait()
+	distinct := map[btcutil.Amount]struct{}{}
+	for _, feeTarget := range feeTargets {
+		if feeTarget.feeRatePerKb != nil {
+			distinct[*feeTarget.feeRatePerKb] = struct{}{}
+		}
+	}
+	// base is the rate of the normal fee target. A single estimate is taken as the normal rate.
+	base := minFeeRatePerKb
+	for feeRatePerKb := range distinct {
+		base = feeRatePerKb
+	}
+	if len(distinct) > 1 {
+		for _, feeTarget := range feeTargets {
+			if feeTarget.feeRatePerKb != nil && feeTarget.code != accounts.FeeTargetCodeEconomy {
+				base = btcutil.Amount(float64(*feeTarget.feeRatePerKb) / feeTargetMultipliers[feeTarget.code])
+				if feeTarget.code == accounts.FeeTargetCodeNormal {
+					break
+				}
+			}
+		}
+	}
+	synthesize := len(distinct) <= 1
+	feeRates := make([]btcutil.Amount, len(feeTargets))
+	// In descending priority, so the economy fee target can take the rate of the low fee target.
+	for index := len(feeTargets) - 1; index >= 0; index-- {
+		feeTarget := feeTargets[index]
+		switch {
+		case feeTarget.code == accounts.FeeTargetCodeEconomy && feeTarget.feeRatePerKb != nil:
+			feeRates[index] = *feeTarget.feeRatePerKb
+		case feeTarget.code == accounts.FeeTargetCodeEconomy:
+			feeRates[index] = feeRates[index+1]
+		case synthesize || feeTarget.feeRatePerKb == nil:
+			feeRates[index] = btcutil.Amount(float64(base) * feeTargetMultipliers[feeTarget.code])
+		default:
+			feeRates[index] = *feeTarget.feeRatePerKb
+		}
+	}
+	result := make([]*FeeRateEstimate, len(feeTargets))
+	for index, feeTarget := range feeTargets {
+		feeRatePerKb := feeRates[index]
+		if feeRatePerKb < minFeeRatePerKb {
+			feeRatePerKb = minFeeRatePerKb
+		}
+		if index > 0 && feeRatePerKb < result[index-1].FeeRatePerKb {
+			feeRatePerKb = result[index-1].FeeRatePerKb
+		}
+		result[index] = &FeeRateEstimate{
+			Code:         feeTarget.code,
+			Blocks:       feeTarget.blocks,
+			FeeRatePerKb: feeRatePerKb,
+		}
+	}
+	return result, minFeeRatePerKb
+}
@@ -0,0 +1,151 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package btc_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcutil"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc"
+	blockchainMock "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/blockchain/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+// feeBlockchain returns a blockchain mock estimating the given fee rates per number of blocks. For
+// all other numbers of blocks, the fee can't be estimated.
+func feeBlockchain(relayFee btcutil.Amount, estimates map[int]btcutil.Amount) *blockchainMock.BlockchainMock {
+	return &blockchainMock.BlockchainMock{
+		MockRelayFee: func(success func(btcutil.Amount), cleanup func(error)) {
+			success(relayFee)
+			cleanup(nil)
+		},
+		MockEstimateFee: func(blocks int, success func(*btcutil.Amount) error, cleanup func(error)) {
+			estimate, ok := estimates[blocks]
+			if !ok {
+				_ = success(nil)
+			} else {
+				_ = success(&estimate)
+			}
+			cleanup(nil)
+		},
+	}
+}
+
+func feeRates(estimates []*btc.FeeRateEstimate) map[accounts.FeeTargetCode]btcutil.Amount {
+	result := map[accounts.FeeTargetCode]btcutil.Amount{}
+	for _, estimate := range estimates {
+		result[estimate.Code] = estimate.FeeRatePerKb
+	}
+	return result
+}
+
+func TestEstimateFeeRates(t *testing.T) {
+	estimates, minFeeRatePerKb := btc.EstimateFeeRates(feeBlockchain(1000,
+		map[int]btcutil.Amount{24: 2000, 12: 4000, 6: 10000, 2: 30000}))
+	require.Equal(t, btcutil.Amount(1000), minFeeRatePerKb)
+	require.Equal(t, []*btc.FeeRateEstimate{
+		{Code: accounts.FeeTargetCodeEconomy, Blocks: 24, FeeRatePerKb: 2000},
+		{Code: accounts.FeeTargetCodeLow, Blocks: 12, FeeRatePerKb: 4000},
+		{Code: accounts.FeeTargetCodeNormal, Blocks: 6, FeeRatePerKb: 10000},
+		{Code: accounts.FeeTargetCodeHigh, Blocks: 2, FeeRatePerKb: 30000},
+	}, estimates)
+
+	// Missing estimates are derived from the normal fee rate. The economy rate is never derived
+	// from another estimate, it takes the low rate.
+	estimates, _ = btc.EstimateFeeRates(feeBlockchain(1000,
+		map[int]btcutil.Amount{6: 10000, 2: 30000}))
+	require.Equal(t, map[accounts.FeeTargetCode]btcutil.Amount{
+		accounts.FeeTargetCodeEconomy: 7500,
+		accounts.FeeTargetCodeLow:     7500,
+		accounts.FeeTargetCodeNormal:  10000,
+		accounts.FeeTargetCodeHigh:    30000,
+	}, feeRates(estimates))
+
+	// A backend returning the same estimate for all targets. The economy estimate is used as is, and
+	// no rate is below it.
+	estimates, _ = btc.EstimateFeeRates(feeBlockchain(1000,
+		map[int]btcutil.Amount{24: 8000, 12: 8000, 6: 8000, 2: 8000}))
+	require.Equal(t, map[accounts.FeeTargetCode]btcutil.Amount{
+		accounts.FeeTargetCodeEconomy: 8000,
+		accounts.FeeTargetCodeLow:     8000,
+		accounts.FeeTargetCodeNormal:  8000,
+		accounts.FeeTargetCodeHigh:    12000,
+	}, feeRates(estimates))
+
+	// No estimates: derived from the relay fee, but never below it.
+	estimates, _ = btc.EstimateFeeRates(feeBlockchain(2000, nil))
+	require.Equal(t, map[accounts.FeeTargetCode]btcutil.Amount{
+		accounts.FeeTargetCodeEconomy: 2000,
+		accounts.FeeTargetCodeLow:     2000,
+		accounts.FeeTargetCodeNormal:  2000,
+		accounts.FeeTargetCodeHigh:    3000,
+	}, feeRates(estimates))
+}
+
+func TestEstimateFeeRatesErrors(t *testing.T) {
+	blockchain := &blockchainMock.BlockchainMock{
+		MockRelayFee: func(success func(btcutil.Amount), cleanup func(error)) {
+			cleanup(errors.New("connection failed"))
+		},
+		MockEstimateFee: func(blocks int, success func(*btcutil.Amount) error, cleanup func(error)) {
+			cleanup(errors.New("connection failed"))
+		},
+	}
+	estimates, minFeeRatePerKb := btc.EstimateFeeRates(blockchain)
+	require.Equal(t, btcutil.Amount(1000), minFeeRatePerKb)
+	require.Equal(t, map[accounts.FeeTargetCode]btcutil.Amount{
+		accounts.FeeTargetCodeEconomy: 1000,
+		accounts.FeeTargetCodeLow:     1000,
+		accounts.FeeTargetCodeNormal:  1000,
+		accounts.FeeTargetCodeHigh:    1500,
+	}, feeRates(estimates))
+}
+
+// TestEstimateFeeRatesConcurrently checks that all fee targets are estimated at the same time, so
+// that slow responses of the backend don't add up.
+func TestEstimateFeeRatesConcurrently(t *testing.T) {
+	const numFeeTargets = 4
+	var lock sync.Mutex
+	started := 0
+	allStarted := make(chan struct{})
+	blockchain := feeBlockchain(1000, nil)
+	blockchain.MockEstimateFee = func(blocks int, success func(*btcutil.Amount) error, cleanup func(error)) {
+		lock.Lock()
+		started++
+		if started == numFeeTargets {
+			close(allStarted)
+		}
+		lock.Unlock()
+		select {
+		case <-allStarted:
+			feeRatePerKb := btcutil.Amount(1000 * (100 - blocks))
+			_ = success(&feeRatePerKb)
+		case <-time.After(time.Second):
+			_ = success(nil)
+		}
+		cleanup(nil)
+	}
+	estimates, _ := btc.EstimateFeeRates(blockchain)
+	require.Equal(t, map[accounts.FeeTargetCode]btcutil.Amount{
+		accounts.FeeTargetCodeEconomy: 76000,
+		accounts.FeeTargetCodeLow:     88000,
+		accounts.FeeTargetCodeNormal:  94000,
+		accounts.FeeTargetCodeHigh:    98000,
+	}, feeRates(estimates))
+}
@@ -0,0 +1,96 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package btc
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/util"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// frozenUTXOsFilename is the name of the file in the account files folder which contains the
+// outpoints the user does not want to spend.
+const frozenUTXOsFilename = "frozen-utxos.json"
+
+// loadFrozenUTXOs reads the frozen outpoints from the given file. A missing file means that no
+// outpoint is frozen.
+func loadFrozenUTXOs(filename string) (map[wire.OutPoint]struct{}, error) {
+	result := map[wire.OutPoint]struct{}{}
+	jsonBytes, err := ioutil.ReadFile(filename) // #nosec G304
+	if os.IsNotExist(err) {
+		return result, nil
+	}
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	var outPoints []string
+	if err := json.Unmarshal(jsonBytes, &outPoints); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	for _, outPointString := range outPoints {
+		outPoint, err := util.ParseOutPoint([]byte(outPointString))
+		if err != nil {
+			return nil, err
+		}
+		result[*outPoint] = struct{}{}
+	}
+	return result, nil
+}
+
+// storeFrozenUTXOs writes the frozen outpoints to the given file.
+func storeFrozenUTXOs(filename string, frozenUTXOs map[wire.OutPoint]struct{}) error {
+	outPoints := []string{}
+	for outPoint := range frozenUTXOs {
+		outPoints = append(outPoints, outPoint.String())
+	}
+	sort.Strings(outPoints)
+	jsonBytes, err := json.Marshal(outPoints)
+	if err != nil {
+		return errp.WithStack(err)
+	}
+	return errp.WithStack(ioutil.WriteFile(filename, jsonBytes, 0600))
+}
+
+// SetUTXOFrozen freezes or unfreezes an output of the account. Frozen outputs are not spent by
+// automatic coin selection and can't be selected manually, but they still count towards the
+// balance. The setting is persisted in the account files folder.
+func (account *Account) SetUTXOFrozen(outPoint wire.OutPoint, frozen bool) error {
+	defer account.Lock()()
+	if account.frozenUTXOs == nil {
+		return errp.New("SetUTXOFrozen: account not initialized")
+	}
+	if frozen {
+		account.frozenUTXOs[outPoint] = struct{}{}
+	} else {
+		delete(account.frozenUTXOs, outPoint)
+	}
+	return storeFrozenUTXOs(path.Join(account.dbSubfolder, frozenUTXOsFilename), account.frozenUTXOs)
+}
+
+// frozenOutPoints returns a copy of the set of frozen outputs.
+func (account *Account) frozenOutPoints() map[wire.OutPoint]struct{} {
+	defer account.RLock()()
+	result := make(map[wire.OutPoint]struct{}, len(account.frozenUTXOs))
+	for outPoint := range account.frozenUTXOs {
+		result[outPoint] = struct{}{}
+	}
+	return result
+}
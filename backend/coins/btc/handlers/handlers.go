@@ -18,6 +18,7 @@ import (
 	"encoding/csv"
 	"encoding/hex"
 	"encoding/json"
+	"math/big"
 	"net/http"
 	"os"
 	"path"
@@ -25,6 +26,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/btcsuite/btcutil"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
@@ -57,18 +59,34 @@ func NewHandlers(
 	handleFunc("/init", handlers.postInit).Methods("POST")
 	handleFunc("/status", handlers.getAccountStatus).Methods("GET")
 	handleFunc("/transactions", handlers.ensureAccountInitialized(handlers.getAccountTransactions)).Methods("GET")
+	handleFunc("/transaction", handlers.ensureAccountInitialized(handlers.getAccountTransaction)).Methods("GET")
+	handleFunc("/balance-history", handlers.ensureAccountInitialized(handlers.getBalanceHistory)).Methods("GET")
 	handleFunc("/export", handlers.ensureAccountInitialized(handlers.postExportTransactions)).Methods("POST")
 	handleFunc("/info", handlers.ensureAccountInitialized(handlers.getAccountInfo)).Methods("GET")
 	handleFunc("/utxos", handlers.ensureAccountInitialized(handlers.getUTXOs)).Methods("GET")
+	handleFunc("/utxo-frozen", handlers.ensureAccountInitialized(handlers.postUTXOFrozen)).Methods("POST")
 	handleFunc("/balance", handlers.ensureAccountInitialized(handlers.getAccountBalance)).Methods("GET")
+	handleFunc("/balance-breakdown", handlers.ensureAccountInitialized(handlers.getAccountBalanceBreakdown)).Methods("GET")
 	handleFunc("/sendtx", handlers.ensureAccountInitialized(handlers.postAccountSendTx)).Methods("POST")
 	handleFunc("/fee-targets", handlers.ensureAccountInitialized(handlers.getAccountFeeTargets)).Methods("GET")
 	handleFunc("/tx-proposal", handlers.ensureAccountInitialized(handlers.getAccountTxProposal)).Methods("POST")
+	handleFunc("/send-all-tx-proposal", handlers.ensureAccountInitialized(handlers.postSendAllTxProposal)).Methods("POST")
+	handleFunc("/consolidation-tx-proposal", handlers.ensureAccountInitialized(handlers.postConsolidationTxProposal)).Methods("POST")
+	handleFunc("/send-consolidation-tx", handlers.ensureAccountInitialized(handlers.postSendConsolidationTx)).Methods("POST")
+	handleFunc("/export-psbt", handlers.ensureAccountInitialized(handlers.postExportPSBT)).Methods("POST")
+	handleFunc("/bump-fee-tx-proposal", handlers.ensureAccountInitialized(handlers.postBumpFeeTxProposal)).Methods("POST")
+	handleFunc("/send-bump-fee-tx", handlers.ensureAccountInitialized(handlers.postSendBumpFeeTx)).Methods("POST")
+	handleFunc("/sweep-private-key-proposal", handlers.ensureAccountInitialized(handlers.postSweepPrivateKeyProposal)).Methods("POST")
+	handleFunc("/sweep-private-key", handlers.ensureAccountInitialized(handlers.postSweepPrivateKey)).Methods("POST")
 	handleFunc("/receive-addresses", handlers.ensureAccountInitialized(handlers.getReceiveAddresses)).Methods("GET")
+	handleFunc("/address-used", handlers.ensureAccountInitialized(handlers.getAddressUsed)).Methods("GET")
+	handleFunc("/address-info", handlers.ensureAccountInitialized(handlers.getAddressInfo)).Methods("GET")
+	handleFunc("/refresh", handlers.ensureAccountInitialized(handlers.postRefresh)).Methods("POST")
 	handleFunc("/verify-address", handlers.ensureAccountInitialized(handlers.postVerifyAddress)).Methods("POST")
 	handleFunc("/can-verify-extended-public-key", handlers.ensureAccountInitialized(handlers.getCanVerifyExtendedPublicKey)).Methods("GET")
 	handleFunc("/verify-extended-public-key", handlers.ensureAccountInitialized(handlers.postVerifyExtendedPublicKey)).Methods("POST")
 	handleFunc("/has-secure-output", handlers.ensureAccountInitialized(handlers.getHasSecureOutput)).Methods("GET")
+	handleFunc("/eth-sign-message", handlers.ensureAccountInitialized(handlers.postETHSignMessage)).Methods("POST")
 	handleFunc("/exchange/safello/buy-supported", handlers.ensureAccountInitialized(handlers.getExchangeSafelloBuySupported)).Methods("GET")
 	handleFunc("/exchange/safello/buy", handlers.ensureAccountInitialized(handlers.getExchangeSafelloBuy)).Methods("GET")
 	handleFunc("/exchange/safello/process-message", handlers.ensureAccountInitialized(handlers.postExchangeSafelloProcessMessage)).Methods("POST")
@@ -137,6 +155,75 @@ func (handlers *Handlers) ensureAccountInitialized(h func(*http.Request) (interf
 	}
 }
 
+// TransactionDetail is a transaction with all its inputs and outputs.
+type TransactionDetail struct {
+	Transaction
+	Inputs  []TransactionInput `json:"inputs"`
+	Outputs []AddressAndAmount `json:"outputs"`
+}
+
+// TransactionInput is the JSON representation of accounts.TransactionInput.
+type TransactionInput struct {
+	PreviousOutput string          `json:"previousOutput"`
+	Address        string          `json:"address"`
+	Amount         FormattedAmount `json:"amount"`
+	Ours           bool            `json:"ours"`
+}
+
+// AddressAndAmount is the JSON representation of accounts.AddressAndAmount.
+type AddressAndAmount struct {
+	Address string          `json:"address"`
+	Amount  FormattedAmount `json:"amount"`
+	Ours    bool            `json:"ours"`
+}
+
+func (handlers *Handlers) formatTransaction(txInfo accounts.Transaction) Transaction {
+	var feeString FormattedAmount
+	fee := txInfo.Fee()
+	if fee != nil {
+		feeString = handlers.formatAmountAsJSON(*fee, true)
+	}
+	var formattedTime *string
+	timestamp := txInfo.Timestamp()
+	if timestamp != nil {
+		t := timestamp.Format(time.RFC3339)
+		formattedTime = &t
+	}
+	addresses := []string{}
+	for _, addressAndAmount := range txInfo.Addresses() {
+		addresses = append(addresses, addressAndAmount.Address)
+	}
+	txInfoJSON := Transaction{
+		TxID:                     txInfo.TxID(),
+		InternalID:               txInfo.InternalID(),
+		NumConfirmations:         txInfo.NumConfirmations(),
+		NumConfirmationsComplete: txInfo.NumConfirmationsComplete(),
+		Type: map[accounts.TxType]string{
+			accounts.TxTypeReceive:  "receive",
+			accounts.TxTypeSend:     "send",
+			accounts.TxTypeSendSelf: "send_to_self",
+		}[txInfo.Type()],
+		Status:    txInfo.Status(),
+		Amount:    handlers.formatAmountAsJSON(txInfo.Amount(), false),
+		Fee:       feeString,
+		Time:      formattedTime,
+		Addresses: addresses,
+	}
+	switch specificInfo := txInfo.(type) {
+	case *transactions.TxInfo:
+		txInfoJSON.VSize = specificInfo.VSize
+		txInfoJSON.Size = specificInfo.Size
+		txInfoJSON.Weight = specificInfo.Weight
+		feeRatePerKb := specificInfo.FeeRatePerKb()
+		if feeRatePerKb != nil {
+			txInfoJSON.FeeRatePerKb = handlers.formatBTCAmountAsJSON(*feeRatePerKb, true)
+		}
+	case types.EthereumTransaction:
+		txInfoJSON.Gas = specificInfo.Gas()
+	}
+	return txInfoJSON
+}
+
 func (handlers *Handlers) getAccountTransactions(_ *http.Request) (interface{}, error) {
 	result := []Transaction{}
 	txs, err := handlers.account.Transactions()
@@ -144,50 +231,74 @@ func (handlers *Handlers) getAccountTransactions(_ *http.Request) (interface{},
 		return nil, err
 	}
 	for _, txInfo := range txs {
-		var feeString FormattedAmount
-		fee := txInfo.Fee()
-		if fee != nil {
-			feeString = handlers.formatAmountAsJSON(*fee, true)
-		}
-		var formattedTime *string
-		timestamp := txInfo.Timestamp()
-		if timestamp != nil {
-			t := timestamp.Format(time.RFC3339)
-			formattedTime = &t
-		}
-		addresses := []string{}
-		for _, addressAndAmount := range txInfo.Addresses() {
-			addresses = append(addresses, addressAndAmount.Address)
-		}
-		txInfoJSON := Transaction{
-			TxID:                     txInfo.TxID(),
-			InternalID:               txInfo.InternalID(),
-			NumConfirmations:         txInfo.NumConfirmations(),
-			NumConfirmationsComplete: txInfo.NumConfirmationsComplete(),
-			Type: map[accounts.TxType]string{
-				accounts.TxTypeReceive:  "receive",
-				accounts.TxTypeSend:     "send",
-				accounts.TxTypeSendSelf: "send_to_self",
-			}[txInfo.Type()],
-			Status:    txInfo.Status(),
-			Amount:    handlers.formatAmountAsJSON(txInfo.Amount(), false),
-			Fee:       feeString,
-			Time:      formattedTime,
-			Addresses: addresses,
+		result = append(result, handlers.formatTransaction(txInfo))
+	}
+	return result, nil
+}
+
+func (handlers *Handlers) getAccountTransaction(r *http.Request) (interface{}, error) {
+	internalID := r.URL.Query().Get("internalID")
+	txs, err := handlers.account.Transactions()
+	if err != nil {
+		return nil, err
+	}
+	txInfo := accounts.TransactionByInternalID(txs, internalID)
+	if txInfo == nil {
+		return nil, errp.Newf("unknown transaction %s", internalID)
+	}
+	detail := accounts.NewTransactionDetail(txInfo)
+	inputs := []TransactionInput{}
+	for _, input := range detail.Inputs {
+		transactionInput := TransactionInput{
+			PreviousOutput: input.PreviousOutput,
+			Address:        input.Address,
+			Ours:           input.Ours,
 		}
-		switch specificInfo := txInfo.(type) {
-		case *transactions.TxInfo:
-			txInfoJSON.VSize = specificInfo.VSize
-			txInfoJSON.Size = specificInfo.Size
-			txInfoJSON.Weight = specificInfo.Weight
-			feeRatePerKb := specificInfo.FeeRatePerKb()
-			if feeRatePerKb != nil {
-				txInfoJSON.FeeRatePerKb = handlers.formatBTCAmountAsJSON(*feeRatePerKb, true)
-			}
-		case types.EthereumTransaction:
-			txInfoJSON.Gas = specificInfo.Gas()
+		if input.Ours {
+			transactionInput.Amount = handlers.formatAmountAsJSON(input.Amount, false)
 		}
-		result = append(result, txInfoJSON)
+		inputs = append(inputs, transactionInput)
+	}
+	outputs := []AddressAndAmount{}
+	for _, addressAndAmount := range detail.Outputs {
+		outputs = append(outputs, AddressAndAmount{
+			Address: addressAndAmount.Address,
+			Amount:  handlers.formatAmountAsJSON(addressAndAmount.Amount, false),
+			Ours:    addressAndAmount.Ours,
+		})
+	}
+	return TransactionDetail{
+		Transaction: handlers.formatTransaction(txInfo),
+		Inputs:      inputs,
+		Outputs:     outputs,
+	}, nil
+}
+
+func (handlers *Handlers) getBalanceHistory(r *http.Request) (interface{}, error) {
+	txs, err := handlers.account.Transactions()
+	if err != nil {
+		return nil, err
+	}
+	// The fees of ERC20 token transactions are paid in ether.
+	includeFees := true
+	if ethCoin, ok := handlers.account.Coin().(*eth.Coin); ok && ethCoin.ERC20Token() != nil {
+		includeFees = false
+	}
+	history, err := accounts.BalanceHistory(
+		txs, accounts.Interval(r.URL.Query().Get("interval")), time.Now(), includeFees)
+	if err != nil {
+		return nil, err
+	}
+	type balancePoint struct {
+		Time    string          `json:"time"`
+		Balance FormattedAmount `json:"balance"`
+	}
+	result := []balancePoint{}
+	for _, point := range history {
+		result = append(result, balancePoint{
+			Time:    point.Time.Format(time.RFC3339),
+			Balance: handlers.formatAmountAsJSON(point.Balance, false),
+		})
 	}
 	return result, nil
 }
@@ -289,15 +400,36 @@ func (handlers *Handlers) getUTXOs(_ *http.Request) (interface{}, error) {
 	for _, output := range t.SpendableOutputs() {
 		result = append(result,
 			map[string]interface{}{
-				"outPoint": output.OutPoint.String(),
-				"amount":   handlers.formatBTCAmountAsJSON(btcutil.Amount(output.TxOut.Value), false),
-				"address":  output.Address,
+				"outPoint":      output.OutPoint.String(),
+				"amount":        handlers.formatBTCAmountAsJSON(btcutil.Amount(output.TxOut.Value), false),
+				"address":       output.Address,
+				"confirmations": output.NumConfirmations,
+				"frozen":        output.Frozen,
 			})
 	}
 
 	return result, nil
 }
 
+func (handlers *Handlers) postUTXOFrozen(r *http.Request) (interface{}, error) {
+	btcAccount, ok := handlers.account.(*btc.Account)
+	if !ok {
+		return nil, errp.New("Interface must be of type btc.Account")
+	}
+	var input struct {
+		OutPoint string `json:"outPoint"`
+		Frozen   bool   `json:"frozen"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	outPoint, err := util.ParseOutPoint([]byte(input.OutPoint))
+	if err != nil {
+		return nil, err
+	}
+	return nil, btcAccount.SetUTXOFrozen(*outPoint, input.Frozen)
+}
+
 func (handlers *Handlers) getAccountBalance(_ *http.Request) (interface{}, error) {
 	balance, err := handlers.account.Balance()
 	if err != nil {
@@ -310,6 +442,19 @@ func (handlers *Handlers) getAccountBalance(_ *http.Request) (interface{}, error
 	}, nil
 }
 
+func (handlers *Handlers) getAccountBalanceBreakdown(_ *http.Request) (interface{}, error) {
+	breakdown, err := handlers.account.BalanceBreakdown()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"total":     handlers.formatAmountAsJSON(breakdown.Total, false),
+		"spendable": handlers.formatAmountAsJSON(breakdown.Spendable, false),
+		"incoming":  handlers.formatAmountAsJSON(breakdown.Incoming, false),
+		"frozen":    handlers.formatAmountAsJSON(breakdown.Frozen, false),
+	}, nil
+}
+
 type sendTxInput struct {
 	address       string
 	sendAmount    coin.SendAmount
@@ -410,6 +555,262 @@ func (handlers *Handlers) getAccountTxProposal(r *http.Request) (interface{}, er
 	}, nil
 }
 
+func (handlers *Handlers) postExportPSBT(r *http.Request) (interface{}, error) {
+	btcAccount, ok := handlers.account.(*btc.Account)
+	if !ok {
+		return nil, errp.New("Interface must be of type btc.Account")
+	}
+	var input sendTxInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		return txProposalError(errp.WithStack(err))
+	}
+	encodedPSBT, err := btcAccount.ExportPSBT(
+		input.address,
+		input.sendAmount,
+		input.feeTargetCode,
+		input.selectedUTXOs,
+	)
+	if err != nil {
+		return txProposalError(err)
+	}
+	return map[string]interface{}{
+		"success": true,
+		"psbt":    encodedPSBT,
+	}, nil
+}
+
+type consolidationTxInput struct {
+	feeTargetCode accounts.FeeTargetCode
+	maxInputs     int
+}
+
+func (input *consolidationTxInput) UnmarshalJSON(jsonBytes []byte) error {
+	jsonBody := struct {
+		FeeTarget string `json:"feeTarget"`
+		MaxInputs int    `json:"maxInputs"`
+	}{}
+	if err := json.Unmarshal(jsonBytes, &jsonBody); err != nil {
+		return errp.WithStack(err)
+	}
+	var err error
+	input.feeTargetCode, err = accounts.NewFeeTargetCode(jsonBody.FeeTarget)
+	if err != nil {
+		return errp.WithMessage(err, "Failed to retrieve fee target code")
+	}
+	input.maxInputs = jsonBody.MaxInputs
+	return nil
+}
+
+func (handlers *Handlers) postConsolidationTxProposal(r *http.Request) (interface{}, error) {
+	btcAccount, ok := handlers.account.(*btc.Account)
+	if !ok {
+		return nil, errp.New("Interface must be of type btc.Account")
+	}
+	var input consolidationTxInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		return txProposalError(errp.WithStack(err))
+	}
+	amount, fee, err := btcAccount.ConsolidationTxProposal(input.feeTargetCode, input.maxInputs)
+	if err != nil {
+		return txProposalError(err)
+	}
+	return map[string]interface{}{
+		"success": true,
+		"amount":  handlers.formatAmountAsJSON(amount, false),
+		"fee":     handlers.formatAmountAsJSON(fee, true),
+	}, nil
+}
+
+func (handlers *Handlers) postSendConsolidationTx(r *http.Request) (interface{}, error) {
+	btcAccount, ok := handlers.account.(*btc.Account)
+	if !ok {
+		return nil, errp.New("Interface must be of type btc.Account")
+	}
+	var input consolidationTxInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	err := btcAccount.SendConsolidationTx(input.feeTargetCode, input.maxInputs)
+	if errp.Cause(err) == keystore.ErrSigningAborted {
+		return map[string]interface{}{"success": false, "aborted": true}, nil
+	}
+	if err != nil {
+		return map[string]interface{}{"success": false, "errorMessage": err.Error()}, nil
+	}
+	return map[string]interface{}{"success": true}, nil
+}
+
+type sendAllTxInput struct {
+	address string
+	// feeRate is in sat/vbyte for BTC and LTC, and the gas price in Gwei for ETH.
+	feeRate float64
+}
+
+func (input *sendAllTxInput) UnmarshalJSON(jsonBytes []byte) error {
+	jsonBody := struct {
+		Address string  `json:"address"`
+		FeeRate float64 `json:"feeRate"`
+	}{}
+	if err := json.Unmarshal(jsonBytes, &jsonBody); err != nil {
+		return errp.WithStack(err)
+	}
+	if jsonBody.FeeRate <= 0 {
+		return errp.New("Fee rate must be positive")
+	}
+	input.address = strings.TrimSpace(jsonBody.Address)
+	input.feeRate = jsonBody.FeeRate
+	return nil
+}
+
+func (handlers *Handlers) postSendAllTxProposal(r *http.Request) (interface{}, error) {
+	var input sendAllTxInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		return txProposalError(errp.WithStack(err))
+	}
+	var amount, fee coin.Amount
+	var err error
+	switch specificAccount := handlers.account.(type) {
+	case *btc.Account:
+		amount, fee, err = specificAccount.SendAllTxProposal(
+			input.address, btcutil.Amount(input.feeRate*1000))
+	case *eth.Account:
+		gasPrice, _ := new(big.Float).Mul(big.NewFloat(input.feeRate), big.NewFloat(1e9)).Int(nil)
+		amount, fee, err = specificAccount.SendAllTxProposal(input.address, gasPrice)
+	default:
+		return nil, errp.New("Unsupported account type")
+	}
+	if err != nil {
+		return txProposalError(err)
+	}
+	return map[string]interface{}{
+		"success": true,
+		"amount":  handlers.formatAmountAsJSON(amount, false),
+		"fee":     handlers.formatAmountAsJSON(fee, true),
+	}, nil
+}
+
+type bumpFeeTxInput struct {
+	txHash       chainhash.Hash
+	feeRatePerKb btcutil.Amount
+}
+
+func (input *bumpFeeTxInput) UnmarshalJSON(jsonBytes []byte) error {
+	jsonBody := struct {
+		TxHash string `json:"txHash"`
+		// FeeRate is the new fee rate in satoshi per vbyte.
+		FeeRate int64 `json:"feeRate"`
+	}{}
+	if err := json.Unmarshal(jsonBytes, &jsonBody); err != nil {
+		return errp.WithStack(err)
+	}
+	txHash, err := chainhash.NewHashFromStr(jsonBody.TxHash)
+	if err != nil {
+		return errp.WithStack(err)
+	}
+	if jsonBody.FeeRate <= 0 {
+		return errp.New("Fee rate must be positive")
+	}
+	input.txHash = *txHash
+	input.feeRatePerKb = btcutil.Amount(jsonBody.FeeRate * 1000)
+	return nil
+}
+
+func (handlers *Handlers) postBumpFeeTxProposal(r *http.Request) (interface{}, error) {
+	btcAccount, ok := handlers.account.(*btc.Account)
+	if !ok {
+		return nil, errp.New("Interface must be of type btc.Account")
+	}
+	var input bumpFeeTxInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		return txProposalError(errp.WithStack(err))
+	}
+	amount, fee, err := btcAccount.BumpFeeTxProposal(input.txHash, input.feeRatePerKb)
+	if err != nil {
+		return txProposalError(err)
+	}
+	return map[string]interface{}{
+		"success": true,
+		"amount":  handlers.formatAmountAsJSON(amount, false),
+		"fee":     handlers.formatAmountAsJSON(fee, true),
+	}, nil
+}
+
+func (handlers *Handlers) postSendBumpFeeTx(r *http.Request) (interface{}, error) {
+	btcAccount, ok := handlers.account.(*btc.Account)
+	if !ok {
+		return nil, errp.New("Interface must be of type btc.Account")
+	}
+	var input bumpFeeTxInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	err := btcAccount.SendBumpFeeTx(input.txHash, input.feeRatePerKb)
+	if errp.Cause(err) == keystore.ErrSigningAborted {
+		return map[string]interface{}{"success": false, "aborted": true}, nil
+	}
+	if err != nil {
+		return map[string]interface{}{"success": false, "errorMessage": err.Error()}, nil
+	}
+	return map[string]interface{}{"success": true}, nil
+}
+
+type sweepPrivateKeyInput struct {
+	wif          string
+	feeRatePerKb btcutil.Amount
+}
+
+func (input *sweepPrivateKeyInput) UnmarshalJSON(jsonBytes []byte) error {
+	jsonBody := struct {
+		WIF string `json:"wif"`
+		// FeeRate is the fee rate in satoshi per vbyte.
+		FeeRate int64 `json:"feeRate"`
+	}{}
+	if err := json.Unmarshal(jsonBytes, &jsonBody); err != nil {
+		return errp.WithStack(err)
+	}
+	if jsonBody.FeeRate <= 0 {
+		return errp.New("Fee rate must be positive")
+	}
+	input.wif = strings.TrimSpace(jsonBody.WIF)
+	input.feeRatePerKb = btcutil.Amount(jsonBody.FeeRate * 1000)
+	return nil
+}
+
+func (handlers *Handlers) postSweepPrivateKeyProposal(r *http.Request) (interface{}, error) {
+	btcAccount, ok := handlers.account.(*btc.Account)
+	if !ok {
+		return nil, errp.New("Interface must be of type btc.Account")
+	}
+	var input sweepPrivateKeyInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		return txProposalError(errp.WithStack(err))
+	}
+	amount, fee, err := btcAccount.SweepPrivateKeyProposal(input.wif, input.feeRatePerKb)
+	if err != nil {
+		return txProposalError(err)
+	}
+	return map[string]interface{}{
+		"success": true,
+		"amount":  handlers.formatAmountAsJSON(amount, false),
+		"fee":     handlers.formatAmountAsJSON(fee, true),
+	}, nil
+}
+
+func (handlers *Handlers) postSweepPrivateKey(r *http.Request) (interface{}, error) {
+	btcAccount, ok := handlers.account.(*btc.Account)
+	if !ok {
+		return nil, errp.New("Interface must be of type btc.Account")
+	}
+	var input sweepPrivateKeyInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	if err := btcAccount.SweepPrivateKey(input.wif, input.feeRatePerKb); err != nil {
+		return map[string]interface{}{"success": false, "errorMessage": err.Error()}, nil
+	}
+	return map[string]interface{}{"success": true}, nil
+}
+
 func (handlers *Handlers) getAccountFeeTargets(_ *http.Request) (interface{}, error) {
 	feeTargets, defaultFeeTarget := handlers.account.FeeTargets()
 	result := []map[string]interface{}{}
@@ -466,6 +867,26 @@ func (handlers *Handlers) getReceiveAddresses(_ *http.Request) (interface{}, err
 	return addresses, nil
 }
 
+func (handlers *Handlers) getAddressUsed(r *http.Request) (interface{}, error) {
+	return handlers.account.AddressUsed(r.URL.Query().Get("addressID"))
+}
+
+func (handlers *Handlers) postRefresh(_ *http.Request) (interface{}, error) {
+	return nil, handlers.account.Refresh()
+}
+
+func (handlers *Handlers) getAddressInfo(r *http.Request) (interface{}, error) {
+	address := r.URL.Query().Get("address")
+	switch specificAccount := handlers.account.(type) {
+	case *btc.Account:
+		return specificAccount.AddressInfo(address)
+	case *eth.Account:
+		return specificAccount.AddressInfo(address)
+	default:
+		return nil, errp.New("The account does not support address infos")
+	}
+}
+
 func (handlers *Handlers) postVerifyAddress(r *http.Request) (interface{}, error) {
 	var addressID string
 	if err := json.NewDecoder(r.Body).Decode(&addressID); err != nil {
@@ -509,6 +930,28 @@ func (handlers *Handlers) getHasSecureOutput(r *http.Request) (interface{}, erro
 	}, nil
 }
 
+func (handlers *Handlers) postETHSignMessage(r *http.Request) (interface{}, error) {
+	var message string
+	if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	ethAccount, ok := handlers.account.(*eth.Account)
+	if !ok {
+		return nil, errp.New("An account must be ETH based to support message signing")
+	}
+	signature, err := ethAccount.SignMessage([]byte(message))
+	if errp.Cause(err) == keystore.ErrSigningAborted {
+		return map[string]interface{}{"success": false, "aborted": true}, nil
+	}
+	if err != nil {
+		return map[string]interface{}{"success": false, "errorMessage": err.Error()}, nil
+	}
+	return map[string]interface{}{
+		"success":   true,
+		"signature": "0x" + hex.EncodeToString(signature),
+	}, nil
+}
+
 func (handlers *Handlers) getExchangeSafelloBuySupported(r *http.Request) (interface{}, error) {
 	return handlers.account.SafelloBuySupported(), nil
 }
@@ -48,3 +48,9 @@ func isDustAmount(
 	// (output size + input size) is greater than 1/3 of the relay fee.
 	return int64(amount)*1000/(3*int64(totalSize)) < int64(relayFeePerKb)
 }
+
+// IsDust returns true if an output sending the given amount to the given address would be
+// considered dust.
+func IsDust(amount btcutil.Amount, address *addresses.AccountAddress, relayFeePerKb btcutil.Amount) bool {
+	return isDustAmount(amount, len(address.PubkeyScript()), address.Configuration, relayFeePerKb)
+}
@@ -29,6 +29,30 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// rbfSequence is the sequence number set on all inputs, signaling that the tx can be replaced by a tx
+// paying a higher fee (BIP125).
+const rbfSequence = wire.MaxTxInSequenceNum - 2
+
+// incrementalRelayFeePerKb is the minimum fee rate by which a replacement tx has to increase the fee
+// to be relayed (BIP125 rule 4). This is the default of Bitcoin Core.
+const incrementalRelayFeePerKb = btcutil.Amount(1000)
+
+// SignalsRBF returns true if the tx can be replaced according to BIP125.
+func SignalsRBF(tx *wire.MsgTx) bool {
+	for _, txIn := range tx.TxIn {
+		if txIn.Sequence < wire.MaxTxInSequenceNum-1 {
+			return true
+		}
+	}
+	return false
+}
+
+func newTxIn(outPoint *wire.OutPoint) *wire.TxIn {
+	txIn := wire.NewTxIn(outPoint, nil, nil)
+	txIn.Sequence = rbfSequence
+	return txIn
+}
+
 // TxProposal is the data needed for a new transaction to be able to display it and sign it.
 type TxProposal struct {
 	// Coin is the coin this tx was made for.
@@ -104,11 +128,12 @@ func NewTxSpendAll(
 		outPoint := outPoint // avoid reference reuse due to range loop
 		selectedOutPoints = append(selectedOutPoints, outPoint)
 		outputsSum += btcutil.Amount(output.Value)
-		inputs = append(inputs, wire.NewTxIn(&outPoint, nil, nil))
+		inputs = append(inputs, newTxIn(&outPoint))
 	}
 	txSize := estimateTxSize(len(selectedOutPoints), inputConfiguration, len(outputPkScript), 0)
 	maxRequiredFee := feeForSerializeSize(feePerKb, txSize, log)
-	if outputsSum < maxRequiredFee {
+	if outputsSum <= maxRequiredFee ||
+		isDustAmount(outputsSum-maxRequiredFee, len(outputPkScript), inputConfiguration, feePerKb) {
 		return nil, errp.WithStack(errors.ErrInsufficientFunds)
 	}
 	output := wire.NewTxOut(int64(outputsSum-maxRequiredFee), outputPkScript)
@@ -168,7 +193,7 @@ func NewTx(
 		inputs := make([]*wire.TxIn, len(selectedOutPoints))
 		for i, outPoint := range selectedOutPoints {
 			outPoint := outPoint // avoids referencing the same variable across loop iterations
-			inputs[i] = wire.NewTxIn(&outPoint, nil, nil)
+			inputs[i] = newTxIn(&outPoint)
 		}
 		unsignedTransaction := &wire.MsgTx{
 			Version:  wire.TxVersion,
@@ -202,3 +227,158 @@ func NewTx(
 		}, nil
 	}
 }
+
+// NewTxConsolidation creates a transaction which spends unspent outputs to a single output, usually
+// an address of the same account, to reduce the number of unspent outputs and the fees of future
+// transactions. Outputs which are worth less than the fee needed to spend them are skipped. If
+// maxInputs is positive, at most that many outputs are spent, the smallest ones first.
+func NewTxConsolidation(
+	coin coin.Coin,
+	inputConfiguration *signing.Configuration,
+	spendableOutputs map[wire.OutPoint]*wire.TxOut,
+	maxInputs int,
+	outputAddress *addresses.AccountAddress,
+	feePerKb btcutil.Amount,
+	log *logrus.Entry,
+) (*TxProposal, error) {
+	inputFee := feeForSerializeSize(
+		feePerKb,
+		estimateTxSize(2, inputConfiguration, 0, 0)-estimateTxSize(1, inputConfiguration, 0, 0),
+		log)
+	outPoints := []wire.OutPoint{}
+	for outPoint, output := range spendableOutputs {
+		if btcutil.Amount(output.Value) <= inputFee {
+			continue
+		}
+		outPoints = append(outPoints, outPoint)
+	}
+	sort.Sort(&byValue{outPoints, spendableOutputs})
+	if maxInputs > 0 && len(outPoints) > maxInputs {
+		outPoints = outPoints[:maxInputs]
+	}
+	if len(outPoints) == 0 {
+		return nil, errp.WithStack(errors.ErrInsufficientFunds)
+	}
+
+	inputs := make([]*wire.TxIn, len(outPoints))
+	outputsSum := btcutil.Amount(0)
+	for i, outPoint := range outPoints {
+		outPoint := outPoint // avoids referencing the same variable across loop iterations
+		inputs[i] = newTxIn(&outPoint)
+		outputsSum += btcutil.Amount(spendableOutputs[outPoint].Value)
+	}
+	outputPkScript := outputAddress.PubkeyScript()
+	txSize := estimateTxSize(len(outPoints), inputConfiguration, len(outputPkScript), 0)
+	fee := feeForSerializeSize(feePerKb, txSize, log)
+	if outputsSum <= fee ||
+		isDustAmount(outputsSum-fee, len(outputPkScript), outputAddress.Configuration, feePerKb) {
+		return nil, errp.WithStack(errors.ErrInsufficientFunds)
+	}
+	output := wire.NewTxOut(int64(outputsSum-fee), outputPkScript)
+	unsignedTransaction := &wire.MsgTx{
+		Version:  wire.TxVersion,
+		TxIn:     inputs,
+		TxOut:    []*wire.TxOut{output},
+		LockTime: 0,
+	}
+	txsort.InPlaceSort(unsignedTransaction)
+	log.WithField("fee", fee).WithField("inputs", len(inputs)).Debug("Preparing consolidation transaction")
+	return &TxProposal{
+		Coin:                 coin,
+		AccountConfiguration: inputConfiguration,
+		Amount:               btcutil.Amount(output.Value),
+		Fee:                  fee,
+		Transaction:          unsignedTransaction,
+	}, nil
+}
+
+// NewTxBumpFee creates a tx which replaces an unconfirmed tx with one paying a higher fee (BIP125).
+// The replacement spends all inputs of the original tx and pays the same amount to the same
+// output. The fee is paid from the change. If the inputs do not cover the new fee, additional
+// outputs from spendableOutputs are added, the largest first. The fee is at least the original
+// fee plus the incremental relay fee for the size of the replacement, as required for it to be
+// relayed.
+func NewTxBumpFee(
+	coin coin.Coin,
+	inputConfiguration *signing.Configuration,
+	originalInputs map[wire.OutPoint]*wire.TxOut,
+	originalFee btcutil.Amount,
+	output *wire.TxOut,
+	spendableOutputs map[wire.OutPoint]*wire.TxOut,
+	feePerKb btcutil.Amount,
+	getChangeAddress func() *addresses.AccountAddress,
+	log *logrus.Entry,
+) (*TxProposal, error) {
+	selectedOutPoints := []wire.OutPoint{}
+	selectedOutputsSum := btcutil.Amount(0)
+	for outPoint, txOut := range originalInputs {
+		selectedOutPoints = append(selectedOutPoints, outPoint)
+		selectedOutputsSum += btcutil.Amount(txOut.Value)
+	}
+	additionalOutPoints := []wire.OutPoint{}
+	for outPoint := range spendableOutputs {
+		if _, ok := originalInputs[outPoint]; ok {
+			continue
+		}
+		additionalOutPoints = append(additionalOutPoints, outPoint)
+	}
+	sort.Sort(sort.Reverse(&byValue{additionalOutPoints, spendableOutputs}))
+
+	targetAmount := btcutil.Amount(output.Value)
+	changeAddress := getChangeAddress()
+	changePKScript := changeAddress.PubkeyScript()
+	var fee btcutil.Amount
+	for {
+		txSize := estimateTxSize(len(selectedOutPoints), inputConfiguration, len(output.PkScript), len(changePKScript))
+		fee = feeForSerializeSize(feePerKb, txSize, log)
+		minFee := originalFee + feeForSerializeSize(incrementalRelayFeePerKb, txSize, log)
+		if fee < minFee {
+			fee = minFee
+		}
+		if selectedOutputsSum >= targetAmount+fee {
+			break
+		}
+		if len(additionalOutPoints) == 0 {
+			return nil, errp.WithStack(errors.ErrInsufficientFunds)
+		}
+		outPoint := additionalOutPoints[0]
+		additionalOutPoints = additionalOutPoints[1:]
+		selectedOutPoints = append(selectedOutPoints, outPoint)
+		selectedOutputsSum += btcutil.Amount(spendableOutputs[outPoint].Value)
+	}
+
+	inputs := make([]*wire.TxIn, len(selectedOutPoints))
+	for i, outPoint := range selectedOutPoints {
+		outPoint := outPoint // avoids referencing the same variable across loop iterations
+		inputs[i] = newTxIn(&outPoint)
+	}
+	unsignedTransaction := &wire.MsgTx{
+		Version:  wire.TxVersion,
+		TxIn:     inputs,
+		TxOut:    []*wire.TxOut{output},
+		LockTime: 0,
+	}
+	changeAmount := selectedOutputsSum - targetAmount - fee
+	changeIsDust := isDustAmount(
+		changeAmount, len(changePKScript), changeAddress.Configuration, feePerKb)
+	if changeIsDust {
+		log.Info("change is dust")
+		fee = selectedOutputsSum - targetAmount
+	}
+	if changeAmount != 0 && !changeIsDust {
+		unsignedTransaction.TxOut = append(unsignedTransaction.TxOut,
+			wire.NewTxOut(int64(changeAmount), changePKScript))
+	} else {
+		changeAddress = nil
+	}
+	txsort.InPlaceSort(unsignedTransaction)
+	log.WithField("fee", fee).WithField("originalFee", originalFee).Debug("Preparing replacement transaction")
+	return &TxProposal{
+		Coin:                 coin,
+		AccountConfiguration: inputConfiguration,
+		Amount:               targetAmount,
+		Fee:                  fee,
+		Transaction:          unsignedTransaction,
+		ChangeAddress:        changeAddress,
+	}, nil
+}
@@ -167,8 +167,10 @@ func (s *newTxSuite) check(
 	for _, txIn := range tx.TxIn {
 		require.Nil(s.T(), txIn.SignatureScript)
 		require.Nil(s.T(), txIn.Witness)
-		require.Equal(s.T(), wire.MaxTxInSequenceNum, txIn.Sequence)
+		// All inputs opt into replace-by-fee.
+		require.Equal(s.T(), uint32(wire.MaxTxInSequenceNum-2), txIn.Sequence)
 	}
+	require.True(s.T(), maketx.SignalsRBF(tx))
 
 	inputSum := int64(0)
 	for _, txIn := range tx.TxIn {
@@ -292,3 +294,149 @@ func (s *newTxSuite) TestNewTxCoinSelection() {
 	// coins: .5, .3, .1, .1, .9, .8, .6. select .5+.3+.1+.1 to get 1BTC, take .9 to cover the fees.
 	s.check(amount, feePerKb, s.buildUTXO(500*mBTC, 300*mBTC, 100*mBTC, 100*mBTC, 90*mBTC, 80*mBTC, 70*mBTC), s.change(90*mBTC-txSizeFiveInputs), noDust, s.selectCoins(0, 1, 2, 3, 4))
 }
+
+func (s *newTxSuite) TestNewTxSpendAll() {
+	feePerKb := btcutil.Amount(2000) // 2 sat / vbyte
+	utxo := s.buildUTXO(10000, 20000, 30000)
+	txProposal, err := maketx.NewTxSpendAll(
+		tbtc, s.inputConfiguration, utxo, s.outputPkScript, feePerKb, s.log)
+	require.NoError(s.T(), err)
+	tx := txProposal.Transaction
+	require.Len(s.T(), tx.TxIn, 3)
+	// No change output: everything except for the fee is sent to the recipient.
+	require.Len(s.T(), tx.TxOut, 1)
+	require.Nil(s.T(), txProposal.ChangeAddress)
+	require.Equal(s.T(), s.outputPkScript, tx.TxOut[0].PkScript)
+	expectedFee := maketx.TstFeeForSerializeSize(
+		feePerKb,
+		maketx.TstEstimateTxSize(3, s.inputConfiguration, len(s.outputPkScript), 0),
+		s.log)
+	require.Equal(s.T(), expectedFee, txProposal.Fee)
+	require.Equal(s.T(), btcutil.Amount(60000)-expectedFee, txProposal.Amount)
+	require.Equal(s.T(), int64(txProposal.Amount), tx.TxOut[0].Value)
+	require.Equal(s.T(), btcutil.Amount(60000), txProposal.Total())
+
+	// Nothing to spend.
+	_, err = maketx.NewTxSpendAll(
+		tbtc, s.inputConfiguration, s.buildUTXO(), s.outputPkScript, feePerKb, s.log)
+	require.Equal(s.T(), errors.ErrInsufficientFunds, errp.Cause(err))
+	// The fee would consume everything, or leave only dust.
+	for _, value := range []int64{100, int64(expectedFee) / 3, int64(expectedFee)/3 + 200} {
+		_, err = maketx.NewTxSpendAll(
+			tbtc, s.inputConfiguration, s.buildUTXO(value), s.outputPkScript, feePerKb, s.log)
+		require.Equal(s.T(), errors.ErrInsufficientFunds, errp.Cause(err), value)
+	}
+}
+
+func (s *newTxSuite) TestNewTxConsolidation() {
+	feePerKb := btcutil.Amount(1000) // 1 sat / vbyte
+	inputSize := int64(maketx.TstEstimateTxSize(2, s.inputConfiguration, 0, 0) -
+		maketx.TstEstimateTxSize(1, s.inputConfiguration, 0, 0))
+	outputAddress := s.changeAddress
+
+	check := func(utxo map[wire.OutPoint]*wire.TxOut, maxInputs int, selectedCoins map[int]struct{}) {
+		txProposal, err := maketx.NewTxConsolidation(
+			tbtc, s.inputConfiguration, utxo, maxInputs, outputAddress, feePerKb, s.log)
+		require.NoError(s.T(), err)
+		tx := txProposal.Transaction
+		require.Equal(s.T(), tbtc, txProposal.Coin)
+		require.Equal(s.T(), s.inputConfiguration, txProposal.AccountConfiguration)
+		require.Nil(s.T(), txProposal.ChangeAddress)
+
+		// All funds are sent to the single output.
+		require.Len(s.T(), tx.TxOut, 1)
+		require.Equal(s.T(), outputAddress.PubkeyScript(), tx.TxOut[0].PkScript)
+		require.Len(s.T(), tx.TxIn, len(selectedCoins))
+		inputSum := int64(0)
+		for i := range selectedCoins {
+			found := false
+			for _, txIn := range tx.TxIn {
+				if txIn.PreviousOutPoint == s.coin(i) {
+					found = true
+					break
+				}
+			}
+			require.True(s.T(), found, "didn't find coin %d", i)
+			inputSum += utxo[s.coin(i)].Value
+		}
+		expectedFee := maketx.TstFeeForSerializeSize(
+			feePerKb,
+			maketx.TstEstimateTxSize(len(tx.TxIn), s.inputConfiguration, len(outputAddress.PubkeyScript()), 0),
+			s.log)
+		require.Equal(s.T(), expectedFee, txProposal.Fee)
+		require.Equal(s.T(), btcutil.Amount(inputSum)-expectedFee, txProposal.Amount)
+		require.Equal(s.T(), inputSum-int64(expectedFee), tx.TxOut[0].Value)
+	}
+
+	// Coins which are not worth spending are skipped.
+	check(s.buildUTXO(100, inputSize, inputSize+1, 5000, 10000), 0, s.selectCoins(2, 3, 4))
+	// The smallest coins are consolidated first.
+	check(s.buildUTXO(10000, inputSize+1, 5000, 100), 2, s.selectCoins(1, 2))
+	check(s.buildUTXO(10000, 20000, 30000), 5, s.selectCoins(0, 1, 2))
+
+	// Nothing to spend.
+	_, err := maketx.NewTxConsolidation(
+		tbtc, s.inputConfiguration, s.buildUTXO(), 0, outputAddress, feePerKb, s.log)
+	require.Equal(s.T(), errors.ErrInsufficientFunds, errp.Cause(err))
+	_, err = maketx.NewTxConsolidation(
+		tbtc, s.inputConfiguration, s.buildUTXO(100, inputSize), 0, outputAddress, feePerKb, s.log)
+	require.Equal(s.T(), errors.ErrInsufficientFunds, errp.Cause(err))
+	// The resulting output would be dust.
+	_, err = maketx.NewTxConsolidation(
+		tbtc, s.inputConfiguration, s.buildUTXO(inputSize+100, inputSize+100), 0, outputAddress, feePerKb, s.log)
+	require.Equal(s.T(), errors.ErrInsufficientFunds, errp.Cause(err))
+}
+
+func (s *newTxSuite) TestNewTxBumpFee() {
+	const mBTC = 100000
+	feePerKb := btcutil.Amount(5000)
+	amount := btcutil.Amount(80 * mBTC)
+	txSize := maketx.TstEstimateTxSize(
+		1, s.inputConfiguration, len(s.outputPkScript), len(s.changeAddress.PubkeyScript()))
+	// The original tx paid 1 sat/vbyte.
+	originalFee := maketx.TstFeeForSerializeSize(1000, txSize, s.log)
+	utxo := s.buildUTXO(100*mBTC, 2*mBTC, 50*mBTC, 10*mBTC)
+	originalInputs := map[wire.OutPoint]*wire.TxOut{s.coin(0): utxo[s.coin(0)]}
+
+	bumpFee := func(
+		originalInputs map[wire.OutPoint]*wire.TxOut,
+		amount btcutil.Amount,
+		feePerKb btcutil.Amount,
+	) (*maketx.TxProposal, error) {
+		return maketx.NewTxBumpFee(
+			tbtc, s.inputConfiguration, originalInputs, originalFee, s.output(amount),
+			utxo, feePerKb, s.getChangeAddress, s.log)
+	}
+	inputsOf := func(tx *wire.MsgTx) []wire.OutPoint {
+		outPoints := []wire.OutPoint{}
+		for _, txIn := range tx.TxIn {
+			outPoints = append(outPoints, txIn.PreviousOutPoint)
+		}
+		return outPoints
+	}
+
+	// The original inputs cover the new fee.
+	txProposal, err := bumpFee(originalInputs, amount, feePerKb)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []wire.OutPoint{s.coin(0)}, inputsOf(txProposal.Transaction))
+	require.True(s.T(), maketx.SignalsRBF(txProposal.Transaction))
+	expectedFee := maketx.TstFeeForSerializeSize(feePerKb, txSize, s.log)
+	require.Equal(s.T(), expectedFee, txProposal.Fee)
+	require.Equal(s.T(), amount, txProposal.Amount)
+	require.Equal(s.T(), s.changeAddress, txProposal.ChangeAddress)
+	require.Len(s.T(), txProposal.Transaction.TxOut, 2)
+
+	// The fee is raised by at least the incremental relay fee, even if the requested rate is lower.
+	txProposal, err = bumpFee(originalInputs, amount, 0)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), 2*originalFee, txProposal.Fee)
+
+	// The largest additional coin is added if the original inputs don't cover the fee.
+	txProposal, err = bumpFee(originalInputs, 100*mBTC-expectedFee/2, feePerKb)
+	require.NoError(s.T(), err)
+	require.ElementsMatch(s.T(), []wire.OutPoint{s.coin(0), s.coin(2)}, inputsOf(txProposal.Transaction))
+
+	// Not enough funds.
+	_, err = bumpFee(originalInputs, 200*mBTC, feePerKb)
+	require.Equal(s.T(), errors.ErrInsufficientFunds, errp.Cause(err))
+}
@@ -0,0 +1,87 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package btc
+
+import (
+	"bytes"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/maketx"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/psbt"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/transactions"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+)
+
+// newPSBT creates an unsigned PSBT for the proposed tx. Segwit inputs contain the spent output,
+// non-segwit inputs the whole tx containing the spent output. The redeem script of the change output
+// is included.
+//
+// The BIP32 derivations of the public keys are left out: they require the fingerprint of the root
+// key, which neither the account nor the keystores provide, and a wrong fingerprint would make
+// signers look for the wrong key.
+func (account *Account) newPSBT(
+	utxo map[wire.OutPoint]*transactions.SpendableOutput,
+	txProposal *maketx.TxProposal,
+) (*psbt.Packet, error) {
+	packet, err := psbt.New(txProposal.Transaction)
+	if err != nil {
+		return nil, err
+	}
+	for index, txIn := range txProposal.Transaction.TxIn {
+		spentOutput := utxo[txIn.PreviousOutPoint]
+		address := account.getAddress(spentOutput.ScriptHashHex())
+		input := packet.Inputs[index]
+		if isSegwit, _ := address.ScriptForHashToSign(); isSegwit {
+			input.WitnessUTXO = spentOutput.TxOut
+		} else {
+			input.NonWitnessUTXO, err = account.transactions.Tx(txIn.PreviousOutPoint.Hash)
+			if err != nil {
+				return nil, err
+			}
+		}
+		input.SighashType = uint32(txscript.SigHashAll)
+		input.RedeemScript = address.RedeemScript()
+	}
+	if changeAddress := txProposal.ChangeAddress; changeAddress != nil {
+		for index, txOut := range txProposal.Transaction.TxOut {
+			if bytes.Equal(txOut.PkScript, changeAddress.PubkeyScript()) {
+				packet.Outputs[index].RedeemScript = changeAddress.RedeemScript()
+			}
+		}
+	}
+	return packet, nil
+}
+
+// ExportPSBT creates a tx like TxProposal() and returns it as a base64 encoded unsigned PSBT
+// (BIP174), to be signed by other wallets or cosigners.
+func (account *Account) ExportPSBT(
+	recipientAddress string,
+	amount coin.SendAmount,
+	feeTargetCode accounts.FeeTargetCode,
+	selectedUTXOs map[wire.OutPoint]struct{},
+) (string, error) {
+	account.log.Info("Exporting transaction as PSBT")
+	utxo, txProposal, err := account.newTx(recipientAddress, amount, feeTargetCode, selectedUTXOs)
+	if err != nil {
+		return "", err
+	}
+	packet, err := account.newPSBT(utxo, txProposal)
+	if err != nil {
+		return "", err
+	}
+	return packet.Encode()
+}
@@ -0,0 +1,277 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package psbt
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// Combine merges the given PSBTs, which all have to be for the same unsigned tx, e.g. to collect
+// the signatures of all cosigners of a multisig account.
+func Combine(packets ...*Packet) (*Packet, error) {
+	if len(packets) == 0 {
+		return nil, errp.New("No PSBTs to combine")
+	}
+	result, err := New(packets[0].UnsignedTx)
+	if err != nil {
+		return nil, err
+	}
+	txHash := result.UnsignedTx.TxHash()
+	for _, packet := range packets {
+		if packet.UnsignedTx.TxHash() != txHash {
+			return nil, errp.New("The PSBTs are for different transactions")
+		}
+		copyMap(result.Unknowns, packet.Unknowns)
+		for index, input := range packet.Inputs {
+			result.Inputs[index].merge(input)
+		}
+		for index, output := range packet.Outputs {
+			result.Outputs[index].merge(output)
+		}
+	}
+	return result, nil
+}
+
+func copyMap(to map[string][]byte, from map[string][]byte) {
+	for key, value := range from {
+		to[key] = value
+	}
+}
+
+func mergeDerivations(to []*BIP32Derivation, from []*BIP32Derivation) []*BIP32Derivation {
+outer:
+	for _, derivation := range from {
+		for _, existing := range to {
+			if bytes.Equal(existing.PublicKey, derivation.PublicKey) {
+				continue outer
+			}
+		}
+		to = append(to, derivation)
+	}
+	return to
+}
+
+func (input *Input) merge(other *Input) {
+	if other.NonWitnessUTXO != nil {
+		input.NonWitnessUTXO = other.NonWitnessUTXO
+	}
+	if other.WitnessUTXO != nil {
+		input.WitnessUTXO = other.WitnessUTXO
+	}
+	copyMap(input.PartialSigs, other.PartialSigs)
+	if other.SighashType != 0 {
+		input.SighashType = other.SighashType
+	}
+	if other.RedeemScript != nil {
+		input.RedeemScript = other.RedeemScript
+	}
+	if other.WitnessScript != nil {
+		input.WitnessScript = other.WitnessScript
+	}
+	input.BIP32Derivations = mergeDerivations(input.BIP32Derivations, other.BIP32Derivations)
+	if other.FinalScriptSig != nil {
+		input.FinalScriptSig = other.FinalScriptSig
+	}
+	if other.FinalScriptWitness != nil {
+		input.FinalScriptWitness = other.FinalScriptWitness
+	}
+	copyMap(input.Unknowns, other.Unknowns)
+}
+
+func (output *Output) merge(other *Output) {
+	if other.RedeemScript != nil {
+		output.RedeemScript = other.RedeemScript
+	}
+	if other.WitnessScript != nil {
+		output.WitnessScript = other.WitnessScript
+	}
+	output.BIP32Derivations = mergeDerivations(output.BIP32Derivations, other.BIP32Derivations)
+	copyMap(output.Unknowns, other.Unknowns)
+}
+
+// SpentOutput returns the output spent by the input at the given index.
+func (packet *Packet) SpentOutput(index int) (*wire.TxOut, error) {
+	input := packet.Inputs[index]
+	if input.WitnessUTXO != nil {
+		return input.WitnessUTXO, nil
+	}
+	if input.NonWitnessUTXO != nil {
+		outPoint := packet.UnsignedTx.TxIn[index].PreviousOutPoint
+		if input.NonWitnessUTXO.TxHash() != outPoint.Hash ||
+			int(outPoint.Index) >= len(input.NonWitnessUTXO.TxOut) {
+			return nil, errp.Newf("Input %d: the spent tx does not match", index)
+		}
+		return input.NonWitnessUTXO.TxOut[outPoint.Index], nil
+	}
+	return nil, errp.Newf("Input %d: the spent output is missing", index)
+}
+
+// Finalize builds the final scriptSig and witness of all inputs from the partial signatures.
+// Supported are P2PKH, P2WPKH and multisig inputs, optionally wrapped in P2SH and/or P2WSH. As
+// required by BIP174, all data which is not needed anymore is removed from finalized inputs.
+func (packet *Packet) Finalize() error {
+	for index, input := range packet.Inputs {
+		if input.isFinalized() {
+			continue
+		}
+		spentOutput, err := packet.SpentOutput(index)
+		if err != nil {
+			return err
+		}
+		if err := input.finalize(spentOutput.PkScript); err != nil {
+			return errp.WithMessage(err, fmt.Sprintf("Input %d", index))
+		}
+	}
+	return nil
+}
+
+func (input *Input) isFinalized() bool {
+	return input.FinalScriptSig != nil || input.FinalScriptWitness != nil
+}
+
+// signaturesFor returns the signatures of the given public keys in that order. An error is
+// returned if less than the required number of signatures are available.
+func (input *Input) signaturesFor(publicKeys [][]byte, required int) ([][]byte, error) {
+	signatures := [][]byte{}
+	for _, publicKey := range publicKeys {
+		if signature, ok := input.PartialSigs[string(publicKey)]; ok {
+			signatures = append(signatures, signature)
+			if len(signatures) == required {
+				return signatures, nil
+			}
+		}
+	}
+	return nil, errp.Newf("%d of %d required signatures available", len(signatures), required)
+}
+
+// singleSignature returns the public key and signature for a script paying to the given public
+// key hash.
+func (input *Input) singleSignature(publicKeyHash []byte) ([]byte, []byte, error) {
+	for publicKey, signature := range input.PartialSigs {
+		if bytes.Equal(btcutil.Hash160([]byte(publicKey)), publicKeyHash) {
+			return []byte(publicKey), signature, nil
+		}
+	}
+	return nil, nil, errp.New("Signature missing")
+}
+
+// multisigSignatures returns the signatures satisfying the given multisig script, ordered like the
+// public keys in the script.
+func (input *Input) multisigSignatures(script []byte) ([][]byte, error) {
+	_, required, err := txscript.CalcMultiSigStats(script)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	pushes, err := txscript.PushedData(script)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return input.signaturesFor(pushes, required)
+}
+
+func (input *Input) finalize(pkScript []byte) error {
+	script := pkScript
+	var scriptSig []byte
+	if txscript.IsPayToScriptHash(script) {
+		if input.RedeemScript == nil ||
+			!bytes.Equal(btcutil.Hash160(input.RedeemScript), script[2:22]) {
+			return errp.New("Redeem script missing or not matching")
+		}
+		var err error
+		scriptSig, err = txscript.NewScriptBuilder().AddData(input.RedeemScript).Script()
+		if err != nil {
+			return errp.WithStack(err)
+		}
+		script = input.RedeemScript
+	}
+	var witness wire.TxWitness
+	switch txscript.GetScriptClass(script) {
+	case txscript.PubKeyHashTy:
+		publicKey, signature, err := input.singleSignature(script[3:23])
+		if err != nil {
+			return err
+		}
+		scriptSig, err = txscript.NewScriptBuilder().AddData(signature).AddData(publicKey).Script()
+		if err != nil {
+			return errp.WithStack(err)
+		}
+	case txscript.WitnessV0PubKeyHashTy:
+		publicKey, signature, err := input.singleSignature(script[2:22])
+		if err != nil {
+			return err
+		}
+		witness = wire.TxWitness{signature, publicKey}
+	case txscript.MultiSigTy:
+		signatures, err := input.multisigSignatures(script)
+		if err != nil {
+			return err
+		}
+		builder := txscript.NewScriptBuilder().AddOp(txscript.OP_0)
+		for _, signature := range signatures {
+			builder.AddData(signature)
+		}
+		if scriptSig != nil {
+			builder.AddData(input.RedeemScript)
+		}
+		scriptSig, err = builder.Script()
+		if err != nil {
+			return errp.WithStack(err)
+		}
+	case txscript.WitnessV0ScriptHashTy:
+		if input.WitnessScript == nil {
+			return errp.New("Witness script missing")
+		}
+		if txscript.GetScriptClass(input.WitnessScript) != txscript.MultiSigTy {
+			return errp.New("Unsupported witness script")
+		}
+		signatures, err := input.multisigSignatures(input.WitnessScript)
+		if err != nil {
+			return err
+		}
+		witness = append(append(wire.TxWitness{{}}, signatures...), input.WitnessScript)
+	default:
+		return errp.New("Unsupported script")
+	}
+	if scriptSig == nil {
+		scriptSig = []byte{}
+	}
+	input.FinalScriptSig = scriptSig
+	input.FinalScriptWitness = witness
+	input.PartialSigs = map[string][]byte{}
+	input.SighashType = 0
+	input.RedeemScript = nil
+	input.WitnessScript = nil
+	input.BIP32Derivations = nil
+	return nil
+}
+
+// Extract returns the signed tx of a finalized PSBT.
+func (packet *Packet) Extract() (*wire.MsgTx, error) {
+	tx := packet.UnsignedTx.Copy()
+	for index, input := range packet.Inputs {
+		if !input.isFinalized() {
+			return nil, errp.Newf("Input %d is not finalized", index)
+		}
+		tx.TxIn[index].SignatureScript = input.FinalScriptSig
+		tx.TxIn[index].Witness = input.FinalScriptWitness
+	}
+	return tx, nil
+}
@@ -0,0 +1,454 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package psbt implements partially signed bitcoin transactions (BIP174), used to exchange
+// unsigned and partially signed transactions with other wallets and cosigners.
+package psbt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"sort"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// magic are the bytes every serialized PSBT starts with.
+var magic = []byte{0x70, 0x73, 0x62, 0x74, 0xff}
+
+// maxValueSize limits the size of keys and values when parsing a PSBT.
+const maxValueSize = 4000000
+
+const (
+	globalUnsignedTx = 0x00
+
+	inputNonWitnessUTXO     = 0x00
+	inputWitnessUTXO        = 0x01
+	inputPartialSig         = 0x02
+	inputSighashType        = 0x03
+	inputRedeemScript       = 0x04
+	inputWitnessScript      = 0x05
+	inputBIP32Derivation    = 0x06
+	inputFinalScriptSig     = 0x07
+	inputFinalScriptWitness = 0x08
+
+	outputRedeemScript    = 0x00
+	outputWitnessScript   = 0x01
+	outputBIP32Derivation = 0x02
+)
+
+// BIP32Derivation is the origin of a public key.
+type BIP32Derivation struct {
+	PublicKey []byte
+	// MasterFingerprint is the fingerprint of the root key, as serialized (little endian).
+	MasterFingerprint uint32
+	Keypath           []uint32
+}
+
+// Input contains the data needed to sign and finalize an input of the unsigned tx.
+type Input struct {
+	// NonWitnessUTXO is the tx containing the spent output. Needed for non-segwit inputs.
+	NonWitnessUTXO *wire.MsgTx
+	// WitnessUTXO is the spent output. Sufficient for segwit inputs.
+	WitnessUTXO *wire.TxOut
+	// PartialSigs maps public keys (as strings of their serialization) to signatures including
+	// the sighash type byte.
+	PartialSigs        map[string][]byte
+	SighashType        uint32
+	RedeemScript       []byte
+	WitnessScript      []byte
+	BIP32Derivations   []*BIP32Derivation
+	FinalScriptSig     []byte
+	FinalScriptWitness wire.TxWitness
+	Unknowns           map[string][]byte
+}
+
+// Output contains the data needed to identify an output of the unsigned tx, e.g. as change.
+type Output struct {
+	RedeemScript     []byte
+	WitnessScript    []byte
+	BIP32Derivations []*BIP32Derivation
+	Unknowns         map[string][]byte
+}
+
+// Packet is a partially signed bitcoin transaction.
+type Packet struct {
+	UnsignedTx *wire.MsgTx
+	Inputs     []*Input
+	Outputs    []*Output
+	Unknowns   map[string][]byte
+}
+
+// New creates a PSBT for the given tx without any additional data. The inputs of the tx must not
+// contain signatures.
+func New(tx *wire.MsgTx) (*Packet, error) {
+	for _, txIn := range tx.TxIn {
+		if len(txIn.SignatureScript) != 0 || len(txIn.Witness) != 0 {
+			return nil, errp.New("The unsigned tx must not contain signatures")
+		}
+	}
+	packet := &Packet{
+		UnsignedTx: tx.Copy(),
+		Inputs:     make([]*Input, len(tx.TxIn)),
+		Outputs:    make([]*Output, len(tx.TxOut)),
+		Unknowns:   map[string][]byte{},
+	}
+	for index := range packet.Inputs {
+		packet.Inputs[index] = &Input{PartialSigs: map[string][]byte{}, Unknowns: map[string][]byte{}}
+	}
+	for index := range packet.Outputs {
+		packet.Outputs[index] = &Output{Unknowns: map[string][]byte{}}
+	}
+	return packet, nil
+}
+
+// Decode parses a base64 encoded PSBT.
+func Decode(encoded string) (*Packet, error) {
+	serialized, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return Parse(bytes.NewReader(serialized))
+}
+
+// Encode returns the base64 encoded PSBT.
+func (packet *Packet) Encode() (string, error) {
+	var buf bytes.Buffer
+	if err := packet.Serialize(&buf); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// keyValue is a key-value pair of a PSBT map. The first byte of the key is the type.
+type keyValue struct {
+	key   []byte
+	value []byte
+}
+
+func readMap(r io.Reader) ([]keyValue, error) {
+	result := []keyValue{}
+	seen := map[string]struct{}{}
+	for {
+		key, err := wire.ReadVarBytes(r, 0, maxValueSize, "psbt key")
+		if err != nil {
+			return nil, errp.WithStack(err)
+		}
+		if len(key) == 0 {
+			return result, nil
+		}
+		if _, ok := seen[string(key)]; ok {
+			return nil, errp.New("Duplicate key in PSBT")
+		}
+		seen[string(key)] = struct{}{}
+		value, err := wire.ReadVarBytes(r, 0, maxValueSize, "psbt value")
+		if err != nil {
+			return nil, errp.WithStack(err)
+		}
+		result = append(result, keyValue{key: key, value: value})
+	}
+}
+
+func writeMap(w io.Writer, keyValues []keyValue, unknowns map[string][]byte) error {
+	for key, value := range unknowns {
+		keyValues = append(keyValues, keyValue{key: []byte(key), value: value})
+	}
+	sort.Slice(keyValues, func(i, j int) bool {
+		return bytes.Compare(keyValues[i].key, keyValues[j].key) < 0
+	})
+	for _, keyValue := range keyValues {
+		if err := wire.WriteVarBytes(w, 0, keyValue.key); err != nil {
+			return errp.WithStack(err)
+		}
+		if err := wire.WriteVarBytes(w, 0, keyValue.value); err != nil {
+			return errp.WithStack(err)
+		}
+	}
+	return errp.WithStack(wire.WriteVarInt(w, 0, 0))
+}
+
+func parseBIP32Derivation(keyValue keyValue) (*BIP32Derivation, error) {
+	publicKey := keyValue.key[1:]
+	if len(publicKey) != 33 && len(publicKey) != 65 {
+		return nil, errp.New("Invalid public key in PSBT derivation")
+	}
+	if len(keyValue.value) < 4 || len(keyValue.value)%4 != 0 {
+		return nil, errp.New("Invalid PSBT derivation")
+	}
+	derivation := &BIP32Derivation{
+		PublicKey:         publicKey,
+		MasterFingerprint: binary.LittleEndian.Uint32(keyValue.value),
+		Keypath:           make([]uint32, len(keyValue.value)/4-1),
+	}
+	for index := range derivation.Keypath {
+		derivation.Keypath[index] = binary.LittleEndian.Uint32(keyValue.value[4*(index+1):])
+	}
+	return derivation, nil
+}
+
+func serializeBIP32Derivations(keyType byte, derivations []*BIP32Derivation) []keyValue {
+	result := make([]keyValue, len(derivations))
+	for index, derivation := range derivations {
+		value := make([]byte, 4*(len(derivation.Keypath)+1))
+		binary.LittleEndian.PutUint32(value, derivation.MasterFingerprint)
+		for i, element := range derivation.Keypath {
+			binary.LittleEndian.PutUint32(value[4*(i+1):], element)
+		}
+		result[index] = keyValue{key: append([]byte{keyType}, derivation.PublicKey...), value: value}
+	}
+	return result
+}
+
+// Parse parses a serialized PSBT.
+func Parse(r io.Reader) (*Packet, error) {
+	prefix := make([]byte, len(magic))
+	if _, err := io.ReadFull(r, prefix); err != nil || !bytes.Equal(prefix, magic) {
+		return nil, errp.New("Invalid PSBT magic")
+	}
+	globals, err := readMap(r)
+	if err != nil {
+		return nil, err
+	}
+	var unsignedTx *wire.MsgTx
+	unknowns := map[string][]byte{}
+	for _, keyValue := range globals {
+		if keyValue.key[0] == globalUnsignedTx && len(keyValue.key) == 1 {
+			unsignedTx = &wire.MsgTx{}
+			if err := unsignedTx.DeserializeNoWitness(bytes.NewReader(keyValue.value)); err != nil {
+				return nil, errp.WithStack(err)
+			}
+			continue
+		}
+		unknowns[string(keyValue.key)] = keyValue.value
+	}
+	if unsignedTx == nil {
+		return nil, errp.New("PSBT does not contain the unsigned tx")
+	}
+	packet, err := New(unsignedTx)
+	if err != nil {
+		return nil, err
+	}
+	packet.Unknowns = unknowns
+	for _, input := range packet.Inputs {
+		if err := input.parse(r); err != nil {
+			return nil, err
+		}
+	}
+	for _, output := range packet.Outputs {
+		if err := output.parse(r); err != nil {
+			return nil, err
+		}
+	}
+	return packet, nil
+}
+
+func (input *Input) parse(r io.Reader) error {
+	keyValues, err := readMap(r)
+	if err != nil {
+		return err
+	}
+	for _, keyValue := range keyValues {
+		keyData := keyValue.key[1:]
+		switch keyValue.key[0] {
+		case inputNonWitnessUTXO:
+			input.NonWitnessUTXO = &wire.MsgTx{}
+			if err := input.NonWitnessUTXO.Deserialize(bytes.NewReader(keyValue.value)); err != nil {
+				return errp.WithStack(err)
+			}
+		case inputWitnessUTXO:
+			var buf bytes.Buffer
+			buf.Write(keyValue.value)
+			var value uint64
+			if err := binary.Read(&buf, binary.LittleEndian, &value); err != nil {
+				return errp.WithStack(err)
+			}
+			pkScript, err := wire.ReadVarBytes(&buf, 0, maxValueSize, "pkScript")
+			if err != nil {
+				return errp.WithStack(err)
+			}
+			input.WitnessUTXO = wire.NewTxOut(int64(value), pkScript)
+		case inputPartialSig:
+			input.PartialSigs[string(keyData)] = keyValue.value
+		case inputSighashType:
+			if len(keyValue.value) != 4 {
+				return errp.New("Invalid PSBT sighash type")
+			}
+			input.SighashType = binary.LittleEndian.Uint32(keyValue.value)
+		case inputRedeemScript:
+			input.RedeemScript = keyValue.value
+		case inputWitnessScript:
+			input.WitnessScript = keyValue.value
+		case inputBIP32Derivation:
+			derivation, err := parseBIP32Derivation(keyValue)
+			if err != nil {
+				return err
+			}
+			input.BIP32Derivations = append(input.BIP32Derivations, derivation)
+		case inputFinalScriptSig:
+			input.FinalScriptSig = keyValue.value
+		case inputFinalScriptWitness:
+			witness, err := parseWitness(keyValue.value)
+			if err != nil {
+				return err
+			}
+			input.FinalScriptWitness = witness
+		default:
+			input.Unknowns[string(keyValue.key)] = keyValue.value
+		}
+	}
+	return nil
+}
+
+func parseWitness(serialized []byte) (wire.TxWitness, error) {
+	r := bytes.NewReader(serialized)
+	count, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	if count > maxValueSize {
+		return nil, errp.New("Invalid PSBT witness")
+	}
+	witness := make(wire.TxWitness, count)
+	for index := range witness {
+		witness[index], err = wire.ReadVarBytes(r, 0, maxValueSize, "witness item")
+		if err != nil {
+			return nil, errp.WithStack(err)
+		}
+	}
+	return witness, nil
+}
+
+func (output *Output) parse(r io.Reader) error {
+	keyValues, err := readMap(r)
+	if err != nil {
+		return err
+	}
+	for _, keyValue := range keyValues {
+		switch keyValue.key[0] {
+		case outputRedeemScript:
+			output.RedeemScript = keyValue.value
+		case outputWitnessScript:
+			output.WitnessScript = keyValue.value
+		case outputBIP32Derivation:
+			derivation, err := parseBIP32Derivation(keyValue)
+			if err != nil {
+				return err
+			}
+			output.BIP32Derivations = append(output.BIP32Derivations, derivation)
+		default:
+			output.Unknowns[string(keyValue.key)] = keyValue.value
+		}
+	}
+	return nil
+}
+
+// Serialize writes the binary serialization of the PSBT.
+func (packet *Packet) Serialize(w io.Writer) error {
+	if _, err := w.Write(magic); err != nil {
+		return errp.WithStack(err)
+	}
+	var unsignedTx bytes.Buffer
+	if err := packet.UnsignedTx.SerializeNoWitness(&unsignedTx); err != nil {
+		return errp.WithStack(err)
+	}
+	if err := writeMap(w,
+		[]keyValue{{key: []byte{globalUnsignedTx}, value: unsignedTx.Bytes()}},
+		packet.Unknowns); err != nil {
+		return err
+	}
+	for _, input := range packet.Inputs {
+		keyValues, err := input.keyValues()
+		if err != nil {
+			return err
+		}
+		if err := writeMap(w, keyValues, input.Unknowns); err != nil {
+			return err
+		}
+	}
+	for _, output := range packet.Outputs {
+		if err := writeMap(w, output.keyValues(), output.Unknowns); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (input *Input) keyValues() ([]keyValue, error) {
+	result := []keyValue{}
+	if input.NonWitnessUTXO != nil {
+		var buf bytes.Buffer
+		if err := input.NonWitnessUTXO.Serialize(&buf); err != nil {
+			return nil, errp.WithStack(err)
+		}
+		result = append(result, keyValue{key: []byte{inputNonWitnessUTXO}, value: buf.Bytes()})
+	}
+	if input.WitnessUTXO != nil {
+		var buf bytes.Buffer
+		if err := binary.Write(&buf, binary.LittleEndian, uint64(input.WitnessUTXO.Value)); err != nil {
+			return nil, errp.WithStack(err)
+		}
+		if err := wire.WriteVarBytes(&buf, 0, input.WitnessUTXO.PkScript); err != nil {
+			return nil, errp.WithStack(err)
+		}
+		result = append(result, keyValue{key: []byte{inputWitnessUTXO}, value: buf.Bytes()})
+	}
+	for publicKey, signature := range input.PartialSigs {
+		result = append(result, keyValue{
+			key:   append([]byte{inputPartialSig}, publicKey...),
+			value: signature,
+		})
+	}
+	if input.SighashType != 0 {
+		value := make([]byte, 4)
+		binary.LittleEndian.PutUint32(value, input.SighashType)
+		result = append(result, keyValue{key: []byte{inputSighashType}, value: value})
+	}
+	if input.RedeemScript != nil {
+		result = append(result, keyValue{key: []byte{inputRedeemScript}, value: input.RedeemScript})
+	}
+	if input.WitnessScript != nil {
+		result = append(result, keyValue{key: []byte{inputWitnessScript}, value: input.WitnessScript})
+	}
+	result = append(result, serializeBIP32Derivations(inputBIP32Derivation, input.BIP32Derivations)...)
+	if input.FinalScriptSig != nil {
+		result = append(result, keyValue{key: []byte{inputFinalScriptSig}, value: input.FinalScriptSig})
+	}
+	if input.FinalScriptWitness != nil {
+		var buf bytes.Buffer
+		if err := wire.WriteVarInt(&buf, 0, uint64(len(input.FinalScriptWitness))); err != nil {
+			return nil, errp.WithStack(err)
+		}
+		for _, item := range input.FinalScriptWitness {
+			if err := wire.WriteVarBytes(&buf, 0, item); err != nil {
+				return nil, errp.WithStack(err)
+			}
+		}
+		result = append(result, keyValue{key: []byte{inputFinalScriptWitness}, value: buf.Bytes()})
+	}
+	return result, nil
+}
+
+func (output *Output) keyValues() []keyValue {
+	result := []keyValue{}
+	if output.RedeemScript != nil {
+		result = append(result, keyValue{key: []byte{outputRedeemScript}, value: output.RedeemScript})
+	}
+	if output.WitnessScript != nil {
+		result = append(result, keyValue{key: []byte{outputWitnessScript}, value: output.WitnessScript})
+	}
+	return append(result, serializeBIP32Derivations(outputBIP32Derivation, output.BIP32Derivations)...)
+}
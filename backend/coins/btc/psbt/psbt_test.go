@@ -0,0 +1,204 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package psbt_test
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/psbt"
+	"github.com/stretchr/testify/require"
+)
+
+var net = &chaincfg.TestNet3Params
+
+func privateKey(seed string) *btcec.PrivateKey {
+	hash := chainhash.HashB([]byte(seed))
+	privateKey, _ := btcec.PrivKeyFromBytes(btcec.S256(), hash)
+	return privateKey
+}
+
+func payToAddress(t *testing.T, address btcutil.Address) []byte {
+	t.Helper()
+	pkScript, err := txscript.PayToAddrScript(address)
+	require.NoError(t, err)
+	return pkScript
+}
+
+// spendingTx returns a tx spending all outputs of the given tx.
+func spendingTx(prevTx *wire.MsgTx) *wire.MsgTx {
+	tx := wire.NewMsgTx(wire.TxVersion)
+	prevTxHash := prevTx.TxHash()
+	for index := range prevTx.TxOut {
+		tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&prevTxHash, uint32(index)), nil, nil))
+	}
+	tx.AddTxOut(wire.NewTxOut(1000, prevTx.TxOut[0].PkScript))
+	return tx
+}
+
+func roundTrip(t *testing.T, packet *psbt.Packet) *psbt.Packet {
+	t.Helper()
+	encoded, err := packet.Encode()
+	require.NoError(t, err)
+	decoded, err := psbt.Decode(encoded)
+	require.NoError(t, err)
+	require.Equal(t, packet, decoded)
+	return decoded
+}
+
+func verify(t *testing.T, packet *psbt.Packet) {
+	t.Helper()
+	require.NoError(t, packet.Finalize())
+	tx, err := packet.Extract()
+	require.NoError(t, err)
+	sigHashes := txscript.NewTxSigHashes(tx)
+	for index := range tx.TxIn {
+		spentOutput, err := packet.SpentOutput(index)
+		require.NoError(t, err)
+		engine, err := txscript.NewEngine(spentOutput.PkScript, tx, index,
+			txscript.StandardVerifyFlags, nil, sigHashes, spentOutput.Value)
+		require.NoError(t, err)
+		require.NoError(t, engine.Execute())
+	}
+}
+
+// TestSinglesig round trips a PSBT spending a segwit and a legacy output, signs it and checks that
+// the finalized tx is valid.
+func TestSinglesig(t *testing.T) {
+	segwitKey := privateKey("segwit")
+	legacyKey := privateKey("legacy")
+	segwitAddress, err := btcutil.NewAddressWitnessPubKeyHash(
+		btcutil.Hash160(segwitKey.PubKey().SerializeCompressed()), net)
+	require.NoError(t, err)
+	legacyAddress, err := btcutil.NewAddressPubKeyHash(
+		btcutil.Hash160(legacyKey.PubKey().SerializeCompressed()), net)
+	require.NoError(t, err)
+
+	prevTx := wire.NewMsgTx(wire.TxVersion)
+	prevTx.AddTxIn(wire.NewTxIn(&wire.OutPoint{}, []byte{0x51}, nil))
+	prevTx.AddTxOut(wire.NewTxOut(10000, payToAddress(t, segwitAddress)))
+	prevTx.AddTxOut(wire.NewTxOut(20000, payToAddress(t, legacyAddress)))
+	tx := spendingTx(prevTx)
+
+	packet, err := psbt.New(tx)
+	require.NoError(t, err)
+	packet.Inputs[0].WitnessUTXO = prevTx.TxOut[0]
+	packet.Inputs[0].BIP32Derivations = []*psbt.BIP32Derivation{{
+		PublicKey:         segwitKey.PubKey().SerializeCompressed(),
+		MasterFingerprint: 0x12345678,
+		Keypath:           []uint32{84 + 0x80000000, 1 + 0x80000000, 0 + 0x80000000, 0, 0},
+	}}
+	packet.Inputs[0].SighashType = uint32(txscript.SigHashAll)
+	packet.Inputs[1].NonWitnessUTXO = prevTx
+	packet.Outputs[0].BIP32Derivations = []*psbt.BIP32Derivation{{
+		PublicKey: segwitKey.PubKey().SerializeCompressed(),
+		Keypath:   []uint32{1, 0},
+	}}
+	packet.Unknowns["\xf0unknown"] = []byte("kept")
+	packet = roundTrip(t, packet)
+
+	_, err = packet.Extract()
+	require.Error(t, err)
+	require.Error(t, packet.Finalize())
+
+	sigHashes := txscript.NewTxSigHashes(tx)
+	segwitSignature, err := txscript.RawTxInWitnessSignature(
+		tx, sigHashes, 0, prevTx.TxOut[0].Value, prevTx.TxOut[0].PkScript, txscript.SigHashAll, segwitKey)
+	require.NoError(t, err)
+	legacySignature, err := txscript.RawTxInSignature(
+		tx, 1, prevTx.TxOut[1].PkScript, txscript.SigHashAll, legacyKey)
+	require.NoError(t, err)
+	packet.Inputs[0].PartialSigs[string(segwitKey.PubKey().SerializeCompressed())] = segwitSignature
+	packet.Inputs[1].PartialSigs[string(legacyKey.PubKey().SerializeCompressed())] = legacySignature
+	packet = roundTrip(t, packet)
+
+	verify(t, packet)
+	// Finalized inputs only keep the data needed to extract the tx.
+	require.Empty(t, packet.Inputs[0].PartialSigs)
+	require.Nil(t, packet.Inputs[0].BIP32Derivations)
+	require.NotNil(t, packet.Inputs[0].FinalScriptWitness)
+	require.NotEmpty(t, packet.Inputs[1].FinalScriptSig)
+	roundTrip(t, packet)
+}
+
+// TestCombineMultisig combines the PSBTs signed by the two cosigners of a 2-of-2 P2SH multisig
+// output.
+func TestCombineMultisig(t *testing.T) {
+	keys := []*btcec.PrivateKey{privateKey("cosigner1"), privateKey("cosigner2")}
+	publicKeys := make([]*btcutil.AddressPubKey, len(keys))
+	for index, key := range keys {
+		var err error
+		publicKeys[index], err = btcutil.NewAddressPubKey(key.PubKey().SerializeCompressed(), net)
+		require.NoError(t, err)
+	}
+	redeemScript, err := txscript.MultiSigScript(publicKeys, 2)
+	require.NoError(t, err)
+	address, err := btcutil.NewAddressScriptHash(redeemScript, net)
+	require.NoError(t, err)
+
+	prevTx := wire.NewMsgTx(wire.TxVersion)
+	prevTx.AddTxIn(wire.NewTxIn(&wire.OutPoint{}, []byte{0x51}, nil))
+	prevTx.AddTxOut(wire.NewTxOut(10000, payToAddress(t, address)))
+	tx := spendingTx(prevTx)
+
+	unsigned, err := psbt.New(tx)
+	require.NoError(t, err)
+	unsigned.Inputs[0].NonWitnessUTXO = prevTx
+	unsigned.Inputs[0].RedeemScript = redeemScript
+	encoded, err := unsigned.Encode()
+	require.NoError(t, err)
+
+	// Each cosigner signs their own copy.
+	signed := make([]*psbt.Packet, len(keys))
+	for index, key := range keys {
+		packet, err := psbt.Decode(encoded)
+		require.NoError(t, err)
+		signature, err := txscript.RawTxInSignature(tx, 0, redeemScript, txscript.SigHashAll, key)
+		require.NoError(t, err)
+		packet.Inputs[0].PartialSigs[string(key.PubKey().SerializeCompressed())] = signature
+		// One signature is not enough.
+		require.Error(t, packet.Finalize())
+		signed[index] = roundTrip(t, packet)
+	}
+
+	// Order does not matter.
+	combined, err := psbt.Combine(signed[1], signed[0])
+	require.NoError(t, err)
+	require.Len(t, combined.Inputs[0].PartialSigs, 2)
+	require.Equal(t, redeemScript, combined.Inputs[0].RedeemScript)
+	verify(t, combined)
+
+	// PSBTs of different txs can't be combined.
+	otherTx := tx.Copy()
+	otherTx.TxOut[0].Value++
+	other, err := psbt.New(otherTx)
+	require.NoError(t, err)
+	_, err = psbt.Combine(signed[0], other)
+	require.Error(t, err)
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	_, err := psbt.Decode("not base64!")
+	require.Error(t, err)
+	_, err = psbt.Decode("cHNidP8=") // only the magic bytes
+	require.Error(t, err)
+	_, err = psbt.Decode("AAAAAAA=")
+	require.Error(t, err)
+}
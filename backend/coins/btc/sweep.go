@@ -0,0 +1,332 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package btc
+
+import (
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/mempool"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/txsort"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/errors"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/addresses"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/blockchain"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/maketx"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/transactions"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/sirupsen/logrus"
+)
+
+// sweepTimeout is how long to wait for each response of the blockchain backend when scanning a
+// private key for funds.
+const sweepTimeout = 30 * time.Second
+
+// SweepAddresses returns the addresses of the given private key which are scanned for funds when
+// sweeping it: P2PKH, and for compressed keys also P2WPKH-P2SH and P2WPKH.
+func SweepAddresses(wif *btcutil.WIF, net *chaincfg.Params) ([]btcutil.Address, error) {
+	publicKeyHash := btcutil.Hash160(wif.SerializePubKey())
+	p2pkh, err := btcutil.NewAddressPubKeyHash(publicKeyHash, net)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	if !wif.CompressPubKey {
+		// Segwit requires compressed public keys.
+		return []btcutil.Address{p2pkh}, nil
+	}
+	p2wpkh, err := btcutil.NewAddressWitnessPubKeyHash(publicKeyHash, net)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	redeemScript, err := txscript.PayToAddrScript(p2wpkh)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	p2wpkhP2SH, err := btcutil.NewAddressScriptHash(redeemScript, net)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return []btcutil.Address{p2pkh, p2wpkhP2SH, p2wpkh}, nil
+}
+
+// signSweepInput signs the input at the given index, which spends an output of one of the
+// SweepAddresses() of the private key.
+func signSweepInput(
+	tx *wire.MsgTx,
+	index int,
+	spentOutput *wire.TxOut,
+	sigHashes *txscript.TxSigHashes,
+	wif *btcutil.WIF,
+) error {
+	txIn := tx.TxIn[index]
+	switch txscript.GetScriptClass(spentOutput.PkScript) {
+	case txscript.PubKeyHashTy:
+		signatureScript, err := txscript.SignatureScript(
+			tx, index, spentOutput.PkScript, txscript.SigHashAll, wif.PrivKey, wif.CompressPubKey)
+		if err != nil {
+			return errp.WithStack(err)
+		}
+		txIn.SignatureScript = signatureScript
+	case txscript.WitnessV0PubKeyHashTy:
+		witness, err := txscript.WitnessSignature(tx, sigHashes, index, spentOutput.Value,
+			spentOutput.PkScript, txscript.SigHashAll, wif.PrivKey, true)
+		if err != nil {
+			return errp.WithStack(err)
+		}
+		txIn.Witness = witness
+	case txscript.ScriptHashTy:
+		// P2WPKH-P2SH
+		redeemScript, err := txscript.NewScriptBuilder().
+			AddOp(txscript.OP_0).
+			AddData(btcutil.Hash160(wif.SerializePubKey())).
+			Script()
+		if err != nil {
+			return errp.WithStack(err)
+		}
+		witness, err := txscript.WitnessSignature(tx, sigHashes, index, spentOutput.Value,
+			redeemScript, txscript.SigHashAll, wif.PrivKey, true)
+		if err != nil {
+			return errp.WithStack(err)
+		}
+		signatureScript, err := txscript.NewScriptBuilder().AddData(redeemScript).Script()
+		if err != nil {
+			return errp.WithStack(err)
+		}
+		txIn.SignatureScript = signatureScript
+		txIn.Witness = witness
+	default:
+		return errp.New("Unsupported output script")
+	}
+	return nil
+}
+
+// NewSweepTx creates and signs a tx which spends all given outputs, which must belong to the
+// SweepAddresses() of the private key, to the given address. It returns the signed tx and the
+// fee. errors.ErrInsufficientFunds is returned if there is nothing to sweep or if the amount left
+// after paying the fee is dust.
+func NewSweepTx(
+	wif *btcutil.WIF,
+	spentOutputs map[wire.OutPoint]*wire.TxOut,
+	outputAddress *addresses.AccountAddress,
+	feePerKb btcutil.Amount,
+	log *logrus.Entry,
+) (*wire.MsgTx, btcutil.Amount, error) {
+	if len(spentOutputs) == 0 {
+		return nil, 0, errp.WithStack(errors.ErrInsufficientFunds)
+	}
+	tx := wire.NewMsgTx(wire.TxVersion)
+	total := btcutil.Amount(0)
+	for outPoint, txOut := range spentOutputs {
+		outPoint := outPoint // avoids referencing the same variable across loop iterations
+		tx.AddTxIn(wire.NewTxIn(&outPoint, nil, nil))
+		total += btcutil.Amount(txOut.Value)
+	}
+	tx.AddTxOut(wire.NewTxOut(int64(total), outputAddress.PubkeyScript()))
+	txsort.InPlaceSort(tx)
+
+	sign := func() error {
+		sigHashes := txscript.NewTxSigHashes(tx)
+		for index, txIn := range tx.TxIn {
+			if err := signSweepInput(tx, index, spentOutputs[txIn.PreviousOutPoint], sigHashes, wif); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	// Sign once to learn the size of the tx. Signatures can be one byte shorter than the worst
+	// case, so one vbyte per input is added.
+	if err := sign(); err != nil {
+		return nil, 0, err
+	}
+	vsize := mempool.GetTxVirtualSize(btcutil.NewTx(tx)) + int64(len(tx.TxIn))
+	fee := feePerKb * btcutil.Amount(vsize) / 1000
+	amount := total - fee
+	if amount <= 0 || maketx.IsDust(amount, outputAddress, feePerKb) {
+		return nil, 0, errp.WithStack(errors.ErrInsufficientFunds)
+	}
+	tx.TxOut[0].Value = int64(amount)
+	if err := sign(); err != nil {
+		return nil, 0, err
+	}
+
+	previousOutputs := make(map[wire.OutPoint]*transactions.SpendableOutput, len(spentOutputs))
+	for outPoint, txOut := range spentOutputs {
+		previousOutputs[outPoint] = &transactions.SpendableOutput{TxOut: txOut}
+	}
+	if err := txValidityCheck(tx, previousOutputs, txscript.NewTxSigHashes(tx)); err != nil {
+		return nil, 0, err
+	}
+	log.WithFields(logrus.Fields{"inputs": len(tx.TxIn), "fee": fee}).Debug("Created sweep transaction")
+	return tx, fee, nil
+}
+
+// scanUnspentOutputs returns the unspent outputs, including unconfirmed ones, paying to the given
+// addresses, by fetching their tx history from the blockchain backend.
+func scanUnspentOutputs(
+	blockchain blockchain.Interface,
+	addresses []btcutil.Address,
+) (map[wire.OutPoint]*wire.TxOut, error) {
+	pkScripts := map[string]struct{}{}
+	txHashes := map[chainhash.Hash]struct{}{}
+	for _, address := range addresses {
+		pkScript, err := txscript.PayToAddrScript(address)
+		if err != nil {
+			return nil, errp.WithStack(err)
+		}
+		pkScripts[string(pkScript)] = struct{}{}
+		history, err := scriptHashGetHistory(blockchain, pkScript)
+		if err != nil {
+			return nil, err
+		}
+		for _, txInfo := range history {
+			txHashes[txInfo.TXHash.Hash()] = struct{}{}
+		}
+	}
+	outputs := map[wire.OutPoint]*wire.TxOut{}
+	spent := map[wire.OutPoint]struct{}{}
+	for txHash := range txHashes {
+		tx, err := transactionGet(blockchain, txHash)
+		if err != nil {
+			return nil, err
+		}
+		for _, txIn := range tx.TxIn {
+			spent[txIn.PreviousOutPoint] = struct{}{}
+		}
+		for index, txOut := range tx.TxOut {
+			if _, ok := pkScripts[string(txOut.PkScript)]; ok {
+				outputs[*wire.NewOutPoint(&txHash, uint32(index))] = txOut
+			}
+		}
+	}
+	for outPoint := range spent {
+		delete(outputs, outPoint)
+	}
+	return outputs, nil
+}
+
+func scriptHashGetHistory(
+	blockchainInterface blockchain.Interface,
+	pkScript []byte,
+) (blockchain.TxHistory, error) {
+	type result struct {
+		history blockchain.TxHistory
+		err     error
+	}
+	resultCh := make(chan result, 1)
+	blockchainInterface.ScriptHashGetHistory(
+		blockchain.ScriptHashHex(chainhash.HashH(pkScript).String()),
+		func(history blockchain.TxHistory) error {
+			resultCh <- result{history: history}
+			return nil
+		},
+		func(err error) {
+			if err != nil {
+				select {
+				case resultCh <- result{err: err}:
+				default:
+				}
+			}
+		},
+	)
+	select {
+	case result := <-resultCh:
+		return result.history, result.err
+	case <-time.After(sweepTimeout):
+		return nil, errp.New("Timeout while fetching the address history")
+	}
+}
+
+func transactionGet(blockchain blockchain.Interface, txHash chainhash.Hash) (*wire.MsgTx, error) {
+	type result struct {
+		tx  *wire.MsgTx
+		err error
+	}
+	resultCh := make(chan result, 1)
+	blockchain.TransactionGet(
+		txHash,
+		func(tx *wire.MsgTx) error {
+			resultCh <- result{tx: tx}
+			return nil
+		},
+		func(err error) {
+			if err != nil {
+				select {
+				case resultCh <- result{err: err}:
+				default:
+				}
+			}
+		},
+	)
+	select {
+	case result := <-resultCh:
+		return result.tx, result.err
+	case <-time.After(sweepTimeout):
+		return nil, errp.Newf("Timeout while fetching transaction %s", txHash)
+	}
+}
+
+// newSweepTx scans the addresses of the given private key (WIF) for funds and creates a tx signed
+// with the key, sending everything to an unused receive address of the account.
+func (account *Account) newSweepTx(wif string, feeRatePerKb btcutil.Amount) (
+	*wire.MsgTx, btcutil.Amount, error) {
+	decodedWIF, err := btcutil.DecodeWIF(wif)
+	if err != nil {
+		return nil, 0, errp.WithMessage(err, "Invalid private key")
+	}
+	if !decodedWIF.IsForNet(account.coin.Net()) {
+		return nil, 0, errp.New("The private key is for a different network")
+	}
+	sweepAddresses, err := SweepAddresses(decodedWIF, account.coin.Net())
+	if err != nil {
+		return nil, 0, err
+	}
+	spentOutputs, err := scanUnspentOutputs(account.blockchain, sweepAddresses)
+	if err != nil {
+		return nil, 0, err
+	}
+	return NewSweepTx(
+		decodedWIF,
+		spentOutputs,
+		account.receiveAddresses.GetUnused()[0],
+		feeRatePerKb,
+		account.log,
+	)
+}
+
+// SweepPrivateKeyProposal returns the amount the account would receive when sweeping the given
+// private key (WIF), and the fee.
+func (account *Account) SweepPrivateKeyProposal(wif string, feeRatePerKb btcutil.Amount) (
+	coin.Amount, coin.Amount, error) {
+	tx, fee, err := account.newSweepTx(wif, feeRatePerKb)
+	if err != nil {
+		return coin.Amount{}, coin.Amount{}, err
+	}
+	return coin.NewAmountFromInt64(tx.TxOut[0].Value), coin.NewAmountFromInt64(int64(fee)), nil
+}
+
+// SweepPrivateKey sends all funds of the given private key (WIF) to an unused receive address of
+// the account. The tx is signed with the private key, not with the keystores of the account.
+func (account *Account) SweepPrivateKey(wif string, feeRatePerKb btcutil.Amount) error {
+	account.log.Info("Sweeping private key")
+	tx, _, err := account.newSweepTx(wif, feeRatePerKb)
+	if err != nil {
+		return errp.WithMessage(err, "Failed to create transaction")
+	}
+	return account.blockchain.TransactionBroadcast(tx)
+}
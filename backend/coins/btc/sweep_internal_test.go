@@ -0,0 +1,181 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package btc
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/mempool"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/errors"
+	addressesTest "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/addresses/test"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/blockchain"
+	blockchainMock "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/blockchain/mocks"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/logging"
+	"github.com/stretchr/testify/require"
+)
+
+// Test vectors of the same private key, uncompressed and compressed.
+const (
+	testWIFUncompressed = "5HueCGU8rMjxEXxiPuD5BDku4MkFqeZyd4dZ1jvhTVqvbTLvyTJ"
+	testWIFCompressed   = "KwdMAjGmerYanjeui5SHS7JkmpZvVipYvB2LJGU1ZxJwYvP98617"
+)
+
+func encodeAddresses(addresses []btcutil.Address) []string {
+	result := make([]string, len(addresses))
+	for index, address := range addresses {
+		result[index] = address.EncodeAddress()
+	}
+	return result
+}
+
+func TestSweepAddresses(t *testing.T) {
+	wif, err := btcutil.DecodeWIF(testWIFUncompressed)
+	require.NoError(t, err)
+	sweepAddresses, err := SweepAddresses(wif, &chaincfg.MainNetParams)
+	require.NoError(t, err)
+	require.Equal(t, []string{"1GAehh7TsJAHuUAeKZcXf5CnwuGuGgyX2S"}, encodeAddresses(sweepAddresses))
+
+	wif, err = btcutil.DecodeWIF(testWIFCompressed)
+	require.NoError(t, err)
+	sweepAddresses, err = SweepAddresses(wif, &chaincfg.MainNetParams)
+	require.NoError(t, err)
+	require.Len(t, sweepAddresses, 3)
+	require.Equal(t, "1LoVGDgRs9hTfTNJNuXKSpywcbdvwRXpmK", sweepAddresses[0].EncodeAddress())
+	// All addresses commit to the same public key hash.
+	publicKeyHash := sweepAddresses[0].ScriptAddress()
+	redeemScript, err := txscript.PayToAddrScript(sweepAddresses[2])
+	require.NoError(t, err)
+	require.Equal(t, btcutil.Hash160(redeemScript), sweepAddresses[1].ScriptAddress())
+	require.Equal(t, publicKeyHash, sweepAddresses[2].ScriptAddress())
+}
+
+// sweepFunding returns a tx paying the given amounts to the sweep addresses of the key, one output
+// per address.
+func sweepFunding(t *testing.T, wif *btcutil.WIF, amounts ...int64) *wire.MsgTx {
+	t.Helper()
+	sweepAddresses, err := SweepAddresses(wif, &chaincfg.TestNet3Params)
+	require.NoError(t, err)
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{}, nil, nil))
+	for index, amount := range amounts {
+		pkScript, err := txscript.PayToAddrScript(sweepAddresses[index])
+		require.NoError(t, err)
+		tx.AddTxOut(wire.NewTxOut(amount, pkScript))
+	}
+	return tx
+}
+
+func outputsOf(tx *wire.MsgTx) map[wire.OutPoint]*wire.TxOut {
+	result := map[wire.OutPoint]*wire.TxOut{}
+	txHash := tx.TxHash()
+	for index, txOut := range tx.TxOut {
+		result[*wire.NewOutPoint(&txHash, uint32(index))] = txOut
+	}
+	return result
+}
+
+func TestNewSweepTx(t *testing.T) {
+	log := logging.Get().WithGroup("sweep_test")
+	outputAddress := addressesTest.GetAddress(signing.ScriptTypeP2WPKH)
+	feePerKb := btcutil.Amount(2000)
+	for _, wifString := range []string{testWIFUncompressed, testWIFCompressed} {
+		wif, err := btcutil.DecodeWIF(wifString)
+		require.NoError(t, err)
+		amounts := []int64{100000, 200000, 300000}
+		if !wif.CompressPubKey {
+			amounts = amounts[:1]
+		}
+		funding := sweepFunding(t, wif, amounts...)
+
+		tx, fee, err := NewSweepTx(wif, outputsOf(funding), outputAddress, feePerKb, log)
+		require.NoError(t, err)
+		require.Len(t, tx.TxIn, len(amounts))
+		require.Len(t, tx.TxOut, 1)
+		require.Equal(t, outputAddress.PubkeyScript(), tx.TxOut[0].PkScript)
+		total := int64(0)
+		for _, amount := range amounts {
+			total += amount
+		}
+		require.Equal(t, total-int64(fee), tx.TxOut[0].Value)
+		// The fee matches the actual size. The signatures can differ in length from those used to
+		// estimate the size.
+		vsize := mempool.GetTxVirtualSize(btcutil.NewTx(tx))
+		require.True(t, fee >= feePerKb*btcutil.Amount(vsize)/1000)
+		require.True(t, fee <= feePerKb*btcutil.Amount(vsize+2*int64(len(tx.TxIn)))/1000)
+	}
+
+	wif, err := btcutil.DecodeWIF(testWIFCompressed)
+	require.NoError(t, err)
+	// Nothing to sweep.
+	_, _, err = NewSweepTx(wif, nil, outputAddress, feePerKb, log)
+	require.Equal(t, errors.ErrInsufficientFunds, errp.Cause(err))
+	// Not worth sweeping.
+	_, _, err = NewSweepTx(wif, outputsOf(sweepFunding(t, wif, 500)), outputAddress, feePerKb, log)
+	require.Equal(t, errors.ErrInsufficientFunds, errp.Cause(err))
+}
+
+func TestScanUnspentOutputs(t *testing.T) {
+	wif, err := btcutil.DecodeWIF(testWIFCompressed)
+	require.NoError(t, err)
+	sweepAddresses, err := SweepAddresses(wif, &chaincfg.TestNet3Params)
+	require.NoError(t, err)
+
+	funding := sweepFunding(t, wif, 1000, 2000, 3000)
+	fundingHash := funding.TxHash()
+	// The first output has been spent already.
+	spending := wire.NewMsgTx(wire.TxVersion)
+	spending.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&fundingHash, 0), nil, nil))
+	spending.AddTxOut(wire.NewTxOut(900, []byte{txscript.OP_TRUE}))
+	txs := map[chainhash.Hash]*wire.MsgTx{fundingHash: funding, spending.TxHash(): spending}
+
+	pkScript, err := txscript.PayToAddrScript(sweepAddresses[0])
+	require.NoError(t, err)
+	histories := map[blockchain.ScriptHashHex]blockchain.TxHistory{}
+	for _, address := range sweepAddresses {
+		addressPkScript, err := txscript.PayToAddrScript(address)
+		require.NoError(t, err)
+		history := blockchain.TxHistory{{TXHash: blockchain.TXHash(fundingHash)}}
+		if string(addressPkScript) == string(pkScript) {
+			history = append(history, &blockchain.TxInfo{TXHash: blockchain.TXHash(spending.TxHash())})
+		}
+		histories[blockchain.ScriptHashHex(chainhash.HashH(addressPkScript).String())] = history
+	}
+	mock := &blockchainMock.BlockchainMock{
+		MockScriptHashGetHistory: func(
+			scriptHashHex blockchain.ScriptHashHex,
+			success func(blockchain.TxHistory) error,
+			cleanup func(error)) {
+			require.NoError(t, success(histories[scriptHashHex]))
+			cleanup(nil)
+		},
+		MockTransactionGet: func(txHash chainhash.Hash, success func(*wire.MsgTx) error, cleanup func(error)) {
+			require.NoError(t, success(txs[txHash]))
+			cleanup(nil)
+		},
+	}
+	outputs, err := scanUnspentOutputs(mock, sweepAddresses)
+	require.NoError(t, err)
+	require.Equal(t, map[wire.OutPoint]*wire.TxOut{
+		*wire.NewOutPoint(&fundingHash, 1): funding.TxOut[1],
+		*wire.NewOutPoint(&fundingHash, 2): funding.TxOut[2],
+	}, outputs)
+}
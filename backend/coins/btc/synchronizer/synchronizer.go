@@ -15,30 +15,52 @@
 package synchronizer
 
 import (
+	"math"
+	"sync/atomic"
+
 	"github.com/digitalbitbox/bitbox-wallet-app/util/locker"
 	"github.com/sirupsen/logrus"
 )
 
+// progressStep is the minimum increase of the progress which is reported.
+const progressStep = 0.01
+
 // Synchronizer keeps track of a reference counter. It is useful to keep track of outstanding tasks
 // that run in goroutines.
 type Synchronizer struct {
 	requestsCounter int32
 	onSyncStarted   func()
 	onSyncFinished  func()
+	onProgress      func(float64)
 	wait            chan struct{}
 	waitLock        locker.Locker
 	log             *logrus.Entry
+
+	// startedCount and finishedCount count the tasks of the current sync.
+	startedCount  int
+	finishedCount int
+	// progressBits holds the last reported progress of the current sync as float64 bits. It is
+	// accessed atomically so that Progress() can be called from the callbacks.
+	progressBits uint64
 }
 
 // NewSynchronizer creates a new Synchronizer. onSyncStarted is called when the counter is first
-// incremented. onSyncFinished is called when the counter is last decremented.
-func NewSynchronizer(onSyncStarted func(), onSyncFinished func(), log *logrus.Entry) *Synchronizer {
+// incremented. onSyncFinished is called when the counter is last decremented. onProgress is called
+// with the progress of the sync between 0 and 1, see Progress().
+func NewSynchronizer(
+	onSyncStarted func(),
+	onSyncFinished func(),
+	onProgress func(float64),
+	log *logrus.Entry,
+) *Synchronizer {
 	synchronizer := &Synchronizer{
 		requestsCounter: 0,
 		onSyncStarted:   onSyncStarted,
 		onSyncFinished:  onSyncFinished,
+		onProgress:      onProgress,
 		wait:            nil,
 		log:             log.WithField("group", "synchronizer"),
+		progressBits:    math.Float64bits(1),
 	}
 	return synchronizer
 }
@@ -48,17 +70,39 @@ func NewSynchronizer(onSyncStarted func(), onSyncFinished func(), log *logrus.En
 func (synchronizer *Synchronizer) IncRequestsCounter() func() {
 	defer synchronizer.waitLock.Lock()()
 	synchronizer.requestsCounter++
+	synchronizer.startedCount++
 	if synchronizer.requestsCounter == 1 {
+		synchronizer.startedCount = 1
+		synchronizer.finishedCount = 0
+		synchronizer.setProgress(0)
 		synchronizer.onSyncStarted()
 		synchronizer.wait = make(chan struct{})
 	}
 	return synchronizer.decRequestsCounter
 }
 
+// setProgress reports the progress if it changed enough. Requires the waitLock.
+func (synchronizer *Synchronizer) setProgress(progress float64) {
+	if progress == 0 || progress == 1 || progress-synchronizer.Progress() >= progressStep {
+		atomic.StoreUint64(&synchronizer.progressBits, math.Float64bits(progress))
+		synchronizer.onProgress(progress)
+	}
+}
+
 func (synchronizer *Synchronizer) decRequestsCounter() {
 	defer synchronizer.waitLock.Lock()()
 	synchronizer.requestsCounter--
+	synchronizer.finishedCount++
+	if synchronizer.requestsCounter > 0 {
+		// Tasks can spawn new tasks, so the progress could decrease. It is only reported if it
+		// increased.
+		progress := float64(synchronizer.finishedCount) / float64(synchronizer.startedCount)
+		if progress > synchronizer.Progress() {
+			synchronizer.setProgress(progress)
+		}
+	}
 	if synchronizer.requestsCounter == 0 {
+		synchronizer.setProgress(1)
 		synchronizer.onSyncFinished()
 		// Everyone waiting will be notified by this.
 		close(synchronizer.wait)
@@ -68,6 +112,13 @@ func (synchronizer *Synchronizer) decRequestsCounter() {
 	}
 }
 
+// Progress returns the progress of the current sync between 0 and 1, which is the share of
+// finished sync tasks. It only increases until the sync is done, and starts at 0 with each new
+// sync. It is 1 if not syncing.
+func (synchronizer *Synchronizer) Progress() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&synchronizer.progressBits))
+}
+
 // WaitSynchronized blocks until all pending synchronization tasks are finished.
 func (synchronizer *Synchronizer) WaitSynchronized() {
 	synchronizer.log.WithFields(logrus.Fields{"requestCounter": synchronizer.requestsCounter}).
@@ -0,0 +1,90 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package synchronizer_test
+
+import (
+	"testing"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/synchronizer"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/logging"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSynchronizerProgress(t *testing.T) {
+	var events []string
+	var progresses []float64
+	sync := synchronizer.NewSynchronizer(
+		func() { events = append(events, "started") },
+		func() { events = append(events, "finished") },
+		func(progress float64) { progresses = append(progresses, progress) },
+		logging.Get().WithGroup("synchronizer_test"),
+	)
+	require.Equal(t, float64(1), sync.Progress())
+
+	// Four tasks, one of which spawns two more before finishing.
+	done1 := sync.IncRequestsCounter()
+	done2 := sync.IncRequestsCounter()
+	done3 := sync.IncRequestsCounter()
+	done4 := sync.IncRequestsCounter()
+	require.Equal(t, float64(0), sync.Progress())
+	done1()
+	require.Equal(t, 0.25, sync.Progress())
+	done5 := sync.IncRequestsCounter()
+	done6 := sync.IncRequestsCounter()
+	// 1/6 finished, but the progress does not go back.
+	require.Equal(t, 0.25, sync.Progress())
+	done2()
+	require.Equal(t, 2.0/6, sync.Progress())
+	done3()
+	require.Equal(t, 0.5, sync.Progress())
+	done5()
+	done6()
+	require.Equal(t, 5.0/6, sync.Progress())
+	done4()
+	require.Equal(t, float64(1), sync.Progress())
+	require.Equal(t, []float64{0, 0.25, 2.0 / 6, 0.5, 4.0 / 6, 5.0 / 6, 1}, progresses)
+	require.Equal(t, []string{"started", "finished"}, events)
+
+	// The next sync starts from zero.
+	progresses = nil
+	done := sync.IncRequestsCounter()
+	require.Equal(t, float64(0), sync.Progress())
+	done()
+	require.Equal(t, []float64{0, 1}, progresses)
+	require.Equal(t, []string{"started", "finished", "started", "finished"}, events)
+}
+
+func TestSynchronizerProgressStep(t *testing.T) {
+	var progresses []float64
+	sync := synchronizer.NewSynchronizer(
+		func() {}, func() {},
+		func(progress float64) { progresses = append(progresses, progress) },
+		logging.Get().WithGroup("synchronizer_test"),
+	)
+	dones := []func(){}
+	for i := 0; i < 1000; i++ {
+		dones = append(dones, sync.IncRequestsCounter())
+	}
+	for _, done := range dones {
+		done()
+	}
+	// Only steps of at least 1% are reported, except for the start and the end.
+	require.True(t, len(progresses) <= 101)
+	require.Equal(t, float64(0), progresses[0])
+	require.Equal(t, float64(1), progresses[len(progresses)-1])
+	for i := 1; i < len(progresses)-1; i++ {
+		require.True(t, progresses[i]-progresses[i-1] >= 0.01)
+	}
+}
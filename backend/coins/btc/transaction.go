@@ -17,8 +17,10 @@ package btc
 import (
 	"math/big"
 
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/errors"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/addresses"
@@ -32,6 +34,50 @@ import (
 // unitSatoshi is 1 BTC (default unit) in Satoshi.
 const unitSatoshi = 1e8
 
+// feeRatePerKb returns the estimated fee rate of the given fee target.
+func (account *Account) feeRatePerKb(feeTargetCode accounts.FeeTargetCode) (btcutil.Amount, error) {
+	for _, target := range account.feeTargets {
+		if target.code == feeTargetCode {
+			if target.feeRatePerKb == nil {
+				break
+			}
+			return *target.feeRatePerKb, nil
+		}
+	}
+	return 0, errp.New("Fee could not be estimated")
+}
+
+// coinControl returns the outputs which can be spent in a new tx. Frozen outputs are never spent.
+// If selectedUTXOs is not empty, only the selected outputs are returned. An error is returned if a
+// selected output is frozen or not spendable, e.g. because it has been spent in the meantime.
+func coinControl(
+	utxo map[wire.OutPoint]*transactions.SpendableOutput,
+	selectedUTXOs map[wire.OutPoint]struct{},
+	frozenUTXOs map[wire.OutPoint]struct{},
+) (map[wire.OutPoint]*wire.TxOut, error) {
+	wireUTXO := make(map[wire.OutPoint]*wire.TxOut, len(utxo))
+	if len(selectedUTXOs) == 0 {
+		for outPoint, txOut := range utxo {
+			if _, frozen := frozenUTXOs[outPoint]; frozen {
+				continue
+			}
+			wireUTXO[outPoint] = txOut.TxOut
+		}
+		return wireUTXO, nil
+	}
+	for outPoint := range selectedUTXOs {
+		txOut, ok := utxo[outPoint]
+		if !ok {
+			return nil, errp.Newf("Selected coin %s is not spendable", outPoint)
+		}
+		if _, frozen := frozenUTXOs[outPoint]; frozen {
+			return nil, errp.Newf("Selected coin %s is frozen", outPoint)
+		}
+		wireUTXO[outPoint] = txOut.TxOut
+	}
+	return wireUTXO, nil
+}
+
 // newTx creates a new tx to the given recipient address. It also returns a set of used account
 // outputs, which contains all outputs that spent in the tx. Those are needed to be able to sign the
 // transaction. selectedUTXOs restricts the available coins; if empty, no restriction is applied and
@@ -43,6 +89,21 @@ func (account *Account) newTx(
 	selectedUTXOs map[wire.OutPoint]struct{},
 ) (
 	map[wire.OutPoint]*transactions.SpendableOutput, *maketx.TxProposal, error) {
+	feeRatePerKb, err := account.feeRatePerKb(feeTargetCode)
+	if err != nil {
+		return nil, nil, err
+	}
+	return account.newTxWithFeeRate(recipientAddress, amount, feeRatePerKb, selectedUTXOs)
+}
+
+// newTxWithFeeRate is like newTx(), paying the given fee rate instead of a fee target.
+func (account *Account) newTxWithFeeRate(
+	recipientAddress string,
+	amount coin.SendAmount,
+	feeRatePerKb btcutil.Amount,
+	selectedUTXOs map[wire.OutPoint]struct{},
+) (
+	map[wire.OutPoint]*transactions.SpendableOutput, *maketx.TxProposal, error) {
 
 	account.log.Debug("Prepare new transaction")
 
@@ -51,31 +112,14 @@ func (account *Account) newTx(
 		return nil, nil, err
 	}
 
-	var feeTarget *FeeTarget
-	for _, target := range account.feeTargets {
-		if target.code == feeTargetCode {
-			feeTarget = target
-			break
-		}
-	}
-	if feeTarget == nil || feeTarget.feeRatePerKb == nil {
-		return nil, nil, errp.New("Fee could not be estimated")
-	}
-
 	pkScript, err := txscript.PayToAddrScript(address)
 	if err != nil {
 		return nil, nil, errp.WithStack(err)
 	}
-	utxo := account.transactions.SpendableOutputs()
-	wireUTXO := make(map[wire.OutPoint]*wire.TxOut, len(utxo))
-	for outPoint, txOut := range utxo {
-		// Apply coin control.
-		if len(selectedUTXOs) != 0 {
-			if _, ok := selectedUTXOs[outPoint]; !ok {
-				continue
-			}
-		}
-		wireUTXO[outPoint] = txOut.TxOut
+	utxo := account.transactions.SpendableOutputs(account.getMinSpendConfirmations())
+	wireUTXO, err := coinControl(utxo, selectedUTXOs, account.frozenOutPoints())
+	if err != nil {
+		return nil, nil, err
 	}
 	var txProposal *maketx.TxProposal
 	if amount.SendAll() {
@@ -84,7 +128,7 @@ func (account *Account) newTx(
 			account.signingConfiguration,
 			wireUTXO,
 			pkScript,
-			*feeTarget.feeRatePerKb,
+			feeRatePerKb,
 			account.log,
 		)
 		if err != nil {
@@ -105,7 +149,7 @@ func (account *Account) newTx(
 			account.signingConfiguration,
 			wireUTXO,
 			wire.NewTxOut(parsedAmountInt64, pkScript),
-			*feeTarget.feeRatePerKb,
+			feeRatePerKb,
 			func() *addresses.AccountAddress {
 				return account.changeAddresses.GetUnused()[0]
 			},
@@ -138,16 +182,27 @@ func (account *Account) SendTx(
 	if err != nil {
 		return errp.WithMessage(err, "Failed to create transaction")
 	}
-	getAddress := func(scriptHashHex blockchain.ScriptHashHex) *addresses.AccountAddress {
-		if address := account.receiveAddresses.LookupByScriptHashHex(scriptHashHex); address != nil {
-			return address
-		}
-		if address := account.changeAddresses.LookupByScriptHashHex(scriptHashHex); address != nil {
-			return address
-		}
-		panic("address must be present")
+	return account.signAndBroadcast(utxo, txProposal)
+}
+
+// getAddress returns the receive or change address of the account with the given script hash. The
+// address must be present.
+func (account *Account) getAddress(scriptHashHex blockchain.ScriptHashHex) *addresses.AccountAddress {
+	if address := account.receiveAddresses.LookupByScriptHashHex(scriptHashHex); address != nil {
+		return address
+	}
+	if address := account.changeAddresses.LookupByScriptHashHex(scriptHashHex); address != nil {
+		return address
 	}
-	if err := SignTransaction(account.keystores, txProposal, utxo, getAddress, account.log); err != nil {
+	panic("address must be present")
+}
+
+// signAndBroadcast signs the tx with the keystores of the account and broadcasts it.
+func (account *Account) signAndBroadcast(
+	utxo map[wire.OutPoint]*transactions.SpendableOutput,
+	txProposal *maketx.TxProposal,
+) error {
+	if err := SignTransaction(account.keystores, txProposal, utxo, account.getAddress, account.log); err != nil {
 		return errp.WithMessage(err, "Failed to sign transaction")
 	}
 	account.log.Info("Signed transaction is broadcasted")
@@ -181,3 +236,190 @@ func (account *Account) TxProposal(
 		coin.NewAmountFromInt64(int64(txProposal.Fee)),
 		coin.NewAmountFromInt64(int64(txProposal.Total())), nil
 }
+
+// SendAllTxProposal returns the maximum amount which can be sent to the recipient at the given fee
+// rate, and the fee. The proposed tx spends all spendable coins of the account except for frozen
+// ones, and has no change output.
+func (account *Account) SendAllTxProposal(recipientAddress string, feeRatePerKb btcutil.Amount) (
+	coin.Amount, coin.Amount, error) {
+	_, txProposal, err := account.newTxWithFeeRate(
+		recipientAddress, coin.NewSendAmountAll(), feeRatePerKb, nil)
+	if err != nil {
+		return coin.Amount{}, coin.Amount{}, err
+	}
+	return coin.NewAmountFromInt64(int64(txProposal.Amount)),
+		coin.NewAmountFromInt64(int64(txProposal.Fee)), nil
+}
+
+// newConsolidationTx creates a tx which spends the unspent outputs of the account to one of its own
+// unused receive addresses. If maxInputs is positive, at most that many outputs are spent. Frozen
+// outputs and outputs which cost more to spend than they are worth are skipped.
+func (account *Account) newConsolidationTx(
+	feeTargetCode accounts.FeeTargetCode,
+	maxInputs int,
+) (
+	map[wire.OutPoint]*transactions.SpendableOutput, *maketx.TxProposal, error) {
+
+	account.log.Debug("Prepare new consolidation transaction")
+	feeRatePerKb, err := account.feeRatePerKb(feeTargetCode)
+	if err != nil {
+		return nil, nil, err
+	}
+	utxo := account.transactions.SpendableOutputs(account.getMinSpendConfirmations())
+	wireUTXO, err := coinControl(utxo, nil, account.frozenOutPoints())
+	if err != nil {
+		return nil, nil, err
+	}
+	txProposal, err := maketx.NewTxConsolidation(
+		account.coin,
+		account.signingConfiguration,
+		wireUTXO,
+		maxInputs,
+		account.receiveAddresses.GetUnused()[0],
+		feeRatePerKb,
+		account.log,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	return utxo, txProposal, nil
+}
+
+// ConsolidationTxProposal creates a tx which consolidates the unspent outputs of the account (see
+// newConsolidationTx) and returns the amount arriving at the account's address and the fee.
+func (account *Account) ConsolidationTxProposal(
+	feeTargetCode accounts.FeeTargetCode,
+	maxInputs int,
+) (coin.Amount, coin.Amount, error) {
+	_, txProposal, err := account.newConsolidationTx(feeTargetCode, maxInputs)
+	if err != nil {
+		return coin.Amount{}, coin.Amount{}, err
+	}
+	return coin.NewAmountFromInt64(int64(txProposal.Amount)),
+		coin.NewAmountFromInt64(int64(txProposal.Fee)), nil
+}
+
+// SendConsolidationTx creates, signs and sends a tx which consolidates the unspent outputs of the
+// account (see newConsolidationTx).
+func (account *Account) SendConsolidationTx(feeTargetCode accounts.FeeTargetCode, maxInputs int) error {
+	account.log.Info("Signing and sending consolidation transaction")
+	utxo, txProposal, err := account.newConsolidationTx(feeTargetCode, maxInputs)
+	if err != nil {
+		return errp.WithMessage(err, "Failed to create transaction")
+	}
+	return account.signAndBroadcast(utxo, txProposal)
+}
+
+// bumpFeeInputs returns the outputs which can be added as inputs to a fee bump replacement. BIP125
+// rule 2 forbids adding unconfirmed inputs, so only confirmed outputs are returned. This also
+// excludes the outputs of the replaced tx and of its descendants, which are all unconfirmed.
+func bumpFeeInputs(
+	utxo map[wire.OutPoint]*transactions.SpendableOutput,
+	frozen map[wire.OutPoint]struct{},
+) (map[wire.OutPoint]*wire.TxOut, error) {
+	wireUTXO, err := coinControl(utxo, nil, frozen)
+	if err != nil {
+		return nil, err
+	}
+	for outPoint := range wireUTXO {
+		if utxo[outPoint].NumConfirmations == 0 {
+			delete(wireUTXO, outPoint)
+		}
+	}
+	return wireUTXO, nil
+}
+
+// newBumpFeeTx creates a tx which replaces the unconfirmed tx with the given hash, paying the given
+// fee rate instead (BIP125). The replacement sends the same amount to the same recipient. The
+// fee increase is taken from the change; if this is not enough, additional unspent outputs of the
+// account are added. An error is returned if the tx is confirmed, does not signal replaceability
+// or was not created by this account.
+func (account *Account) newBumpFeeTx(
+	txHash chainhash.Hash,
+	feeRatePerKb btcutil.Amount,
+) (
+	map[wire.OutPoint]*transactions.SpendableOutput, *maketx.TxProposal, error) {
+
+	account.log.WithField("txHash", txHash).Debug("Prepare fee bump transaction")
+	originalTx, originalInputs, err := account.transactions.UnconfirmedTx(txHash)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !maketx.SignalsRBF(originalTx) {
+		return nil, nil, errp.Newf("Transaction %s does not signal replaceability", txHash)
+	}
+	originalFee := btcutil.Amount(0)
+	for _, txOut := range originalInputs {
+		originalFee += btcutil.Amount(txOut.Value)
+	}
+	var recipientOutput *wire.TxOut
+	var changeAddress *addresses.AccountAddress
+	for _, txOut := range originalTx.TxOut {
+		originalFee -= btcutil.Amount(txOut.Value)
+		scriptHashHex := blockchain.ScriptHashHex(chainhash.HashH(txOut.PkScript).String())
+		if address := account.changeAddresses.LookupByScriptHashHex(scriptHashHex); address != nil {
+			changeAddress = address
+			continue
+		}
+		if recipientOutput != nil {
+			return nil, nil, errp.Newf("Transaction %s has more than one recipient", txHash)
+		}
+		recipientOutput = txOut
+	}
+	if recipientOutput == nil {
+		return nil, nil, errp.Newf("Transaction %s has no recipient", txHash)
+	}
+
+	utxo := account.transactions.SpendableOutputs(account.getMinSpendConfirmations())
+	wireUTXO, err := bumpFeeInputs(utxo, account.frozenOutPoints())
+	if err != nil {
+		return nil, nil, err
+	}
+	txProposal, err := maketx.NewTxBumpFee(
+		account.coin,
+		account.signingConfiguration,
+		originalInputs,
+		originalFee,
+		recipientOutput,
+		wireUTXO,
+		feeRatePerKb,
+		func() *addresses.AccountAddress {
+			if changeAddress != nil {
+				return changeAddress
+			}
+			return account.changeAddresses.GetUnused()[0]
+		},
+		account.log,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The inputs of the original tx are spent already, but are needed to sign the replacement.
+	for outPoint, txOut := range originalInputs {
+		utxo[outPoint] = &transactions.SpendableOutput{TxOut: txOut}
+	}
+	return utxo, txProposal, nil
+}
+
+// BumpFeeTxProposal creates a tx replacing the unconfirmed tx with the given hash (see
+// newBumpFeeTx) and returns the amount sent to the recipient and the new fee.
+func (account *Account) BumpFeeTxProposal(txHash chainhash.Hash, feeRatePerKb btcutil.Amount) (
+	coin.Amount, coin.Amount, error) {
+	_, txProposal, err := account.newBumpFeeTx(txHash, feeRatePerKb)
+	if err != nil {
+		return coin.Amount{}, coin.Amount{}, err
+	}
+	return coin.NewAmountFromInt64(int64(txProposal.Amount)),
+		coin.NewAmountFromInt64(int64(txProposal.Fee)), nil
+}
+
+// SendBumpFeeTx creates, signs and sends a tx replacing the unconfirmed tx with the given hash
+// (see newBumpFeeTx).
+func (account *Account) SendBumpFeeTx(txHash chainhash.Hash, feeRatePerKb btcutil.Amount) error {
+	account.log.Info("Signing and sending fee bump transaction")
+	utxo, txProposal, err := account.newBumpFeeTx(txHash, feeRatePerKb)
+	if err != nil {
+		return errp.WithMessage(err, "Failed to create transaction")
+	}
+	return account.signAndBroadcast(utxo, txProposal)
+}
@@ -0,0 +1,163 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package btc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/errors"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/addresses"
+	addressesTest "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/addresses/test"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/maketx"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/transactions"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/logging"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/socksproxy"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/test"
+	"github.com/stretchr/testify/require"
+)
+
+func testOutPoint(index uint32) wire.OutPoint {
+	return wire.OutPoint{Hash: chainhash.HashH([]byte("some-tx")), Index: index}
+}
+
+func TestCoinControl(t *testing.T) {
+	utxo := map[wire.OutPoint]*transactions.SpendableOutput{
+		testOutPoint(0): {TxOut: wire.NewTxOut(1000, nil)},
+		testOutPoint(1): {TxOut: wire.NewTxOut(2000, nil)},
+		testOutPoint(2): {TxOut: wire.NewTxOut(3000, nil)},
+	}
+
+	// No selection: all coins can be spent.
+	wireUTXO, err := coinControl(utxo, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, wireUTXO, 3)
+
+	// Manual selection.
+	wireUTXO, err = coinControl(utxo, map[wire.OutPoint]struct{}{
+		testOutPoint(0): {},
+		testOutPoint(2): {},
+	}, nil)
+	require.NoError(t, err)
+	require.Equal(t, map[wire.OutPoint]*wire.TxOut{
+		testOutPoint(0): utxo[testOutPoint(0)].TxOut,
+		testOutPoint(2): utxo[testOutPoint(2)].TxOut,
+	}, wireUTXO)
+
+	// Selected coin not spendable.
+	_, err = coinControl(utxo, map[wire.OutPoint]struct{}{
+		testOutPoint(0): {},
+		testOutPoint(3): {},
+	}, nil)
+	require.Error(t, err)
+}
+
+func TestCoinControlFrozen(t *testing.T) {
+	utxo := map[wire.OutPoint]*transactions.SpendableOutput{
+		testOutPoint(0): {TxOut: wire.NewTxOut(1000, nil)},
+		testOutPoint(1): {TxOut: wire.NewTxOut(2000, nil)},
+		testOutPoint(2): {TxOut: wire.NewTxOut(3000, nil)},
+	}
+	frozen := map[wire.OutPoint]struct{}{testOutPoint(1): {}}
+
+	// Frozen coins are excluded from automatic coin selection.
+	wireUTXO, err := coinControl(utxo, nil, frozen)
+	require.NoError(t, err)
+	require.Equal(t, map[wire.OutPoint]*wire.TxOut{
+		testOutPoint(0): utxo[testOutPoint(0)].TxOut,
+		testOutPoint(2): utxo[testOutPoint(2)].TxOut,
+	}, wireUTXO)
+
+	// Frozen coins can't be selected manually.
+	_, err = coinControl(utxo, map[wire.OutPoint]struct{}{
+		testOutPoint(0): {},
+		testOutPoint(1): {},
+	}, frozen)
+	require.Error(t, err)
+}
+
+func TestBumpFeeInputs(t *testing.T) {
+	replacedTxHash := chainhash.HashH([]byte("replaced-tx"))
+	otherTxHash := chainhash.HashH([]byte("other-unconfirmed-tx"))
+	utxo := map[wire.OutPoint]*transactions.SpendableOutput{
+		testOutPoint(0): {TxOut: wire.NewTxOut(1000, nil), NumConfirmations: 1},
+		testOutPoint(1): {TxOut: wire.NewTxOut(2000, nil), NumConfirmations: 10},
+		testOutPoint(2): {TxOut: wire.NewTxOut(3000, nil), NumConfirmations: 3},
+		// Change of the replaced tx.
+		{Hash: replacedTxHash, Index: 1}: {TxOut: wire.NewTxOut(4000, nil)},
+		// Change of another unconfirmed tx, which could be a descendant of the replaced tx.
+		{Hash: otherTxHash, Index: 0}: {TxOut: wire.NewTxOut(5000, nil)},
+	}
+	frozen := map[wire.OutPoint]struct{}{testOutPoint(1): {}}
+
+	wireUTXO, err := bumpFeeInputs(utxo, frozen)
+	require.NoError(t, err)
+	require.Equal(t, map[wire.OutPoint]*wire.TxOut{
+		testOutPoint(0): utxo[testOutPoint(0)].TxOut,
+		testOutPoint(2): utxo[testOutPoint(2)].TxOut,
+	}, wireUTXO)
+}
+
+func TestFrozenUTXOsPersistence(t *testing.T) {
+	dir := test.TstTempDir("frozen-utxos")
+	defer func() { _ = os.RemoveAll(dir) }()
+	filename := filepath.Join(dir, frozenUTXOsFilename)
+
+	frozen, err := loadFrozenUTXOs(filename)
+	require.NoError(t, err)
+	require.Empty(t, frozen)
+
+	expected := map[wire.OutPoint]struct{}{testOutPoint(0): {}, testOutPoint(3): {}}
+	require.NoError(t, storeFrozenUTXOs(filename, expected))
+	frozen, err = loadFrozenUTXOs(filename)
+	require.NoError(t, err)
+	require.Equal(t, expected, frozen)
+}
+
+func TestCoinControlInsufficientFunds(t *testing.T) {
+	inputConfiguration, addressChain := addressesTest.NewAddressChain()
+	someAddresses := addressChain.EnsureAddresses()
+	utxo := map[wire.OutPoint]*transactions.SpendableOutput{
+		testOutPoint(0): {TxOut: wire.NewTxOut(1000, someAddresses[0].PubkeyScript())},
+		testOutPoint(1): {TxOut: wire.NewTxOut(100000, someAddresses[0].PubkeyScript())},
+	}
+	coin := NewCoin("tbtc", "TBTC", &chaincfg.TestNet3Params, "", nil, "",
+		socksproxy.NewSocksProxy(false, ""))
+	newTx := func(selectedUTXOs map[wire.OutPoint]struct{}) error {
+		wireUTXO, err := coinControl(utxo, selectedUTXOs, nil)
+		require.NoError(t, err)
+		_, err = maketx.NewTx(
+			coin,
+			inputConfiguration,
+			wireUTXO,
+			wire.NewTxOut(50000, someAddresses[1].PubkeyScript()),
+			btcutil.Amount(1000),
+			func() *addresses.AccountAddress { return someAddresses[2] },
+			logging.Get().WithGroup("transaction_test"),
+		)
+		return err
+	}
+	require.NoError(t, newTx(nil))
+	require.NoError(t, newTx(map[wire.OutPoint]struct{}{testOutPoint(1): {}}))
+	// The selected coin does not cover the amount, even though the account balance would.
+	require.Equal(t, errors.ErrInsufficientFunds,
+		errp.Cause(newTx(map[wire.OutPoint]struct{}{testOutPoint(0): {}})))
+}
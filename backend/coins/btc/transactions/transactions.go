@@ -30,6 +30,7 @@ import (
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/headers"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/synchronizer"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/locker"
 	"github.com/sirupsen/logrus"
 )
@@ -38,6 +39,9 @@ import (
 type SpendableOutput struct {
 	*wire.TxOut
 	Address string
+	// NumConfirmations is the number of confirmations of the tx which created the output. It is 0
+	// if the tx is unconfirmed.
+	NumConfirmations int
 }
 
 // ScriptHashHex returns the hash of the PkScript of the output, in hex format.
@@ -69,6 +73,10 @@ type Transactions struct {
 	notifier     accounts.Notifier
 	log          *logrus.Entry
 
+	// replacedTxs contains the hashes of the pending txs which were removed because they were
+	// replaced, so they are not indexed again while a stale address history still lists them.
+	replacedTxs map[chainhash.Hash]struct{}
+
 	closed     bool
 	closedLock locker.Locker
 }
@@ -94,6 +102,8 @@ func NewTransactions(
 		blockchain:   blockchain,
 		notifier:     notifier,
 		log:          log.WithFields(logrus.Fields{"group": "transactions", "net": net.Name}),
+
+		replacedTxs: map[chainhash.Hash]struct{}{},
 	}
 	transactions.unsubscribeHeadersEvent = headers.SubscribeEvent(transactions.onHeadersEvent)
 	return transactions
@@ -117,6 +127,10 @@ func (transactions *Transactions) isClosed() bool {
 
 func (transactions *Transactions) processTxForAddress(
 	dbTx DBTxInterface, scriptHashHex blockchain.ScriptHashHex, txHash chainhash.Hash, tx *wire.MsgTx, height int) {
+	if !transactions.removeReplacedTxs(dbTx, txHash, tx, height) {
+		return
+	}
+
 	_, _, previousHeight, _, err := dbTx.TxInfo(txHash)
 	if err != nil {
 		transactions.log.WithError(err).Panic("Failed to retrieve tx info")
@@ -196,9 +210,9 @@ func (transactions *Transactions) allInputsOurs(dbTx DBTxInterface, transaction
 }
 
 // SpendableOutputs returns all unspent outputs of the wallet which are eligible to be spent. Those
-// include all unspent outputs of confirmed transactions, and unconfirmed outputs that we created
-// ourselves.
-func (transactions *Transactions) SpendableOutputs() map[wire.OutPoint]*SpendableOutput {
+// include all unspent outputs of transactions with at least minConfirmations confirmations, and
+// unconfirmed outputs that we created ourselves.
+func (transactions *Transactions) SpendableOutputs(minConfirmations int) map[wire.OutPoint]*SpendableOutput {
 	transactions.synchronizer.WaitSynchronized()
 	defer transactions.RLock()()
 
@@ -218,19 +232,89 @@ func (transactions *Transactions) SpendableOutputs() map[wire.OutPoint]*Spendabl
 		if err != nil {
 			transactions.log.WithError(err).Panic("Failed to retrieve tx info")
 		}
-		confirmed := height > 0
+		numConfirmations := transactions.numConfirmations(height)
+		confirmed := height > 0 && (minConfirmations <= 1 || numConfirmations >= minConfirmations)
 
 		spent := transactions.isInputSpent(dbTx, outPoint)
 		if !spent && (confirmed || transactions.allInputsOurs(dbTx, tx)) {
 			result[outPoint] = &SpendableOutput{
-				TxOut:   txOut,
-				Address: transactions.outputToAddress(txOut.PkScript),
+				TxOut:            txOut,
+				Address:          transactions.outputToAddress(txOut.PkScript),
+				NumConfirmations: numConfirmations,
 			}
 		}
 	}
 	return result
 }
 
+// Tx returns the tx with the given hash, which must be a tx of the account.
+func (transactions *Transactions) Tx(txHash chainhash.Hash) (*wire.MsgTx, error) {
+	transactions.synchronizer.WaitSynchronized()
+	defer transactions.RLock()()
+
+	dbTx, err := transactions.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer dbTx.Rollback()
+
+	tx, _, _, _, err := dbTx.TxInfo(txHash)
+	if err != nil {
+		return nil, err
+	}
+	if tx == nil {
+		return nil, errp.Newf("Unknown transaction %s", txHash)
+	}
+	return tx, nil
+}
+
+// UnconfirmedTx returns the unconfirmed tx with the given hash and the outputs it spends. An error
+// is returned if the tx is unknown, already confirmed or if it spends outputs which do not belong
+// to the account.
+func (transactions *Transactions) UnconfirmedTx(txHash chainhash.Hash) (
+	*wire.MsgTx, map[wire.OutPoint]*wire.TxOut, error) {
+	transactions.synchronizer.WaitSynchronized()
+	defer transactions.RLock()()
+
+	dbTx, err := transactions.db.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer dbTx.Rollback()
+
+	tx, _, height, _, err := dbTx.TxInfo(txHash)
+	if err != nil {
+		return nil, nil, err
+	}
+	if tx == nil {
+		return nil, nil, errp.Newf("Unknown transaction %s", txHash)
+	}
+	if height > 0 {
+		return nil, nil, errp.Newf("Transaction %s is already confirmed", txHash)
+	}
+	spentOutputs := make(map[wire.OutPoint]*wire.TxOut, len(tx.TxIn))
+	for _, txIn := range tx.TxIn {
+		txOut, err := dbTx.Output(txIn.PreviousOutPoint)
+		if err != nil {
+			return nil, nil, err
+		}
+		if txOut == nil {
+			return nil, nil, errp.Newf("Transaction %s spends foreign outputs", txHash)
+		}
+		spentOutputs[txIn.PreviousOutPoint] = txOut
+	}
+	return tx, spentOutputs, nil
+}
+
+// numConfirmations returns the number of confirmations of a tx at the given height. Height 0 means
+// unconfirmed.
+func (transactions *Transactions) numConfirmations(height int) int {
+	if height > 0 && transactions.headersTipHeight > 0 {
+		return transactions.headersTipHeight - height + 1
+	}
+	return 0
+}
+
 func (transactions *Transactions) isInputSpent(dbTx DBTxInterface, outPoint wire.OutPoint) bool {
 	input, err := dbTx.Input(outPoint)
 	if err != nil {
@@ -259,25 +343,130 @@ func (transactions *Transactions) removeTxForAddress(
 	}
 	if empty {
 		// Tx is not touching any of our outputs anymore. Remove.
+		transactions.removeTx(dbTx, txHash, tx)
+	}
+}
 
-		for _, txIn := range tx.TxIn {
-			transactions.log.Debug("Deleting transaction iput")
-			dbTx.DeleteInput(txIn.PreviousOutPoint)
+// removeTx removes the tx and its inputs and outputs from the index, regardless of the addresses
+// it touches.
+func (transactions *Transactions) removeTx(dbTx DBTxInterface, txHash chainhash.Hash, tx *wire.MsgTx) {
+	for _, txIn := range tx.TxIn {
+		// In case of a double spend, the input may be indexed for the other tx.
+		inputTxHash, err := dbTx.Input(txIn.PreviousOutPoint)
+		if err != nil {
+			transactions.log.WithError(err).Panic("Failed to retrieve input from previous outpoint")
+		}
+		if inputTxHash == nil || *inputTxHash != txHash {
+			continue
 		}
+		transactions.log.Debug("Deleting transaction iput")
+		dbTx.DeleteInput(txIn.PreviousOutPoint)
+	}
+
+	// Remove the outputs added by this tx.
+	for index := range tx.TxOut {
+		dbTx.DeleteOutput(wire.OutPoint{
+			Hash:  txHash,
+			Index: uint32(index),
+		})
+	}
+
+	dbTx.DeleteTx(txHash)
+	if err := transactions.notifier.Delete(txHash[:]); err != nil {
+		transactions.log.WithError(err).Error("Failed notifier.Delete")
+	}
+}
 
-		// Remove the outputs added by this tx.
-		for index := range tx.TxOut {
-			dbTx.DeleteOutput(wire.OutPoint{
-				Hash:  txHash,
-				Index: uint32(index),
-			})
+// removeTxAndDependants removes the replaced tx like removeTx, together with all indexed txs
+// spending its outputs, recursively. If a tx was replaced, the txs built on top of it can't confirm
+// anymore. The removed txs are remembered in replacedTxs, so they are not indexed again.
+func (transactions *Transactions) removeTxAndDependants(
+	dbTx DBTxInterface, txHash chainhash.Hash, tx *wire.MsgTx) {
+	transactions.replacedTxs[txHash] = struct{}{}
+	dependantHashes := []chainhash.Hash{}
+	for index := range tx.TxOut {
+		spendingTxHash, err := dbTx.Input(wire.OutPoint{Hash: txHash, Index: uint32(index)})
+		if err != nil {
+			transactions.log.WithError(err).Panic("Failed to retrieve input from previous outpoint")
 		}
+		if spendingTxHash == nil || *spendingTxHash == txHash {
+			continue
+		}
+		dependantHashes = append(dependantHashes, *spendingTxHash)
+	}
+	storedTx, _, _, _, err := dbTx.TxInfo(txHash)
+	if err != nil {
+		transactions.log.WithError(err).Panic("Failed to retrieve tx info")
+	}
+	if storedTx != nil {
+		transactions.removeTx(dbTx, txHash, tx)
+	}
+	for _, dependantHash := range dependantHashes {
+		dependant, _, _, _, err := dbTx.TxInfo(dependantHash)
+		if err != nil {
+			transactions.log.WithError(err).Panic("Failed to retrieve tx info")
+		}
+		// A tx spending several of the outputs is only removed once.
+		if dependant == nil {
+			continue
+		}
+		transactions.log.WithFields(logrus.Fields{"txHash": dependantHash, "spentTxHash": txHash}).
+			Info("Removing tx spending a removed tx")
+		transactions.removeTxAndDependants(dbTx, dependantHash, dependant)
+	}
+}
 
-		dbTx.DeleteTx(txHash)
-		if err := transactions.notifier.Delete(txHash[:]); err != nil {
-			transactions.log.WithError(err).Error("Failed notifier.Delete")
+// removeReplacedTxs handles double spends of the given tx, e.g. when a pending tx was replaced by
+// a fee bump (RBF). A pending tx which spends the same outputs as a confirmed tx can't confirm
+// anymore and is removed from the index, even if the history of some of its addresses still lists
+// it. The pending txs spending its outputs are removed as well. Returns false if the given tx itself
+// was replaced, or spends the outputs of a replaced tx, and must not be indexed.
+func (transactions *Transactions) removeReplacedTxs(
+	dbTx DBTxInterface, txHash chainhash.Hash, tx *wire.MsgTx, height int) bool {
+	if height > 0 {
+		delete(transactions.replacedTxs, txHash)
+	} else {
+		if _, ok := transactions.replacedTxs[txHash]; ok {
+			return false
+		}
+		for _, txIn := range tx.TxIn {
+			if _, ok := transactions.replacedTxs[txIn.PreviousOutPoint.Hash]; ok {
+				transactions.log.WithFields(logrus.Fields{
+					"txHash": txHash, "spentTxHash": txIn.PreviousOutPoint.Hash}).
+					Info("Ignoring pending tx spending a replaced tx")
+				transactions.removeTxAndDependants(dbTx, txHash, tx)
+				return false
+			}
 		}
 	}
+	for _, txIn := range tx.TxIn {
+		otherTxHash, err := dbTx.Input(txIn.PreviousOutPoint)
+		if err != nil {
+			transactions.log.WithError(err).Panic("Failed to retrieve input from previous outpoint")
+		}
+		if otherTxHash == nil || *otherTxHash == txHash {
+			continue
+		}
+		otherTx, _, otherHeight, _, err := dbTx.TxInfo(*otherTxHash)
+		if err != nil {
+			transactions.log.WithError(err).Panic("Failed to retrieve tx info")
+		}
+		if otherTx == nil {
+			continue
+		}
+		switch {
+		case height > 0 && otherHeight <= 0:
+			transactions.log.WithFields(logrus.Fields{"txHash": txHash, "replacedTxHash": otherTxHash}).
+				Info("Removing pending tx replaced by a confirmed tx")
+			transactions.removeTxAndDependants(dbTx, *otherTxHash, otherTx)
+		case height <= 0 && otherHeight > 0:
+			transactions.log.WithFields(logrus.Fields{"txHash": txHash, "replacedByTxHash": otherTxHash}).
+				Info("Ignoring pending tx replaced by a confirmed tx")
+			transactions.removeTxAndDependants(dbTx, txHash, tx)
+			return false
+		}
+	}
+	return true
 }
 
 // UpdateAddressHistory should be called when initializing a wallet address, or when the history of
@@ -421,15 +610,19 @@ type TxInfo struct {
 	// Weight is the tx weight.
 	Weight int64
 	// Height is the height this tx was confirmed at. 0 (or -1) for unconfirmed.
-	Height           int
-	numConfirmations int
-	txType           accounts.TxType
-	amount           btcutil.Amount
-	fee              *btcutil.Amount
+	Height                   int
+	numConfirmations         int
+	numConfirmationsComplete int
+	txType                   accounts.TxType
+	amount                   btcutil.Amount
+	fee                      *btcutil.Amount
 	// Time of confirmation. nil for unconfirmed tx or when the headers are not synced yet.
 	timestamp *time.Time
 	// addresses money was sent to / received on (without change addresses).
 	addresses []accounts.AddressAndAmount
+	inputs    []accounts.TransactionInput
+	// outputs are all outputs, including change outputs.
+	outputs []accounts.AddressAndAmount
 }
 
 // Fee implements accounts.Transaction.
@@ -472,7 +665,7 @@ func (txInfo *TxInfo) NumConfirmations() int {
 
 // NumConfirmationsComplete implements accounts.Transaction.
 func (txInfo *TxInfo) NumConfirmationsComplete() int {
-	return 6
+	return txInfo.numConfirmationsComplete
 }
 
 // Type implements accounts.Transaction.
@@ -498,6 +691,16 @@ func (txInfo *TxInfo) Addresses() []accounts.AddressAndAmount {
 	return txInfo.addresses
 }
 
+// Inputs implements accounts.UTXOTransaction.
+func (txInfo *TxInfo) Inputs() []accounts.TransactionInput {
+	return txInfo.inputs
+}
+
+// Outputs implements accounts.UTXOTransaction.
+func (txInfo *TxInfo) Outputs() []accounts.AddressAndAmount {
+	return txInfo.outputs
+}
+
 func (transactions *Transactions) outputToAddress(pkScript []byte) string {
 	_, extractedAddresses, _, err := txscript.ExtractPkScriptAddrs(pkScript, transactions.net)
 	// unknown addresses and multisig scripts ignored.
@@ -513,26 +716,36 @@ func (transactions *Transactions) txInfo(
 	tx *wire.MsgTx,
 	height int,
 	timestamp *time.Time,
+	numConfirmationsComplete int,
 	isChange func(blockchain.ScriptHashHex) bool) *TxInfo {
 	defer transactions.RLock()()
 	var sumOurInputs btcutil.Amount
 	var result btcutil.Amount
 	allInputsOurs := true
+	inputs := []accounts.TransactionInput{}
 	for _, txIn := range tx.TxIn {
 		spentOut, err := dbTx.Output(txIn.PreviousOutPoint)
 		if err != nil {
 			// TODO
 			panic(err)
 		}
+		input := accounts.TransactionInput{
+			PreviousOutput: txIn.PreviousOutPoint.String(),
+			Ours:           spentOut != nil,
+		}
 		if spentOut != nil {
 			sumOurInputs += btcutil.Amount(spentOut.Value)
+			input.Address = transactions.outputToAddress(spentOut.PkScript)
+			input.Amount = coin.NewAmountFromInt64(spentOut.Value)
 		} else {
 			allInputsOurs = false
 		}
+		inputs = append(inputs, input)
 	}
 	var sumAllOutputs, sumOurReceive, sumOurChange btcutil.Amount
 	receiveAddresses := []accounts.AddressAndAmount{}
 	sendAddresses := []accounts.AddressAndAmount{}
+	outputs := []accounts.AddressAndAmount{}
 	allOutputsOurs := true
 	for index, txOut := range tx.TxOut {
 		sumAllOutputs += btcutil.Amount(txOut.Value)
@@ -549,6 +762,7 @@ func (transactions *Transactions) txInfo(
 			Amount:  coin.NewAmountFromInt64(txOut.Value),
 			Ours:    output != nil,
 		}
+		outputs = append(outputs, addressAndAmount)
 		if output != nil {
 			receiveAddresses = append(receiveAddresses, addressAndAmount)
 			if isChange(getScriptHashHex(output)) {
@@ -593,28 +807,30 @@ func (transactions *Transactions) txInfo(
 		}
 
 	}
-	numConfirmations := 0
-	if height > 0 && transactions.headersTipHeight > 0 {
-		numConfirmations = transactions.headersTipHeight - height + 1
-	}
+	numConfirmations := transactions.numConfirmations(height)
 	btcutilTx := btcutil.NewTx(tx)
 	return &TxInfo{
-		Tx:               tx,
-		VSize:            mempool.GetTxVirtualSize(btcutilTx),
-		Size:             int64(tx.SerializeSize()),
-		Weight:           btcdBlockchain.GetTransactionWeight(btcutilTx),
-		numConfirmations: numConfirmations,
-		Height:           height,
-		txType:           txType,
-		amount:           result,
-		fee:              feeP,
-		timestamp:        timestamp,
-		addresses:        addresses,
-	}
-}
-
-// Transactions returns an ordered list of transactions.
+		Tx:                       tx,
+		VSize:                    mempool.GetTxVirtualSize(btcutilTx),
+		Size:                     int64(tx.SerializeSize()),
+		Weight:                   btcdBlockchain.GetTransactionWeight(btcutilTx),
+		numConfirmations:         numConfirmations,
+		numConfirmationsComplete: numConfirmationsComplete,
+		Height:                   height,
+		txType:                   txType,
+		amount:                   result,
+		fee:                      feeP,
+		timestamp:                timestamp,
+		addresses:                addresses,
+		inputs:                   inputs,
+		outputs:                  outputs,
+	}
+}
+
+// Transactions returns an ordered list of transactions. numConfirmationsComplete is the number of
+// confirmations after which a transaction is considered complete.
 func (transactions *Transactions) Transactions(
+	numConfirmationsComplete int,
 	isChange func(blockchain.ScriptHashHex) bool) []*TxInfo {
 	transactions.synchronizer.WaitSynchronized()
 	defer transactions.RLock()()
@@ -636,7 +852,8 @@ func (transactions *Transactions) Transactions(
 			// TODO
 			panic(err)
 		}
-		txs = append(txs, transactions.txInfo(dbTx, tx, height, timestamp, isChange))
+		txs = append(txs, transactions.txInfo(
+			dbTx, tx, height, timestamp, numConfirmationsComplete, isChange))
 	}
 	sort.Sort(sort.Reverse(byHeight(txs)))
 	return txs
@@ -41,6 +41,10 @@ import (
 	"github.com/stretchr/testify/suite"
 )
 
+// numConfirmationsComplete is the number of confirmations after which txs are complete in the
+// tests, the Bitcoin default.
+const numConfirmationsComplete = 6
+
 func TestMain(m *testing.M) {
 	test.TstSetupLogging()
 	os.Exit(m.Run())
@@ -100,7 +104,7 @@ func (s *transactionsSuite) SetupTest() {
 	s.log = logging.Get().WithGroup("transactions_test")
 
 	_, s.addressChain = addressesTest.NewAddressChain()
-	s.synchronizer = synchronizer.NewSynchronizer(func() {}, func() {}, s.log)
+	s.synchronizer = synchronizer.NewSynchronizer(func() {}, func() {}, func(float64) {}, s.log)
 	s.blockchainMock = NewBlockchainMock()
 	db, err := transactionsdb.NewDB(test.TstTempFile("bitbox-wallet-db-"))
 	if err != nil {
@@ -175,14 +179,17 @@ func (s *transactionsSuite) TestUpdateAddressHistorySingleTxReceive() {
 	utxo := &transactions.SpendableOutput{
 		TxOut:   wire.NewTxOut(int64(expectedAmount), address.PubkeyScript()),
 		Address: "n4PBA1ARca4UcMBnssfFpkF7LraS58SZ4y",
+		// Tip is at height 15.
+		NumConfirmations: 6,
 	}
 	require.Equal(s.T(),
 		map[wire.OutPoint]*transactions.SpendableOutput{
 			{Hash: tx1.TxHash(), Index: 0}: utxo,
 		},
-		s.transactions.SpendableOutputs(),
+		s.transactions.SpendableOutputs(1),
 	)
-	transactions := s.transactions.Transactions(func(blockchainpkg.ScriptHashHex) bool { return false })
+	transactions := s.transactions.Transactions(
+		numConfirmationsComplete, func(blockchainpkg.ScriptHashHex) bool { return false })
 	require.Len(s.T(), transactions, 1)
 	require.Equal(s.T(), tx1, transactions[0].Tx)
 	require.Equal(s.T(), expectedHeight, transactions[0].Height)
@@ -192,7 +199,7 @@ func (s *transactionsSuite) TestUpdateAddressHistorySingleTxReceive() {
 // we own) outputs can be spent.
 func (s *transactionsSuite) TestSpendableOutputs() {
 	// Starts out empty.
-	require.Empty(s.T(), s.transactions.SpendableOutputs())
+	require.Empty(s.T(), s.transactions.SpendableOutputs(1))
 	addresses := s.addressChain.EnsureAddresses()
 	address1 := addresses[0]
 	address2 := addresses[1]
@@ -215,11 +222,12 @@ func (s *transactionsSuite) TestSpendableOutputs() {
 		{TXHash: blockchainpkg.TXHash(tx22.TxHash()), Height: 10},
 	})
 
-	spendableOutputs := s.transactions.SpendableOutputs()
+	spendableOutputs := s.transactions.SpendableOutputs(1)
 	// Two confirmed txs.
 	require.Len(s.T(), spendableOutputs, 2)
 	require.Contains(s.T(), spendableOutputs, wire.OutPoint{Hash: tx12.TxHash(), Index: 0})
 	require.Contains(s.T(), spendableOutputs, wire.OutPoint{Hash: tx22.TxHash(), Index: 0})
+	require.Equal(s.T(), 6, spendableOutputs[wire.OutPoint{Hash: tx12.TxHash(), Index: 0}].NumConfirmations)
 	// Spend output generated from tx12 to an external address, the spend being unconfirmed => the
 	// output can't be spent anymore.
 	tx12Spend := newTx(tx12.TxHash(), 0, otherAddress, 1000)
@@ -229,7 +237,7 @@ func (s *transactionsSuite) TestSpendableOutputs() {
 		{TXHash: blockchainpkg.TXHash(tx12.TxHash()), Height: 10},
 		{TXHash: blockchainpkg.TXHash(tx12Spend.TxHash()), Height: 0},
 	})
-	spendableOutputs = s.transactions.SpendableOutputs()
+	spendableOutputs = s.transactions.SpendableOutputs(1)
 	require.Len(s.T(), spendableOutputs, 1)
 	require.NotContains(s.T(), spendableOutputs, wire.OutPoint{Hash: tx12.TxHash(), Index: 0})
 	require.Contains(s.T(), spendableOutputs, wire.OutPoint{Hash: tx22.TxHash(), Index: 0})
@@ -242,12 +250,141 @@ func (s *transactionsSuite) TestSpendableOutputs() {
 		{TXHash: blockchainpkg.TXHash(tx22.TxHash()), Height: 10},
 		{TXHash: blockchainpkg.TXHash(tx22Spend.TxHash()), Height: 0},
 	})
-	spendableOutputs = s.transactions.SpendableOutputs()
+	spendableOutputs = s.transactions.SpendableOutputs(1)
 	require.Len(s.T(), spendableOutputs, 1)
 	// tx22 spent, not available anymore
 	require.NotContains(s.T(), spendableOutputs, wire.OutPoint{Hash: tx22.TxHash(), Index: 0})
 	// Output from the spend tx address available.
 	require.Contains(s.T(), spendableOutputs, wire.OutPoint{Hash: tx22Spend.TxHash(), Index: 0})
+	require.Equal(s.T(), 0, spendableOutputs[wire.OutPoint{Hash: tx22Spend.TxHash(), Index: 0}].NumConfirmations)
+}
+
+// TestSpendableOutputsMinConfirmations checks that incoming outputs only become spendable once they
+// reach the minimum number of confirmations, while our own unconfirmed outputs are always spendable.
+func (s *transactionsSuite) TestSpendableOutputsMinConfirmations() {
+	addresses := s.addressChain.EnsureAddresses()
+	address1 := addresses[0]
+	address2 := addresses[1]
+	// Tip is at height 15: tx1 has 6 confirmations, tx2 has 2 confirmations.
+	tx1 := newTx(chainhash.HashH(nil), 0, address1, 1000)
+	tx2 := newTx(chainhash.HashH(nil), 1, address1, 2000)
+	// Unconfirmed, spending our own output.
+	tx3 := newTx(tx1.TxHash(), 0, address2, 900)
+	s.blockchainMock.RegisterTxs(tx1, tx2, tx3)
+	s.headersMock.On("VerifiedHeaderByHeight", 10).Return(nil, nil).Once()
+	s.headersMock.On("VerifiedHeaderByHeight", 14).Return(nil, nil).Once()
+	s.updateAddressHistory(address1, []*blockchainpkg.TxInfo{
+		{TXHash: blockchainpkg.TXHash(tx1.TxHash()), Height: 10},
+		{TXHash: blockchainpkg.TXHash(tx2.TxHash()), Height: 14},
+		{TXHash: blockchainpkg.TXHash(tx3.TxHash()), Height: 0},
+	})
+	s.updateAddressHistory(address2, []*blockchainpkg.TxInfo{
+		{TXHash: blockchainpkg.TXHash(tx3.TxHash()), Height: 0},
+	})
+
+	spendableValue := func(minConfirmations int) int64 {
+		value := int64(0)
+		for _, output := range s.transactions.SpendableOutputs(minConfirmations) {
+			value += output.TxOut.Value
+		}
+		return value
+	}
+	require.Equal(s.T(), int64(2900), spendableValue(0))
+	require.Equal(s.T(), int64(2900), spendableValue(1))
+	require.Equal(s.T(), int64(2900), spendableValue(2))
+	require.Equal(s.T(), int64(900), spendableValue(3))
+	require.Equal(s.T(), int64(900), spendableValue(100))
+}
+
+// TestTransactionsStatus checks that the complete status of a tx depends on the configured number
+// of confirmations, e.g. a higher finality depth for Litecoin.
+func (s *transactionsSuite) TestTransactionsStatus() {
+	address := s.addressChain.EnsureAddresses()[0]
+	// Tip is at height 15: tx1 has 6 confirmations, tx2 is unconfirmed.
+	tx1 := newTx(chainhash.HashH(nil), 0, address, 1000)
+	tx2 := newTx(chainhash.HashH(nil), 1, address, 2000)
+	s.blockchainMock.RegisterTxs(tx1, tx2)
+	s.headersMock.On("VerifiedHeaderByHeight", 10).Return(nil, nil).Once()
+	s.updateAddressHistory(address, []*blockchainpkg.TxInfo{
+		{TXHash: blockchainpkg.TXHash(tx1.TxHash()), Height: 10},
+		{TXHash: blockchainpkg.TXHash(tx2.TxHash()), Height: 0},
+	})
+
+	statuses := func(numConfirmationsComplete int) map[chainhash.Hash]accounts.TxStatus {
+		result := map[chainhash.Hash]accounts.TxStatus{}
+		for _, txInfo := range s.transactions.Transactions(
+			numConfirmationsComplete, func(blockchainpkg.ScriptHashHex) bool { return false }) {
+			require.Equal(s.T(), numConfirmationsComplete, txInfo.NumConfirmationsComplete())
+			result[txInfo.Tx.TxHash()] = txInfo.Status()
+		}
+		return result
+	}
+	require.Equal(s.T(),
+		map[chainhash.Hash]accounts.TxStatus{
+			tx1.TxHash(): accounts.TxStatusComplete,
+			tx2.TxHash(): accounts.TxStatusPending,
+		},
+		statuses(numConfirmationsComplete),
+	)
+	require.Equal(s.T(),
+		map[chainhash.Hash]accounts.TxStatus{
+			tx1.TxHash(): accounts.TxStatusPending,
+			tx2.TxHash(): accounts.TxStatusPending,
+		},
+		statuses(12),
+	)
+}
+
+// TestTransactionInputsAndOutputs checks that a tx lists all its inputs and outputs, including
+// inputs which are not ours and change outputs.
+func (s *transactionsSuite) TestTransactionInputsAndOutputs() {
+	addresses := s.addressChain.EnsureAddresses()
+	address1 := addresses[0]
+	address2 := addresses[1]
+	// address not belonging to the wallet.
+	otherAddress := addresses[2]
+	tx1 := newTx(chainhash.HashH(nil), 0, address1, 1000)
+	foreignOutPoint := wire.OutPoint{Hash: chainhash.HashH([]byte("foreign")), Index: 1}
+	tx2 := newTx(tx1.TxHash(), 0, otherAddress, 500)
+	tx2.AddTxIn(wire.NewTxIn(&foreignOutPoint, nil, nil))
+	tx2.AddTxOut(wire.NewTxOut(400, address2.PubkeyScript()))
+	s.blockchainMock.RegisterTxs(tx1, tx2)
+	s.headersMock.On("VerifiedHeaderByHeight", 10).Return(nil, nil).Once()
+	s.updateAddressHistory(address1, []*blockchainpkg.TxInfo{
+		{TXHash: blockchainpkg.TXHash(tx1.TxHash()), Height: 10},
+		{TXHash: blockchainpkg.TXHash(tx2.TxHash()), Height: 0},
+	})
+	s.updateAddressHistory(address2, []*blockchainpkg.TxInfo{
+		{TXHash: blockchainpkg.TXHash(tx2.TxHash()), Height: 0},
+	})
+
+	var txInfo *transactions.TxInfo
+	for _, transaction := range s.transactions.Transactions(
+		numConfirmationsComplete, func(blockchainpkg.ScriptHashHex) bool { return false }) {
+		if transaction.Tx.TxHash() == tx2.TxHash() {
+			txInfo = transaction
+		}
+	}
+	require.NotNil(s.T(), txInfo)
+	require.Equal(s.T(),
+		[]accounts.TransactionInput{
+			{
+				PreviousOutput: tx1.TxHash().String() + ":0",
+				Address:        address1.EncodeForHumans(),
+				Amount:         coin.NewAmountFromInt64(1000),
+				Ours:           true,
+			},
+			{PreviousOutput: foreignOutPoint.String()},
+		},
+		txInfo.Inputs(),
+	)
+	require.Equal(s.T(),
+		[]accounts.AddressAndAmount{
+			{Address: otherAddress.EncodeForHumans(), Amount: coin.NewAmountFromInt64(500)},
+			{Address: address2.EncodeForHumans(), Amount: coin.NewAmountFromInt64(400), Ours: true},
+		},
+		txInfo.Outputs(),
+	)
 }
 
 func (s *transactionsSuite) TestBalance() {
@@ -346,7 +483,8 @@ func (s *transactionsSuite) TestRemoveTransaction() {
 		newBalance(2+10+34, 0),
 		s.transactions.Balance())
 	require.Len(s.T(),
-		s.transactions.Transactions(func(blockchainpkg.ScriptHashHex) bool { return false }),
+		s.transactions.Transactions(
+			numConfirmationsComplete, func(blockchainpkg.ScriptHashHex) bool { return false }),
 		3)
 	// Remove tx3 from the history of address2. Now it's not referenced anymore and disappears.
 	s.updateAddressHistory(address2, []*blockchainpkg.TxInfo{
@@ -356,6 +494,140 @@ func (s *transactionsSuite) TestRemoveTransaction() {
 		newBalance(12+34, 0),
 		s.transactions.Balance())
 	require.Len(s.T(),
-		s.transactions.Transactions(func(blockchainpkg.ScriptHashHex) bool { return false }),
+		s.transactions.Transactions(
+			numConfirmationsComplete, func(blockchainpkg.ScriptHashHex) bool { return false }),
 		2)
 }
+
+// TestReplacedTransaction checks that a pending tx is removed once a tx spending the same output,
+// e.g. a fee bump (RBF), confirms, even if the history of its addresses still lists it.
+func (s *transactionsSuite) TestReplacedTransaction() {
+	addresses := s.addressChain.EnsureAddresses()
+	address1 := addresses[0]
+	address2 := addresses[1]
+	address3 := addresses[2]
+	isChange := func(blockchainpkg.ScriptHashHex) bool { return false }
+	tx1 := newTx(chainhash.HashH(nil), 0, address1, 1000)
+	pendingTx := newTx(tx1.TxHash(), 0, address2, 900)
+	replacementTx := newTx(tx1.TxHash(), 0, address3, 800)
+	s.blockchainMock.RegisterTxs(tx1, pendingTx, replacementTx)
+
+	s.headersMock.On("VerifiedHeaderByHeight", 10).Return(nil, nil).Once()
+	s.updateAddressHistory(address1, []*blockchainpkg.TxInfo{
+		{TXHash: blockchainpkg.TXHash(tx1.TxHash()), Height: 10},
+		{TXHash: blockchainpkg.TXHash(pendingTx.TxHash()), Height: 0},
+	})
+	s.updateAddressHistory(address2, []*blockchainpkg.TxInfo{
+		{TXHash: blockchainpkg.TXHash(pendingTx.TxHash()), Height: 0},
+	})
+	require.Equal(s.T(), newBalance(900, 0), s.transactions.Balance())
+	require.Len(s.T(), s.transactions.Transactions(numConfirmationsComplete, isChange), 2)
+
+	// The replacement confirms. The histories of address1 and address2 are not updated yet.
+	pendingTxHash := pendingTx.TxHash()
+	s.notifierMock.On("Delete", pendingTxHash[:]).Return(nil).Once()
+	s.headersMock.On("VerifiedHeaderByHeight", 12).Return(nil, nil).Once()
+	s.updateAddressHistory(address3, []*blockchainpkg.TxInfo{
+		{TXHash: blockchainpkg.TXHash(replacementTx.TxHash()), Height: 12},
+	})
+	require.Equal(s.T(), newBalance(800, 0), s.transactions.Balance())
+	transactions := s.transactions.Transactions(numConfirmationsComplete, isChange)
+	require.Len(s.T(), transactions, 2)
+	for _, transaction := range transactions {
+		require.NotEqual(s.T(), pendingTx.TxHash(), transaction.Tx.TxHash())
+	}
+	require.Equal(s.T(),
+		[]wire.OutPoint{{Hash: replacementTx.TxHash(), Index: 0}},
+		spendableOutPoints(s.transactions.SpendableOutputs(0)))
+
+	// A stale history still listing the replaced tx does not bring it back.
+	s.updateAddressHistory(address2, []*blockchainpkg.TxInfo{
+		{TXHash: blockchainpkg.TXHash(pendingTx.TxHash()), Height: 0},
+	})
+	require.Equal(s.T(), newBalance(800, 0), s.transactions.Balance())
+	require.Len(s.T(), s.transactions.Transactions(numConfirmationsComplete, isChange), 2)
+	s.notifierMock.AssertCalled(s.T(), "Delete", pendingTxHash[:])
+}
+
+// TestReplacedTransactionDependants checks that the pending txs spending the outputs of a replaced
+// tx are removed as well, as they can't confirm anymore.
+func (s *transactionsSuite) TestReplacedTransactionDependants() {
+	addresses := s.addressChain.EnsureAddresses()
+	address1 := addresses[0]
+	address2 := addresses[1]
+	address3 := addresses[2]
+	address4 := addresses[3]
+	address5 := addresses[4]
+	isChange := func(blockchainpkg.ScriptHashHex) bool { return false }
+	tx1 := newTx(chainhash.HashH(nil), 0, address1, 1000)
+	pendingTx := newTx(tx1.TxHash(), 0, address2, 500)
+	pendingTx.AddTxOut(wire.NewTxOut(400, address2.PubkeyScript()))
+	// Spends both outputs of the pending tx.
+	childTx := newTx(pendingTx.TxHash(), 0, address3, 850)
+	childTx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: pendingTx.TxHash(), Index: 1}, nil, nil))
+	grandchildTx := newTx(childTx.TxHash(), 0, address4, 800)
+	replacementTx := newTx(tx1.TxHash(), 0, address5, 950)
+	s.blockchainMock.RegisterTxs(tx1, pendingTx, childTx, grandchildTx, replacementTx)
+
+	s.headersMock.On("VerifiedHeaderByHeight", 10).Return(nil, nil).Once()
+	s.updateAddressHistory(address1, []*blockchainpkg.TxInfo{
+		{TXHash: blockchainpkg.TXHash(tx1.TxHash()), Height: 10},
+		{TXHash: blockchainpkg.TXHash(pendingTx.TxHash()), Height: 0},
+	})
+	s.updateAddressHistory(address2, []*blockchainpkg.TxInfo{
+		{TXHash: blockchainpkg.TXHash(pendingTx.TxHash()), Height: 0},
+		{TXHash: blockchainpkg.TXHash(childTx.TxHash()), Height: 0},
+	})
+	s.updateAddressHistory(address3, []*blockchainpkg.TxInfo{
+		{TXHash: blockchainpkg.TXHash(childTx.TxHash()), Height: 0},
+		{TXHash: blockchainpkg.TXHash(grandchildTx.TxHash()), Height: 0},
+	})
+	s.updateAddressHistory(address4, []*blockchainpkg.TxInfo{
+		{TXHash: blockchainpkg.TXHash(grandchildTx.TxHash()), Height: 0},
+	})
+	require.Equal(s.T(), newBalance(800, 0), s.transactions.Balance())
+	require.Len(s.T(), s.transactions.Transactions(numConfirmationsComplete, isChange), 4)
+
+	// The replacement confirms. The histories of the other addresses are not updated yet.
+	for _, tx := range []*wire.MsgTx{pendingTx, childTx, grandchildTx} {
+		txHash := tx.TxHash()
+		s.notifierMock.On("Delete", txHash[:]).Return(nil).Once()
+	}
+	s.headersMock.On("VerifiedHeaderByHeight", 12).Return(nil, nil).Once()
+	s.updateAddressHistory(address5, []*blockchainpkg.TxInfo{
+		{TXHash: blockchainpkg.TXHash(replacementTx.TxHash()), Height: 12},
+	})
+	require.Equal(s.T(), newBalance(950, 0), s.transactions.Balance())
+	transactions := s.transactions.Transactions(numConfirmationsComplete, isChange)
+	require.Len(s.T(), transactions, 2)
+	for _, transaction := range transactions {
+		require.Contains(s.T(),
+			[]chainhash.Hash{tx1.TxHash(), replacementTx.TxHash()}, transaction.Tx.TxHash())
+	}
+	require.Equal(s.T(),
+		[]wire.OutPoint{{Hash: replacementTx.TxHash(), Index: 0}},
+		spendableOutPoints(s.transactions.SpendableOutputs(0)))
+	for _, tx := range []*wire.MsgTx{pendingTx, childTx, grandchildTx} {
+		txHash := tx.TxHash()
+		s.notifierMock.AssertCalled(s.T(), "Delete", txHash[:])
+	}
+
+	// The stale history of address3 still lists the removed txs, which are not indexed again.
+	s.updateAddressHistory(address3, []*blockchainpkg.TxInfo{
+		{TXHash: blockchainpkg.TXHash(childTx.TxHash()), Height: 0},
+		{TXHash: blockchainpkg.TXHash(grandchildTx.TxHash()), Height: 0},
+	})
+	require.Equal(s.T(), newBalance(950, 0), s.transactions.Balance())
+	require.Len(s.T(), s.transactions.Transactions(numConfirmationsComplete, isChange), 2)
+	require.Equal(s.T(),
+		[]wire.OutPoint{{Hash: replacementTx.TxHash(), Index: 0}},
+		spendableOutPoints(s.transactions.SpendableOutputs(0)))
+}
+
+func spendableOutPoints(outputs map[wire.OutPoint]*transactions.SpendableOutput) []wire.OutPoint {
+	outPoints := []wire.OutPoint{}
+	for outPoint := range outputs {
+		outPoints = append(outPoints, outPoint)
+	}
+	return outPoints
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/rates"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
 )
 
 func formatAsCurrency(amount float64) string {
@@ -17,15 +18,22 @@ func formatAsCurrency(amount float64) string {
 	return formatted
 }
 
+// RatesUnit returns the unit under which the rates of the coin are listed. Testnet coins use the
+// rates of their mainnet counterparts.
+func RatesUnit(coin Coin, isFee bool) string {
+	unit := coin.Unit(isFee)
+	if len(unit) == 4 && strings.HasPrefix(unit, "T") || unit == "RETH" {
+		unit = unit[1:]
+	}
+	return unit
+}
+
 // Conversions handles fiat conversions
 func Conversions(amount Amount, coin Coin, isFee bool, ratesUpdater *rates.RateUpdater) map[string]string {
 	var conversions map[string]string
 	rates := ratesUpdater.Last()
 	if rates != nil {
-		unit := coin.Unit(isFee)
-		if len(unit) == 4 && strings.HasPrefix(unit, "T") || unit == "RETH" {
-			unit = unit[1:]
-		}
+		unit := RatesUnit(coin, isFee)
 		float := coin.ToUnit(amount, isFee)
 		conversions = map[string]string{}
 		for key, value := range rates[unit] {
@@ -34,3 +42,26 @@ func Conversions(amount Amount, coin Coin, isFee bool, ratesUpdater *rates.RateU
 	}
 	return conversions
 }
+
+// FiatValue returns the value of the amount in the given fiat currency using the given rates, which
+// are in the format returned by `rates.RateUpdater.Last()`. An error is returned if there is no rate
+// for the coin and fiat currency, e.g. if the rates have not been fetched yet.
+func FiatValue(
+	amount Amount, coin Coin, isFee bool, fiat string, rates map[string]map[string]float64) (float64, error) {
+	unit := RatesUnit(coin, isFee)
+	rate, ok := rates[unit][fiat]
+	if !ok {
+		return 0, errp.Newf("no exchange rate available for %s/%s", unit, fiat)
+	}
+	return coin.ToUnit(amount, isFee) * rate, nil
+}
+
+// ConvertToFiat converts the amount to the given fiat currency and formats it, see FiatValue().
+func ConvertToFiat(
+	amount Amount, coin Coin, isFee bool, fiat string, rates map[string]map[string]float64) (string, error) {
+	value, err := FiatValue(amount, coin, isFee, fiat, rates)
+	if err != nil {
+		return "", err
+	}
+	return formatAsCurrency(value), nil
+}
@@ -0,0 +1,57 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package coin_test
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/socksproxy"
+	"github.com/stretchr/testify/require"
+)
+
+var testRates = map[string]map[string]float64{
+	"BTC": {"USD": 10000, "EUR": 9001.2},
+}
+
+func TestConvertToFiat(t *testing.T) {
+	btcCoin := btc.NewCoin("btc", "BTC", &chaincfg.MainNetParams, "", nil, "",
+		socksproxy.NewSocksProxy(false, ""))
+	tbtcCoin := btc.NewCoin("tbtc", "TBTC", &chaincfg.TestNet3Params, "", nil, "",
+		socksproxy.NewSocksProxy(false, ""))
+
+	fiat, err := coin.ConvertToFiat(coin.NewAmountFromInt64(150000000), btcCoin, false, "USD", testRates)
+	require.NoError(t, err)
+	require.Equal(t, "15'000.00", fiat)
+
+	fiat, err = coin.ConvertToFiat(coin.NewAmountFromInt64(1000000), btcCoin, false, "EUR", testRates)
+	require.NoError(t, err)
+	require.Equal(t, "90.01", fiat)
+
+	// Testnet coins use the mainnet rates.
+	fiat, err = coin.ConvertToFiat(coin.NewAmountFromInt64(100000000), tbtcCoin, false, "USD", testRates)
+	require.NoError(t, err)
+	require.Equal(t, "10'000.00", fiat)
+
+	// Unsupported fiat.
+	_, err = coin.ConvertToFiat(coin.NewAmountFromInt64(100000000), btcCoin, false, "XYZ", testRates)
+	require.Error(t, err)
+
+	// Rates not available yet.
+	_, err = coin.ConvertToFiat(coin.NewAmountFromInt64(100000000), btcCoin, false, "USD", nil)
+	require.Error(t, err)
+}
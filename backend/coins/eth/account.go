@@ -46,8 +46,6 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-var pollInterval = 30 * time.Second
-
 // Account is an Ethereum account, with one address.
 type Account struct {
 	locker.Locker
@@ -68,6 +66,14 @@ type Account struct {
 	notifier                accounts.Notifier
 	offline                 bool
 	onEvent                 func(accounts.Event)
+	// getPollInterval returns the interval in which the account is refreshed.
+	getPollInterval func() time.Duration
+	// getNumConfirmationsComplete returns the number of confirmations after which a tx is
+	// complete.
+	getNumConfirmationsComplete func() int
+	// getGasLimitMultiplier returns the factor by which the estimated gas of contract interactions
+	// is increased.
+	getGasLimitMultiplier func() float64
 
 	initialized bool
 	// enqueueUpdateCh is used to invoke an account update outside of the regular poll update
@@ -77,6 +83,8 @@ type Account struct {
 	address     Address
 	balance     coin.Amount
 	blockNumber *big.Int
+	// blockGasLimit is the gas limit of the latest block.
+	blockGasLimit uint64
 
 	nextNonce    uint64
 	transactions []accounts.Transaction
@@ -96,6 +104,9 @@ func NewAccount(
 	getSigningConfiguration func() (*signing.Configuration, error),
 	keystores *keystore.Keystores,
 	getNotifier func(*signing.Configuration) accounts.Notifier,
+	getPollInterval func() time.Duration,
+	getNumConfirmationsComplete func() int,
+	getGasLimitMultiplier func() float64,
 	onEvent func(accounts.Event),
 	log *logrus.Entry,
 	rateUpdater *rates.RateUpdater,
@@ -115,8 +126,12 @@ func NewAccount(
 		keystores:               keystores,
 		getNotifier:             getNotifier,
 		onEvent:                 onEvent,
+		getPollInterval:         getPollInterval,
 		balance:                 coin.NewAmountFromInt64(0),
 
+		getNumConfirmationsComplete: getNumConfirmationsComplete,
+		getGasLimitMultiplier:       getGasLimitMultiplier,
+
 		initialized:     false,
 		enqueueUpdateCh: make(chan struct{}),
 		quitChan:        make(chan struct{}),
@@ -132,6 +147,7 @@ func NewAccount(
 			}
 			onEvent(accounts.EventSyncDone)
 		},
+		func(float64) { onEvent(accounts.EventSyncProgress) },
 		log,
 	)
 	return account
@@ -270,13 +286,13 @@ func (account *Account) poll() {
 				account.offline = false
 				account.onEvent(accounts.EventStatusChanged)
 			}
-			timer = time.After(pollInterval)
+			timer = time.After(account.getPollInterval())
 		}
 	}
 }
 
 // updateOutgoingTransactions updates the height of the stored outgoing transactions.
-// We update heights for tx with up to 12 confirmations, so re-orgs are taken into account.
+// We update heights for tx until they are complete, so re-orgs are taken into account.
 // tipHeight is the current blockchain height.
 func (account *Account) updateOutgoingTransactions(tipHeight uint64) {
 	defer account.synchronizer.IncRequestsCounter()()
@@ -295,7 +311,8 @@ func (account *Account) updateOutgoingTransactions(tipHeight uint64) {
 		return
 	}
 
-	// Update the stored txs' metadata if up to 12 confirmations.
+	// Update the stored txs' metadata until they are complete.
+	numConfirmationsComplete := uint64(account.getNumConfirmationsComplete())
 	for _, tx := range outgoingTransactions {
 		remoteTx, err := account.coin.client.TransactionReceiptWithBlockNumber(context.TODO(), tx.Transaction.Hash())
 		if err != nil {
@@ -306,7 +323,7 @@ func (account *Account) updateOutgoingTransactions(tipHeight uint64) {
 			continue
 		}
 		success := remoteTx.Status == types.ReceiptStatusSuccessful
-		if tx.Height == 0 || (tipHeight-remoteTx.BlockNumber) < ethtypes.NumConfirmationsComplete || tx.Success != success {
+		if tx.Height == 0 || (tipHeight-remoteTx.BlockNumber) < numConfirmationsComplete || tx.Success != success {
 			tx.Height = remoteTx.BlockNumber
 			tx.GasUsed = remoteTx.GasUsed
 			tx.Success = success
@@ -349,7 +366,9 @@ func (account *Account) outgoingTransactions(allTxs []accounts.Transaction) (
 			continue
 		}
 		transactions = append(transactions,
-			ethtypes.NewTransactionWithConfirmations(tx, account.blockNumber.Uint64(), account.coin.erc20Token))
+			ethtypes.NewTransactionWithConfirmations(
+				tx, account.blockNumber.Uint64(), account.getNumConfirmationsComplete(),
+				account.coin.erc20Token))
 	}
 	return transactions, nil
 }
@@ -362,6 +381,7 @@ func (account *Account) update() error {
 		return errp.WithStack(err)
 	}
 	account.blockNumber = header.Number
+	account.blockGasLimit = header.GasLimit
 
 	transactionsSource := account.coin.TransactionsSource()
 
@@ -372,7 +392,7 @@ func (account *Account) update() error {
 	if transactionsSource != nil {
 		var err error
 		confirmedTansactions, err = transactionsSource.Transactions(
-			account.blockNumber,
+			account.blockNumber, account.getNumConfirmationsComplete(),
 			account.address.Address, account.blockNumber, account.coin.erc20Token)
 		if err != nil {
 			return err
@@ -437,6 +457,11 @@ func (account *Account) Initialized() bool {
 	return account.initialized
 }
 
+// SyncProgress implements accounts.Interface.
+func (account *Account) SyncProgress() float64 {
+	return account.synchronizer.Progress()
+}
+
 // Offline implements accounts.Interface.
 func (account *Account) Offline() bool {
 	return account.offline
@@ -480,6 +505,38 @@ func (account *Account) Balance() (*accounts.Balance, error) {
 	return accounts.NewBalance(account.balance, coin.NewAmountFromInt64(0)), nil
 }
 
+// BalanceBreakdown implements accounts.Interface. The balance reported by the node does not include
+// pending transactions yet, so pending outgoing transactions are subtracted from the spendable
+// amount and pending incoming transactions are incoming. Mined transactions are part of the
+// balance, so there is nothing incoming once a transaction is confirmed. The fees of ERC20
+// transfers are paid in ether and do not reduce the token balance. Nothing is frozen in Ethereum
+// accounts.
+func (account *Account) BalanceBreakdown() (*accounts.BalanceBreakdown, error) {
+	account.synchronizer.WaitSynchronized()
+	spendable := new(big.Int).Set(account.balance.BigInt())
+	incoming := new(big.Int)
+	for _, transaction := range account.transactions {
+		if transaction.NumConfirmations() != 0 {
+			continue
+		}
+		switch transaction.Type() {
+		case accounts.TxTypeReceive:
+			incoming.Add(incoming, transaction.Amount().BigInt())
+			continue
+		case accounts.TxTypeSend:
+			spendable.Sub(spendable, transaction.Amount().BigInt())
+		}
+		if fee := transaction.Fee(); fee != nil && account.coin.erc20Token == nil {
+			spendable.Sub(spendable, fee.BigInt())
+		}
+	}
+	if spendable.Sign() < 0 {
+		spendable.SetInt64(0)
+	}
+	return accounts.NewBalanceBreakdown(
+		coin.NewAmount(spendable), coin.NewAmount(incoming), coin.NewAmountFromInt64(0)), nil
+}
+
 // TxProposal holds all info needed to create and sign a transacstion.
 type TxProposal struct {
 	Coin coin.Coin
@@ -488,24 +545,48 @@ type TxProposal struct {
 	// Value can be the same as Tx.Value(), but in case of e.g. ERC20, tx.Value() is zero, while the
 	// Token value is encoded in the contract input data.
 	Value *big.Int
-	// Signer contains the sighash algo, which depends on the block number.
+	// Signer contains the sighash algo. It always commits to the chain ID (EIP155) to protect
+	// against replays on other chains.
 	Signer types.Signer
 	// KeyPath is the location of this account's address/pubkey/privkey.
 	Keypath signing.AbsoluteKeypath
 }
 
+// VerifyChainID returns an error if the tx proposal does not commit to the given chain ID as per
+// EIP155, so that the transaction can't be replayed on another chain. If the transaction is already
+// signed, the chain ID of the signature is checked as well.
+func (txProposal *TxProposal) VerifyChainID(chainID *big.Int) error {
+	if !txProposal.Signer.Equal(types.NewEIP155Signer(chainID)) {
+		return errp.Newf("tx proposal is not signed for chain ID %s", chainID)
+	}
+	if v, _, _ := txProposal.Tx.RawSignatureValues(); v.Sign() == 0 {
+		return nil
+	}
+	if !txProposal.Tx.Protected() || txProposal.Tx.ChainId().Cmp(chainID) != 0 {
+		return errp.Newf("signed tx does not match chain ID %s", chainID)
+	}
+	return nil
+}
+
+// newTx creates a tx to the recipient. If gasPrice is nil, the gas price suggested by the node is
+// used.
 func (account *Account) newTx(
 	recipientAddress string,
 	amount coin.SendAmount,
 	data []byte,
+	gasPrice *big.Int,
 ) (*TxProposal, error) {
-	if !common.IsHexAddress(recipientAddress) {
+	if !IsValidAddress(recipientAddress) {
 		return nil, errp.WithStack(errors.ErrInvalidAddress)
 	}
 
-	suggestedGasPrice, err := account.coin.client.SuggestGasPrice(context.TODO())
-	if err != nil {
-		return nil, err
+	suggestedGasPrice := gasPrice
+	if suggestedGasPrice == nil {
+		var err error
+		suggestedGasPrice, err = account.coin.client.SuggestGasPrice(context.TODO())
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	var value *big.Int
@@ -565,16 +646,17 @@ func (account *Account) newTx(
 		}
 	}
 	gasLimit := uint64(21000) // gas limit for standard ethereum transactions
-	if account.coin.erc20Token != nil {
+	// ERC20 transfers and txs with data call a contract, which needs more gas.
+	if account.coin.erc20Token != nil || len(message.Data) > 0 {
 		n, err := account.coin.client.EstimateGas(context.TODO(), message)
 		if err != nil {
 			account.log.WithError(err).Error("Could not estimate the gas limit.")
 			return nil, errp.WithStack(errors.ErrInvalidData)
 		}
-		gasLimit = n
+		gasLimit = ContractGasLimit(n, account.getGasLimitMultiplier(), account.blockGasLimit)
 	}
 
-	fee := new(big.Int).Mul(new(big.Int).SetUint64(gasLimit), suggestedGasPrice)
+	fee := Fee(gasLimit, suggestedGasPrice)
 
 	// Adjust amount with fee
 	if account.coin.erc20Token != nil {
@@ -587,11 +669,12 @@ func (account *Account) newTx(
 	} else {
 		if amount.SendAll() {
 			// Set the value correctly and check that the fee is smaller than or equal to the balance.
-			value = new(big.Int).Sub(account.balance.BigInt(), fee)
-			message.Value = value
-			if message.Value.Sign() < 0 {
-				return nil, errp.WithStack(errors.ErrInsufficientFunds)
+			var err error
+			value, err = SendAllValue(account.balance.BigInt(), gasLimit, suggestedGasPrice)
+			if err != nil {
+				return nil, err
 			}
+			message.Value = value
 		} else {
 			// Check that the entered value and the estimated fee are not greater than the balance.
 			total := new(big.Int).Add(message.Value, fee)
@@ -608,7 +691,7 @@ func (account *Account) newTx(
 		Tx:      tx,
 		Fee:     fee,
 		Value:   value,
-		Signer:  types.MakeSigner(account.coin.Net(), account.blockNumber),
+		Signer:  types.NewEIP155Signer(account.coin.Net().ChainID),
 		Keypath: account.signingConfiguration.AbsoluteKeypath(),
 	}, nil
 }
@@ -642,13 +725,20 @@ func (account *Account) SendTx(
 	_ map[wire.OutPoint]struct{},
 	data []byte) error {
 	account.log.Info("Signing and sending transaction")
-	txProposal, err := account.newTx(recipientAddress, amount, data)
+	txProposal, err := account.newTx(recipientAddress, amount, data, nil)
 	if err != nil {
 		return err
 	}
+	chainID := account.coin.Net().ChainID
+	if err := txProposal.VerifyChainID(chainID); err != nil {
+		return err
+	}
 	if err := account.keystores.SignTransaction(txProposal); err != nil {
 		return err
 	}
+	if err := txProposal.VerifyChainID(chainID); err != nil {
+		return err
+	}
 	if err := account.coin.client.SendTransaction(context.TODO(), txProposal.Tx); err != nil {
 		return errp.WithStack(err)
 	}
@@ -659,6 +749,28 @@ func (account *Account) SendTx(
 	return nil
 }
 
+// SignMessage signs the message with the account's key according to personal_sign. The result is
+// the 65 byte recoverable signature [R || S || V], with V being 27 or 28. Returns
+// keystore.ErrSigningAborted on user abort.
+func (account *Account) SignMessage(message []byte) ([]byte, error) {
+	if account.signingConfiguration == nil {
+		return nil, errp.New("account must be initialized")
+	}
+	if account.keystores.Count() != 1 {
+		return nil, errp.New("signing messages requires exactly one keystore")
+	}
+	signature, err := account.keystores.Keystores()[0].SignETHMessage(
+		account.coin, account.signingConfiguration.AbsoluteKeypath(), message)
+	if err != nil {
+		return nil, err
+	}
+	if len(signature) != 65 {
+		return nil, errp.Newf("unexpected signature length: %d", len(signature))
+	}
+	signature[64] += 27
+	return signature, nil
+}
+
 // FeeTargets implements accounts.Interface.
 func (account *Account) FeeTargets() ([]accounts.FeeTarget, accounts.FeeTargetCode) {
 	return nil, ""
@@ -672,7 +784,7 @@ func (account *Account) TxProposal(
 	_ map[wire.OutPoint]struct{},
 	data []byte) (coin.Amount, coin.Amount, coin.Amount, error) {
 
-	txProposal, err := account.newTx(recipientAddress, amount, data)
+	txProposal, err := account.newTx(recipientAddress, amount, data, nil)
 	if err != nil {
 		return coin.Amount{}, coin.Amount{}, coin.Amount{}, err
 	}
@@ -686,6 +798,18 @@ func (account *Account) TxProposal(
 	return coin.NewAmount(txProposal.Value), coin.NewAmount(txProposal.Fee), coin.NewAmount(total), nil
 }
 
+// SendAllTxProposal returns the maximum amount which can be sent to the recipient at the given gas
+// price in wei, and the fee. For ether, this is the balance minus the fee. For ERC20 tokens, it is
+// the token balance, as the fee is paid in ether.
+func (account *Account) SendAllTxProposal(recipientAddress string, gasPrice *big.Int) (
+	coin.Amount, coin.Amount, error) {
+	txProposal, err := account.newTx(recipientAddress, coin.NewSendAmountAll(), nil, gasPrice)
+	if err != nil {
+		return coin.Amount{}, coin.Amount{}, err
+	}
+	return coin.NewAmount(txProposal.Value), coin.NewAmount(txProposal.Fee), nil
+}
+
 // GetUnusedReceiveAddresses implements accounts.Interface.
 func (account *Account) GetUnusedReceiveAddresses() []accounts.Address {
 	return []accounts.Address{account.address}
@@ -709,6 +833,49 @@ func (account *Account) VerifyAddress(addressID string) (bool, error) {
 	return false, nil
 }
 
+// Refresh updates the account right away instead of waiting for the next poll.
+func (account *Account) Refresh() error {
+	if account.signingConfiguration == nil {
+		return errp.New("account must be initialized")
+	}
+	select {
+	case account.enqueueUpdateCh <- struct{}{}:
+	case <-account.quitChan:
+		return errp.New("account was closed")
+	}
+	return nil
+}
+
+// AddressInfo returns the keypath of the single address of the account. An error is returned if
+// the given address is not the account address.
+func (account *Account) AddressInfo(address string) (*accounts.AddressInfo, error) {
+	if account.signingConfiguration == nil {
+		return nil, errp.New("account must be initialized")
+	}
+	if !common.IsHexAddress(address) || common.HexToAddress(address) != account.address.Address {
+		return nil, errp.New("unknown address not found")
+	}
+	return &accounts.AddressInfo{
+		Keypath: account.signingConfiguration.AbsoluteKeypath().Encode(),
+	}, nil
+}
+
+// AddressUsed implements accounts.Interface. Ethereum accounts have a single address which is
+// always reused, so this is informational only.
+func (account *Account) AddressUsed(addressID string) (bool, error) {
+	if account.signingConfiguration == nil {
+		return false, errp.New("account must be initialized")
+	}
+	if addressID != account.address.ID() {
+		return false, errp.New("unknown address not found")
+	}
+	transactions, err := account.Transactions()
+	if err != nil {
+		return false, err
+	}
+	return len(transactions) > 0, nil
+}
+
 // CanVerifyAddresses implements accounts.Interface.
 func (account *Account) CanVerifyAddresses() (bool, bool, error) {
 	if account.signingConfiguration == nil {
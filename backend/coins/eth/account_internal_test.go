@@ -0,0 +1,226 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eth
+
+import (
+	"context"
+	"math/big"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/synchronizer"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/erc20"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/rpcclient"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/logging"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/socksproxy"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/test"
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+// estimateGasClient is an rpc client which only estimates gas.
+type estimateGasClient struct {
+	rpcclient.Interface
+	gas uint64
+}
+
+func (client *estimateGasClient) EstimateGas(context.Context, ethereum.CallMsg) (uint64, error) {
+	return client.gas, nil
+}
+
+func newGasTestAccount(t *testing.T, erc20Token *erc20.Token) *Account {
+	t.Helper()
+	ethCoin := NewCoin("teth", "TETH", "TETH", params.TestnetChainConfig, "",
+		TransactionsSourceNone, "", erc20Token, socksproxy.NewSocksProxy(false, ""))
+	ethCoin.client = &estimateGasClient{gas: 50000}
+	keypath, err := signing.NewAbsoluteKeypath("m/44'/1'/0'/0/0")
+	require.NoError(t, err)
+	const address = "0x0000000000000000000000000000000000000002"
+	return &Account{
+		coin: ethCoin,
+		signingConfiguration: signing.NewAddressConfiguration(
+			signing.ScriptTypeP2WPKH, keypath, address),
+		getGasLimitMultiplier: func() float64 { return 1.5 },
+		balance:               coin.NewAmountFromInt64(1000000000000000000),
+		blockGasLimit:         10000000,
+		log:                   logging.Get().WithGroup("account_test"),
+	}
+}
+
+func TestNewTxGasLimit(t *testing.T) {
+	const recipient = "0x0000000000000000000000000000000000000001"
+	gasPrice := big.NewInt(1)
+	amount := coin.NewSendAmount("0.001")
+
+	// Plain transfer: fixed gas limit, no multiplier.
+	account := newGasTestAccount(t, nil)
+	txProposal, err := account.newTx(recipient, amount, nil, gasPrice)
+	require.NoError(t, err)
+	require.Equal(t, uint64(21000), txProposal.Tx.Gas())
+
+	// Contract call with data: estimated gas with the multiplier applied.
+	txProposal, err = account.newTx(recipient, amount, []byte{0x01, 0x02}, gasPrice)
+	require.NoError(t, err)
+	require.Equal(t, uint64(75000), txProposal.Tx.Gas())
+
+	// ERC20 transfer.
+	account = newGasTestAccount(t,
+		erc20.NewToken("0x0000000000000000000000000000000000000003", 18))
+	txProposal, err = account.newTx(recipient, amount, nil, gasPrice)
+	require.NoError(t, err)
+	require.Equal(t, uint64(75000), txProposal.Tx.Gas())
+
+	// Capped at the block gas limit.
+	account.blockGasLimit = 60000
+	txProposal, err = account.newTx(recipient, amount, nil, gasPrice)
+	require.NoError(t, err)
+	require.Equal(t, uint64(60000), txProposal.Tx.Gas())
+}
+
+type breakdownTestTransaction struct {
+	accounts.Transaction
+	txType           accounts.TxType
+	amount           int64
+	fee              int64
+	numConfirmations int
+}
+
+func (tx *breakdownTestTransaction) Type() accounts.TxType { return tx.txType }
+func (tx *breakdownTestTransaction) Amount() coin.Amount   { return coin.NewAmountFromInt64(tx.amount) }
+func (tx *breakdownTestTransaction) NumConfirmations() int { return tx.numConfirmations }
+func (tx *breakdownTestTransaction) Fee() *coin.Amount {
+	if tx.txType == accounts.TxTypeReceive {
+		return nil
+	}
+	fee := coin.NewAmountFromInt64(tx.fee)
+	return &fee
+}
+
+func TestBalanceBreakdown(t *testing.T) {
+	transactions := []accounts.Transaction{
+		// Pending outgoing transactions are not part of the node balance yet.
+		&breakdownTestTransaction{txType: accounts.TxTypeSend, amount: 100, fee: 10},
+		&breakdownTestTransaction{txType: accounts.TxTypeSendSelf, amount: 30, fee: 5},
+		// Pending incoming transactions are not part of the node balance yet.
+		&breakdownTestTransaction{txType: accounts.TxTypeReceive, amount: 50},
+		// Confirmed transactions are part of the node balance.
+		&breakdownTestTransaction{
+			txType: accounts.TxTypeSend, amount: 300, fee: 20, numConfirmations: 1},
+		&breakdownTestTransaction{
+			txType: accounts.TxTypeReceive, amount: 200, numConfirmations: 1},
+	}
+	newAccount := func(erc20Token *erc20.Token) *Account {
+		account := newGasTestAccount(t, erc20Token)
+		account.synchronizer = synchronizer.NewSynchronizer(
+			func() {}, func() {}, func(float64) {}, account.log)
+		account.balance = coin.NewAmountFromInt64(1000)
+		account.transactions = transactions
+		return account
+	}
+
+	breakdown, err := newAccount(nil).BalanceBreakdown()
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(885), breakdown.Spendable.BigInt())
+	require.Equal(t, big.NewInt(50), breakdown.Incoming.BigInt())
+	require.Equal(t, big.NewInt(0), breakdown.Frozen.BigInt())
+	require.Equal(t, big.NewInt(935), breakdown.Total.BigInt())
+
+	// The fees of ERC20 transfers are paid in ether and do not reduce the token balance.
+	breakdown, err = newAccount(
+		erc20.NewToken("0x0000000000000000000000000000000000000003", 18)).BalanceBreakdown()
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(900), breakdown.Spendable.BigInt())
+	require.Equal(t, big.NewInt(50), breakdown.Incoming.BigInt())
+	require.Equal(t, big.NewInt(950), breakdown.Total.BigInt())
+
+	// The spendable amount does not become negative.
+	account := newAccount(nil)
+	account.balance = coin.NewAmountFromInt64(50)
+	breakdown, err = account.BalanceBreakdown()
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(0), breakdown.Spendable.BigInt())
+}
+
+// pollClient is an rpc client which counts the account updates.
+type pollClient struct {
+	rpcclient.Interface
+	updates int32
+}
+
+func (client *pollClient) HeaderByNumber(context.Context, *big.Int) (*types.Header, error) {
+	atomic.AddInt32(&client.updates, 1)
+	return &types.Header{Number: big.NewInt(100), GasLimit: 10000000}, nil
+}
+
+func (client *pollClient) PendingNonceAt(context.Context, common.Address) (uint64, error) {
+	return 0, nil
+}
+
+func (client *pollClient) BalanceAt(context.Context, common.Address, *big.Int) (*big.Int, error) {
+	return big.NewInt(1), nil
+}
+
+func TestPollInterval(t *testing.T) {
+	ethCoin := NewCoin("teth", "TETH", "TETH", params.TestnetChainConfig, "",
+		TransactionsSourceNone, "", nil, socksproxy.NewSocksProxy(false, ""))
+	client := &pollClient{}
+	ethCoin.client = client
+	// The client is set already, don't connect to a node.
+	ethCoin.initOnce.Do(func() {})
+
+	keypath, err := signing.NewAbsoluteKeypath("m/44'/1'/0'/0")
+	require.NoError(t, err)
+	var pollIntervalRequested int32
+	account := NewAccount(
+		ethCoin,
+		test.TstTempDir("eth-poll-interval"),
+		"teth",
+		"Ethereum Ropsten",
+		func() (*signing.Configuration, error) {
+			return signing.NewAddressConfiguration(signing.ScriptTypeP2WPKH, keypath,
+				"0x0000000000000000000000000000000000000002"), nil
+		},
+		nil,
+		func(*signing.Configuration) accounts.Notifier { return nil },
+		func() time.Duration {
+			atomic.AddInt32(&pollIntervalRequested, 1)
+			return 10 * time.Millisecond
+		},
+		func() int { return 12 },
+		func() float64 { return 1 },
+		func(accounts.Event) {},
+		logging.Get().WithGroup("account_test"),
+		nil,
+	)
+	require.NoError(t, account.Initialize())
+	defer account.Close()
+
+	// With the default interval of 30 seconds, there would be only one update.
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&client.updates) < 3 {
+		if time.Now().After(deadline) {
+			require.FailNow(t, "the account was not updated in the configured interval")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.True(t, atomic.LoadInt32(&pollIntervalRequested) >= 2)
+}
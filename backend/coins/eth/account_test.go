@@ -0,0 +1,62 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eth_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxProposalVerifyChainID(t *testing.T) {
+	mainnetChainID := params.MainnetChainConfig.ChainID
+	testnetChainID := params.TestnetChainConfig.ChainID
+	tx := types.NewTransaction(0, common.HexToAddress("0x0000000000000000000000000000000000000001"),
+		big.NewInt(1), 21000, big.NewInt(1), nil)
+
+	txProposal := &eth.TxProposal{Tx: tx, Signer: types.NewEIP155Signer(mainnetChainID)}
+	require.NoError(t, txProposal.VerifyChainID(mainnetChainID))
+	require.Error(t, txProposal.VerifyChainID(testnetChainID))
+
+	// Signers without replay protection are rejected.
+	txProposal = &eth.TxProposal{Tx: tx, Signer: types.HomesteadSigner{}}
+	require.Error(t, txProposal.VerifyChainID(mainnetChainID))
+
+	privateKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+
+	signedTx, err := types.SignTx(tx, types.NewEIP155Signer(mainnetChainID), privateKey)
+	require.NoError(t, err)
+	txProposal = &eth.TxProposal{Tx: signedTx, Signer: types.NewEIP155Signer(mainnetChainID)}
+	require.NoError(t, txProposal.VerifyChainID(mainnetChainID))
+
+	// The signature was made for a different chain.
+	signedTx, err = types.SignTx(tx, types.NewEIP155Signer(testnetChainID), privateKey)
+	require.NoError(t, err)
+	txProposal = &eth.TxProposal{Tx: signedTx, Signer: types.NewEIP155Signer(mainnetChainID)}
+	require.Error(t, txProposal.VerifyChainID(mainnetChainID))
+
+	// The signature is not replay protected.
+	signedTx, err = types.SignTx(tx, types.HomesteadSigner{}, privateKey)
+	require.NoError(t, err)
+	txProposal = &eth.TxProposal{Tx: signedTx, Signer: types.NewEIP155Signer(mainnetChainID)}
+	require.Error(t, txProposal.VerifyChainID(mainnetChainID))
+}
@@ -14,7 +14,11 @@
 
 package eth
 
-import "github.com/ethereum/go-ethereum/common"
+import (
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
 
 // Address holds an Ethereum address and implements coin.Address.
 type Address struct {
@@ -30,3 +34,20 @@ func (address Address) ID() string {
 func (address Address) EncodeForHumans() string {
 	return address.Address.Hex()
 }
+
+// IsValidAddress returns true if the string is a hex encoded Ethereum address, with or without the
+// 0x prefix. If the address is mixed-case, it must have a valid EIP-55 checksum. All-lowercase and
+// all-uppercase addresses carry no checksum and are accepted.
+func IsValidAddress(address string) bool {
+	if !common.IsHexAddress(address) {
+		return false
+	}
+	hexAddress := address
+	if strings.HasPrefix(hexAddress, "0x") || strings.HasPrefix(hexAddress, "0X") {
+		hexAddress = hexAddress[2:]
+	}
+	if hexAddress == strings.ToLower(hexAddress) || hexAddress == strings.ToUpper(hexAddress) {
+		return true
+	}
+	return common.HexToAddress(address).Hex()[2:] == hexAddress
+}
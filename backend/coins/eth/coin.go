@@ -15,20 +15,28 @@
 package eth
 
 import (
+	"context"
+	"encoding/hex"
 	"math/big"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/errors"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/erc20"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/etherscan"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/rpcclient"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/logging"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/observable"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/socksproxy"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/sirupsen/logrus"
 )
 
@@ -37,6 +45,7 @@ import (
 type TransactionsSource interface {
 	Transactions(
 		blockTipHeight *big.Int,
+		numConfirmationsComplete int,
 		address common.Address, endBlock *big.Int, erc20Token *erc20.Token) (
 		[]accounts.Transaction, error)
 }
@@ -126,9 +135,33 @@ func (coin *Coin) Initialize() {
 		}
 
 		coin.transactionsSource = coin.makeTransactionsSource()
+		if coin.erc20Token != nil {
+			go coin.verifyERC20Token()
+		}
 	})
 }
 
+// verifyERC20Token checks the static token data against the token contract. Wrong decimals are
+// corrected by the token, a wrong symbol is only logged.
+func (coin *Coin) verifyERC20Token() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	decimals := coin.erc20Token.Decimals()
+	contractDetails, err := coin.erc20Token.Verify(ctx, coin.client)
+	if err != nil {
+		coin.log.WithError(err).Warning("Could not verify the erc20 token")
+		return
+	}
+	if contractDetails.Decimals != decimals {
+		coin.log.WithFields(logrus.Fields{"decimals": decimals, "contractDecimals": contractDetails.Decimals}).
+			Warning("erc20 token decimals differ from the contract, using the contract decimals")
+	}
+	if !strings.EqualFold(contractDetails.Symbol, coin.unit) {
+		coin.log.WithFields(logrus.Fields{"unit": coin.unit, "contractSymbol": contractDetails.Symbol}).
+			Warning("erc20 token unit differs from the contract symbol")
+	}
+}
+
 // Code implements coin.Coin.
 func (coin *Coin) Code() string {
 	return coin.code
@@ -197,6 +230,63 @@ func (coin *Coin) ERC20Token() *erc20.Token {
 	return coin.erc20Token
 }
 
+// BroadcastRawTransaction broadcasts a signed transaction given as RLP encoded hex and returns its
+// transaction hash. errors.ErrInvalidData is returned if the transaction can't be decoded. The
+// signature must be replay protected for the chain of this coin. The coin must be initialized.
+func (coin *Coin) BroadcastRawTransaction(rawTxHex string) (string, error) {
+	rawTx, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(rawTxHex), "0x"))
+	if err != nil {
+		return "", errp.WithStack(errors.ErrInvalidData)
+	}
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(rawTx, tx); err != nil {
+		return "", errp.WithStack(errors.ErrInvalidData)
+	}
+	if v, _, _ := tx.RawSignatureValues(); v.Sign() == 0 {
+		return "", errp.New("transaction is not signed")
+	}
+	txProposal := &TxProposal{Tx: tx, Signer: types.NewEIP155Signer(coin.net.ChainID)}
+	if err := txProposal.VerifyChainID(coin.net.ChainID); err != nil {
+		return "", err
+	}
+	if coin.client == nil {
+		return "", errp.New("coin not initialized")
+	}
+	if err := coin.client.SendTransaction(context.TODO(), tx); err != nil {
+		return "", errp.WithStack(err)
+	}
+	return tx.Hash().Hex(), nil
+}
+
+// AddressBalance returns the balance of an arbitrary address in wei, or in the smallest unit of the
+// token for ERC20 tokens, without persisting anything. errors.ErrInvalidAddress is returned if the
+// address is not valid, see IsValidAddress. The coin must be initialized.
+func (coin *Coin) AddressBalance(address string) (*big.Int, error) {
+	if !IsValidAddress(address) {
+		return nil, errp.WithStack(errors.ErrInvalidAddress)
+	}
+	if coin.client == nil {
+		return nil, errp.New("coin not initialized")
+	}
+	ethAddress := common.HexToAddress(address)
+	if coin.erc20Token != nil {
+		token, err := erc20.NewIERC20(coin.erc20Token.ContractAddress(), coin.client)
+		if err != nil {
+			return nil, errp.WithStack(err)
+		}
+		balance, err := token.BalanceOf(&bind.CallOpts{}, ethAddress)
+		if err != nil {
+			return nil, errp.WithStack(err)
+		}
+		return balance, nil
+	}
+	balance, err := coin.client.BalanceAt(context.TODO(), ethAddress, nil)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return balance, nil
+}
+
 // Close implements coin.Coin.
 func (coin *Coin) Close() error {
 	// TODO: shut down rpc connection.
@@ -12,10 +12,10 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-package btc
+package eth
 
-import "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/blockchain"
+import "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/rpcclient"
 
-func (coin *Coin) TstSetMakeBlockchain(f func() blockchain.Interface) {
-	coin.makeBlockchain = f
+func (coin *Coin) TstSetClient(client rpcclient.Interface) {
+	coin.client = client
 }
@@ -0,0 +1,145 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eth_test
+
+import (
+	"context"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/errors"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/rpcclient"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/socksproxy"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/stretchr/testify/require"
+)
+
+// sendTxClient is an rpc client which only sends transactions.
+type sendTxClient struct {
+	rpcclient.Interface
+	sent []*types.Transaction
+	err  error
+}
+
+func (client *sendTxClient) SendTransaction(_ context.Context, tx *types.Transaction) error {
+	if client.err != nil {
+		return client.err
+	}
+	client.sent = append(client.sent, tx)
+	return nil
+}
+
+func TestBroadcastRawTransaction(t *testing.T) {
+	coin := eth.NewCoin("teth", "TETH", "TETH", params.TestnetChainConfig, "",
+		eth.TransactionsSourceNone, "", nil, socksproxy.NewSocksProxy(false, ""))
+	client := &sendTxClient{}
+	coin.TstSetClient(client)
+
+	privateKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	tx := types.NewTransaction(0, common.HexToAddress("0x0000000000000000000000000000000000000001"),
+		big.NewInt(1), 21000, big.NewInt(1), nil)
+	rawTxHex := func(signer types.Signer) string {
+		signedTx, err := types.SignTx(tx, signer, privateKey)
+		require.NoError(t, err)
+		rawTx, err := rlp.EncodeToBytes(signedTx)
+		require.NoError(t, err)
+		return "0x" + hex.EncodeToString(rawTx)
+	}
+
+	signedTxHex := rawTxHex(types.NewEIP155Signer(params.TestnetChainConfig.ChainID))
+	txID, err := coin.BroadcastRawTransaction(signedTxHex)
+	require.NoError(t, err)
+	require.Len(t, client.sent, 1)
+	require.Equal(t, client.sent[0].Hash().Hex(), txID)
+
+	// Malformed.
+	for _, malformed := range []string{"", "0xzz", "0x0102", signedTxHex[:len(signedTxHex)-2]} {
+		_, err := coin.BroadcastRawTransaction(malformed)
+		require.Equal(t, errors.ErrInvalidData, errp.Cause(err))
+	}
+
+	// Not signed, signed for another chain, or not replay protected.
+	unsignedTx, err := rlp.EncodeToBytes(tx)
+	require.NoError(t, err)
+	for _, rejected := range []string{
+		hex.EncodeToString(unsignedTx),
+		rawTxHex(types.NewEIP155Signer(params.MainnetChainConfig.ChainID)),
+		rawTxHex(types.HomesteadSigner{}),
+	} {
+		_, err := coin.BroadcastRawTransaction(rejected)
+		require.Error(t, err)
+	}
+	require.Len(t, client.sent, 1)
+
+	// Rejected by the node.
+	client.err = errp.New("nonce too low")
+	_, err = coin.BroadcastRawTransaction(signedTxHex)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "nonce too low")
+}
+
+// balanceClient is an rpc client which only returns the balances of addresses.
+type balanceClient struct {
+	rpcclient.Interface
+	balances map[common.Address]*big.Int
+}
+
+func (client *balanceClient) BalanceAt(
+	_ context.Context, account common.Address, _ *big.Int) (*big.Int, error) {
+	balance, ok := client.balances[account]
+	if !ok {
+		return big.NewInt(0), nil
+	}
+	return balance, nil
+}
+
+func TestAddressBalance(t *testing.T) {
+	coin := eth.NewCoin("teth", "TETH", "TETH", params.TestnetChainConfig, "",
+		eth.TransactionsSourceNone, "", nil, socksproxy.NewSocksProxy(false, ""))
+	_, err := coin.AddressBalance("0x0000000000000000000000000000000000000001")
+	require.EqualError(t, err, "coin not initialized")
+
+	address := common.HexToAddress("0xAa0c45d2877373ad1AB2aa5Eab15563301e9b4eD")
+	coin.TstSetClient(&balanceClient{balances: map[common.Address]*big.Int{
+		address: big.NewInt(123456789),
+	}})
+
+	balance, err := coin.AddressBalance(address.Hex())
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(123456789), balance)
+	// Without checksum.
+	balance, err = coin.AddressBalance(strings.ToLower(address.Hex()))
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(123456789), balance)
+
+	balance, err = coin.AddressBalance("0x0000000000000000000000000000000000000001")
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(0), balance)
+
+	// Invalid, or mixed-case with a wrong checksum.
+	for _, invalid := range []string{"", "0x1234", "0xaA0c45d2877373ad1AB2aa5Eab15563301e9b4eD"} {
+		_, err := coin.AddressBalance(invalid)
+		require.Equal(t, errors.ErrInvalidAddress, errp.Cause(err), invalid)
+	}
+}
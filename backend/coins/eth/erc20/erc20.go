@@ -14,12 +14,61 @@
 
 package erc20
 
-import "github.com/ethereum/go-ethereum/common"
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// detailsABI is the ABI of the optional ERC20 functions `decimals()` and `symbol()`, which are
+// not part of IERC20.
+const detailsABI = `[
+{"constant":true,"inputs":[],"name":"decimals","outputs":[{"name":"","type":"uint8"}],"payable":false,"stateMutability":"view","type":"function"},
+{"constant":true,"inputs":[],"name":"symbol","outputs":[{"name":"","type":"string"}],"payable":false,"stateMutability":"view","type":"function"}
+]`
+
+// ContractDetails holds the token details stored in the token contract.
+type ContractDetails struct {
+	Decimals uint
+	Symbol   string
+}
+
+// FetchContractDetails reads the decimals and the symbol from the token contract.
+func FetchContractDetails(
+	ctx context.Context,
+	caller bind.ContractCaller,
+	contractAddress common.Address,
+) (*ContractDetails, error) {
+	parsedABI, err := abi.JSON(strings.NewReader(detailsABI))
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	contract := bind.NewBoundContract(contractAddress, parsedABI, caller, nil, nil)
+	opts := &bind.CallOpts{Context: ctx}
+	var decimals uint8
+	if err := contract.Call(opts, &decimals, "decimals"); err != nil {
+		return nil, errp.WithMessage(err, "could not read the token decimals")
+	}
+	var symbol string
+	if err := contract.Call(opts, &symbol, "symbol"); err != nil {
+		return nil, errp.WithMessage(err, "could not read the token symbol")
+	}
+	return &ContractDetails{Decimals: uint(decimals), Symbol: symbol}, nil
+}
 
 // Token holds infos about the erc20 token needed to fetch balances, format amounts, etc.
 type Token struct {
 	contractAddress common.Address
 	decimals        uint
+
+	// contractDetails caches the result of Verify().
+	contractDetails *ContractDetails
+	lock            sync.RWMutex
 }
 
 // NewToken creates a new Token instance.
@@ -42,5 +91,28 @@ func (token *Token) ContractAddress() common.Address {
 // Decimals returns the number of decimals needed to convert between the smallest unit and the
 // standard unit.
 func (token *Token) Decimals() uint {
+	token.lock.RLock()
+	defer token.lock.RUnlock()
 	return token.decimals
 }
+
+// Verify reads the token details from the contract. If the decimals stored in the contract differ
+// from the static ones, the ones from the contract are used from now on. The details are cached
+// after the first successful call.
+func (token *Token) Verify(ctx context.Context, caller bind.ContractCaller) (*ContractDetails, error) {
+	token.lock.RLock()
+	contractDetails := token.contractDetails
+	token.lock.RUnlock()
+	if contractDetails != nil {
+		return contractDetails, nil
+	}
+	contractDetails, err := FetchContractDetails(ctx, caller, token.contractAddress)
+	if err != nil {
+		return nil, err
+	}
+	token.lock.Lock()
+	defer token.lock.Unlock()
+	token.contractDetails = contractDetails
+	token.decimals = contractDetails.Decimals
+	return contractDetails, nil
+}
@@ -0,0 +1,94 @@
+// Copyright 2018 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package erc20_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/erc20"
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+// contractCallerMock answers `decimals()` and `symbol()` calls of a token contract.
+type contractCallerMock struct {
+	t        *testing.T
+	decimals uint8
+	symbol   string
+	calls    int
+}
+
+func (caller *contractCallerMock) CodeAt(context.Context, common.Address, *big.Int) ([]byte, error) {
+	return []byte{1}, nil
+}
+
+func (caller *contractCallerMock) CallContract(
+	_ context.Context, call ethereum.CallMsg, _ *big.Int) ([]byte, error) {
+	caller.calls++
+	newType := func(typ string) abi.Type {
+		result, err := abi.NewType(typ, nil)
+		require.NoError(caller.t, err)
+		return result
+	}
+	switch string(call.Data) {
+	case string(crypto.Keccak256([]byte("decimals()"))[:4]):
+		return abi.Arguments{{Type: newType("uint8")}}.Pack(caller.decimals)
+	case string(crypto.Keccak256([]byte("symbol()"))[:4]):
+		return abi.Arguments{{Type: newType("string")}}.Pack(caller.symbol)
+	default:
+		caller.t.Fatal("unexpected call")
+		return nil, nil
+	}
+}
+
+const testContractAddress = "0xdac17f958d2ee523a2206206994597c13d831ec7"
+
+func TestFetchContractDetails(t *testing.T) {
+	caller := &contractCallerMock{t: t, decimals: 6, symbol: "USDT"}
+	details, err := erc20.FetchContractDetails(
+		context.Background(), caller, common.HexToAddress(testContractAddress))
+	require.NoError(t, err)
+	require.Equal(t, &erc20.ContractDetails{Decimals: 6, Symbol: "USDT"}, details)
+}
+
+func TestTokenVerify(t *testing.T) {
+	// Matching the static data.
+	token := erc20.NewToken(testContractAddress, 6)
+	caller := &contractCallerMock{t: t, decimals: 6, symbol: "USDT"}
+	details, err := token.Verify(context.Background(), caller)
+	require.NoError(t, err)
+	require.Equal(t, uint(6), details.Decimals)
+	require.Equal(t, uint(6), token.Decimals())
+
+	// Not matching the static data: the decimals of the contract are used.
+	token = erc20.NewToken(testContractAddress, 18)
+	caller = &contractCallerMock{t: t, decimals: 6, symbol: "USDT"}
+	details, err = token.Verify(context.Background(), caller)
+	require.NoError(t, err)
+	require.Equal(t, uint(6), details.Decimals)
+	require.Equal(t, uint(6), token.Decimals())
+
+	// The result is cached.
+	calls := caller.calls
+	cachedDetails, err := token.Verify(context.Background(), caller)
+	require.NoError(t, err)
+	require.Equal(t, details, cachedDetails)
+	require.Equal(t, calls, caller.calls)
+}
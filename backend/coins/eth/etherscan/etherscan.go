@@ -147,6 +147,8 @@ type Transaction struct {
 	jsonTransaction jsonTransaction
 	txType          accounts.TxType
 	blockTipHeight  *big.Int
+	// numConfirmationsComplete is the number of confirmations after which the tx is complete.
+	numConfirmationsComplete int
 	// isInternal: true if tx was fetched via `txlistinternal`, false if via `txlist`.
 	isInternal bool
 }
@@ -222,7 +224,7 @@ func (tx *Transaction) NumConfirmations() int {
 
 // NumConfirmationsComplete implements accounts.Transaction.
 func (tx *Transaction) NumConfirmationsComplete() int {
-	return ethtypes.NumConfirmationsComplete
+	return tx.numConfirmationsComplete
 }
 
 // Type implements accounts.Transaction.
@@ -273,6 +275,7 @@ func (tx *Transaction) Gas() uint64 {
 // transaction type (send, receive, send to self) based on the account address.
 func prepareTransactions(
 	blockTipHeight *big.Int,
+	numConfirmationsComplete int,
 	isInternal bool,
 	transactions []*Transaction, address common.Address) ([]accounts.Transaction, error) {
 	seen := map[string]struct{}{}
@@ -306,6 +309,7 @@ func prepareTransactions(
 			transaction.txType = accounts.TxTypeReceive
 		}
 		transaction.blockTipHeight = blockTipHeight
+		transaction.numConfirmationsComplete = numConfirmationsComplete
 		transaction.isInternal = isInternal
 		castTransactions = append(castTransactions, transaction)
 	}
@@ -316,6 +320,7 @@ func prepareTransactions(
 // Provide erc20Token to filter for those. If nil, standard etheruem transactions will be fetched.
 func (etherScan *EtherScan) Transactions(
 	blockTipHeight *big.Int,
+	numConfirmationsComplete int,
 	address common.Address, endBlock *big.Int, erc20Token *erc20.Token) (
 	[]accounts.Transaction, error) {
 	params := url.Values{}
@@ -339,7 +344,8 @@ func (etherScan *EtherScan) Transactions(
 	if err := etherScan.call(params, &result); err != nil {
 		return nil, err
 	}
-	transactionsNormal, err := prepareTransactions(blockTipHeight, false, result.Result, address)
+	transactionsNormal, err := prepareTransactions(
+		blockTipHeight, numConfirmationsComplete, false, result.Result, address)
 	if err != nil {
 		return nil, err
 	}
@@ -355,7 +361,7 @@ func (etherScan *EtherScan) Transactions(
 		}
 		var err error
 		transactionsInternal, err = prepareTransactions(
-			blockTipHeight, true, resultInternal.Result, address)
+			blockTipHeight, numConfirmationsComplete, true, resultInternal.Result, address)
 		if err != nil {
 			return nil, err
 		}
@@ -373,12 +379,26 @@ func (etherScan *EtherScan) rpcCall(params url.Values, result interface{}) error
 		ID      int              `json:"id"`
 		Error   *json.RawMessage `json:"error"`
 		Result  *json.RawMessage `json:"result"`
+		// Status and Message are set instead of Error if etherscan itself rejects the call, e.g.
+		// `{"status":"0","message":"NOTOK","result":"Invalid API Key"}`.
+		Status  string `json:"status"`
+		Message string `json:"message"`
 	}
 	if err := etherScan.call(params, &wrapped); err != nil {
-		return nil
+		return err
 	}
 	if wrapped.Error != nil {
-		return errp.WithMessage(errp.New("unexpected error"), string(*wrapped.Error))
+		return rpcError(*wrapped.Error)
+	}
+	if wrapped.Status == "0" {
+		message := wrapped.Message
+		if wrapped.Result != nil {
+			var resultMessage string
+			if err := json.Unmarshal(*wrapped.Result, &resultMessage); err == nil && resultMessage != "" {
+				message = resultMessage
+			}
+		}
+		return errp.New(message)
 	}
 	if result == nil {
 		return nil
@@ -389,6 +409,18 @@ func (etherScan *EtherScan) rpcCall(params url.Values, result interface{}) error
 	return json.Unmarshal(*wrapped.Result, result)
 }
 
+// rpcError returns the message of a JSON-RPC error object, e.g.
+// `{"code":-32000,"message":"nonce too low"}`, falling back to the raw error if it has no message.
+func rpcError(rawError json.RawMessage) error {
+	var rpcErr struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(rawError, &rpcErr); err == nil && rpcErr.Message != "" {
+		return errp.New(rpcErr.Message)
+	}
+	return errp.WithMessage(errp.New("unexpected error"), string(rawError))
+}
+
 // TransactionReceiptWithBlockNumber implements rpc.Interface
 func (etherScan *EtherScan) TransactionReceiptWithBlockNumber(
 	ctx context.Context, hash common.Hash) (*rpcclient.RPCTransactionReceipt, error) {
@@ -526,15 +558,30 @@ func (etherScan *EtherScan) PendingNonceAt(ctx context.Context, account common.A
 
 // SendTransaction implements rpc.Interface
 func (etherScan *EtherScan) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	_, err := etherScan.SendTransactionWithTxID(ctx, tx)
+	return err
+}
+
+// SendTransactionWithTxID is like SendTransaction, but also returns the hash of the broadcasted
+// transaction as reported by the node.
+func (etherScan *EtherScan) SendTransactionWithTxID(
+	ctx context.Context, tx *types.Transaction) (common.Hash, error) {
 	encodedTx, err := rlp.EncodeToBytes(tx)
 	if err != nil {
-		return errp.WithStack(err)
+		return common.Hash{}, errp.WithStack(err)
 	}
 
 	params := url.Values{}
 	params.Set("action", "eth_sendRawTransaction")
 	params.Set("hex", hexutil.Encode(encodedTx))
-	return etherScan.rpcCall(params, nil)
+	var txID common.Hash
+	if err := etherScan.rpcCall(params, &txID); err != nil {
+		return common.Hash{}, err
+	}
+	if txID != tx.Hash() {
+		return common.Hash{}, errp.Newf("unexpected tx hash %s, expected %s", txID.Hex(), tx.Hash().Hex())
+	}
+	return txID, nil
 }
 
 // SubscribeFilterLogs implements rpc.Interface
@@ -0,0 +1,80 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etherscan_test
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/etherscan"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/socksproxy"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendTransaction(t *testing.T) {
+	tx := types.NewTransaction(0, common.HexToAddress("0x0000000000000000000000000000000000000001"),
+		big.NewInt(1), 21000, big.NewInt(1), nil)
+	encodedTx, err := rlp.EncodeToBytes(tx)
+	require.NoError(t, err)
+
+	var response string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "proxy", r.URL.Query().Get("module"))
+		require.Equal(t, "eth_sendRawTransaction", r.URL.Query().Get("action"))
+		require.Equal(t, hexutil.Encode(encodedTx), r.URL.Query().Get("hex"))
+		_, _ = w.Write([]byte(response))
+	}))
+	defer server.Close()
+	etherScan := etherscan.NewEtherScan(server.URL, socksproxy.NewSocksProxy(false, ""))
+
+	response = `{"jsonrpc":"2.0","id":1,"result":"` + tx.Hash().Hex() + `"}`
+	txID, err := etherScan.SendTransactionWithTxID(context.Background(), tx)
+	require.NoError(t, err)
+	require.Equal(t, tx.Hash(), txID)
+	require.NoError(t, etherScan.SendTransaction(context.Background(), tx))
+
+	// The node reports a different tx.
+	response = `{"jsonrpc":"2.0","id":1,"result":"0x` + common.Bytes2Hex(make([]byte, 32)) + `"}`
+	_, err = etherScan.SendTransactionWithTxID(context.Background(), tx)
+	require.Error(t, err)
+
+	// JSON-RPC error.
+	response = `{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"nonce too low"}}`
+	_, err = etherScan.SendTransactionWithTxID(context.Background(), tx)
+	require.EqualError(t, err, "nonce too low")
+	require.EqualError(t, etherScan.SendTransaction(context.Background(), tx), "nonce too low")
+
+	// Rejected by etherscan itself.
+	response = `{"status":"0","message":"NOTOK","result":"Invalid API Key"}`
+	_, err = etherScan.SendTransactionWithTxID(context.Background(), tx)
+	require.EqualError(t, err, "Invalid API Key")
+}
+
+func TestRPCCallHTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+	etherScan := etherscan.NewEtherScan(server.URL, socksproxy.NewSocksProxy(false, ""))
+	_, err := etherScan.SuggestGasPrice(context.Background())
+	require.Error(t, err)
+}
@@ -0,0 +1,123 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eth
+
+import (
+	"context"
+	"math"
+	"math/big"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/errors"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/rpcclient"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// GasPriceEstimate is the gas price of a fee target.
+type GasPriceEstimate struct {
+	Code accounts.FeeTargetCode
+	// GasPrice is in wei.
+	GasPrice *big.Int
+}
+
+// gasPriceTiers are the gas prices of the fee targets in percent of the suggested gas price, sorted
+// by ascending priority. The node only suggests a single gas price.
+var gasPriceTiers = []struct {
+	code    accounts.FeeTargetCode
+	percent int64
+}{
+	{code: accounts.FeeTargetCodeEconomy, percent: 80},
+	{code: accounts.FeeTargetCodeNormal, percent: 100},
+	{code: accounts.FeeTargetCodeHigh, percent: 150},
+}
+
+// EstimateGasPrices returns the gas prices of the fee targets, sorted by ascending priority,
+// derived from the gas price suggested by the node.
+func EstimateGasPrices(ctx context.Context, client rpcclient.Interface) ([]*GasPriceEstimate, error) {
+	suggestedGasPrice, err := client.SuggestGasPrice(ctx)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	result := make([]*GasPriceEstimate, len(gasPriceTiers))
+	for index, tier := range gasPriceTiers {
+		gasPrice := new(big.Int).Mul(suggestedGasPrice, big.NewInt(tier.percent))
+		result[index] = &GasPriceEstimate{
+			Code:     tier.code,
+			GasPrice: gasPrice.Div(gasPrice, big.NewInt(100)),
+		}
+	}
+	return result, nil
+}
+
+// Fee returns the maximum fee in wei of a tx with the given gas limit and gas price.
+func Fee(gasLimit uint64, gasPrice *big.Int) *big.Int {
+	return new(big.Int).Mul(new(big.Int).SetUint64(gasLimit), gasPrice)
+}
+
+// ContractGasLimit returns the gas limit of a contract interaction: the estimated gas increased by
+// the given multiplier, so the tx does not run out of gas if the contract state changes before it
+// is mined. The result is capped at the block gas limit, unless that is unknown (0).
+func ContractGasLimit(estimatedGas uint64, multiplier float64, blockGasLimit uint64) uint64 {
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	gasLimit := uint64(math.Ceil(float64(estimatedGas) * multiplier))
+	if blockGasLimit != 0 && gasLimit > blockGasLimit {
+		return blockGasLimit
+	}
+	return gasLimit
+}
+
+// SendAllValue returns the value of an ether tx which spends the whole balance, i.e. the balance
+// minus the fee. errors.ErrInsufficientFunds is returned if the balance does not cover the fee.
+func SendAllValue(balance *big.Int, gasLimit uint64, gasPrice *big.Int) (*big.Int, error) {
+	value := new(big.Int).Sub(balance, Fee(gasLimit, gasPrice))
+	if value.Sign() < 0 {
+		return nil, errp.WithStack(errors.ErrInsufficientFunds)
+	}
+	return value, nil
+}
+
+// DefaultReplacementGasPriceBumpPercent is the minimum increase of the gas price in percent which
+// nodes require to accept a tx replacing a pending tx with the same nonce. Geth rejects smaller
+// increases with "replacement transaction underpriced".
+const DefaultReplacementGasPriceBumpPercent = 10
+
+// MinReplacementGasPrice returns the lowest gas price a tx replacing a pending tx with the given
+// gas price must pay, i.e. the original gas price increased by bumpPercent, rounded up.
+func MinReplacementGasPrice(originalGasPrice *big.Int, bumpPercent int64) *big.Int {
+	gasPrice := new(big.Int).Mul(originalGasPrice, big.NewInt(100+bumpPercent))
+	gasPrice.Add(gasPrice, big.NewInt(99))
+	return gasPrice.Div(gasPrice, big.NewInt(100))
+}
+
+// ValidateReplacementGasPrice returns errors.ErrReplacementUnderpriced if gasPrice is below
+// MinReplacementGasPrice() of the original gas price. Fee bumping must check this before signing a
+// replacement tx, as it would be rejected by the nodes anyway.
+func ValidateReplacementGasPrice(originalGasPrice, gasPrice *big.Int, bumpPercent int64) error {
+	if gasPrice.Cmp(MinReplacementGasPrice(originalGasPrice, bumpPercent)) < 0 {
+		return errp.WithStack(errors.ErrReplacementUnderpriced)
+	}
+	return nil
+}
+
+// EstimateGasPrices returns the gas prices of the fee targets, see EstimateGasPrices(). The coin
+// must be initialized.
+func (coin *Coin) EstimateGasPrices() ([]*GasPriceEstimate, error) {
+	if coin.client == nil {
+		return nil, errp.New("coin not initialized")
+	}
+	return EstimateGasPrices(context.TODO(), coin.client)
+}
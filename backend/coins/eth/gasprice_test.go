@@ -0,0 +1,104 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eth_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/errors"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/rpcclient"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/stretchr/testify/require"
+)
+
+// gasPriceClient is an rpc client which only suggests gas prices.
+type gasPriceClient struct {
+	rpcclient.Interface
+	gasPrice *big.Int
+	err      error
+}
+
+func (client *gasPriceClient) SuggestGasPrice(context.Context) (*big.Int, error) {
+	return client.gasPrice, client.err
+}
+
+func TestEstimateGasPrices(t *testing.T) {
+	estimates, err := eth.EstimateGasPrices(context.Background(),
+		&gasPriceClient{gasPrice: big.NewInt(20000000000)})
+	require.NoError(t, err)
+	require.Equal(t, []*eth.GasPriceEstimate{
+		{Code: accounts.FeeTargetCodeEconomy, GasPrice: big.NewInt(16000000000)},
+		{Code: accounts.FeeTargetCodeNormal, GasPrice: big.NewInt(20000000000)},
+		{Code: accounts.FeeTargetCodeHigh, GasPrice: big.NewInt(30000000000)},
+	}, estimates)
+
+	_, err = eth.EstimateGasPrices(context.Background(),
+		&gasPriceClient{err: errp.New("node unreachable")})
+	require.Error(t, err)
+}
+
+func TestSendAllValue(t *testing.T) {
+	gasPrice := big.NewInt(20000000000)
+	fee := eth.Fee(21000, gasPrice)
+	require.Equal(t, big.NewInt(420000000000000), fee)
+
+	balance := big.NewInt(1000000000000000000)
+	value, err := eth.SendAllValue(balance, 21000, gasPrice)
+	require.NoError(t, err)
+	// Nothing is left after paying the value and the fee.
+	require.Equal(t, balance, new(big.Int).Add(value, fee))
+
+	value, err = eth.SendAllValue(fee, 21000, gasPrice)
+	require.NoError(t, err)
+	require.Equal(t, 0, value.Sign())
+
+	_, err = eth.SendAllValue(new(big.Int).Sub(fee, big.NewInt(1)), 21000, gasPrice)
+	require.Equal(t, errors.ErrInsufficientFunds, errp.Cause(err))
+}
+
+func TestContractGasLimit(t *testing.T) {
+	require.Equal(t, uint64(60000), eth.ContractGasLimit(50000, 1.2, 0))
+	// Rounded up.
+	require.Equal(t, uint64(36980), eth.ContractGasLimit(30816, 1.2, 0))
+	// Capped at the block gas limit.
+	require.Equal(t, uint64(55000), eth.ContractGasLimit(50000, 1.2, 55000))
+	require.Equal(t, uint64(60000), eth.ContractGasLimit(50000, 1.2, 10000000))
+	// Never below the estimate.
+	require.Equal(t, uint64(50000), eth.ContractGasLimit(50000, 0.5, 0))
+}
+
+func TestReplacementGasPrice(t *testing.T) {
+	bumpPercent := int64(eth.DefaultReplacementGasPriceBumpPercent)
+	require.Equal(t, big.NewInt(110), eth.MinReplacementGasPrice(big.NewInt(100), bumpPercent))
+	// Rounded up, so that the replacement is not rejected due to rounding.
+	require.Equal(t, big.NewInt(112), eth.MinReplacementGasPrice(big.NewInt(101), bumpPercent))
+	require.Equal(t, big.NewInt(125), eth.MinReplacementGasPrice(big.NewInt(100), 25))
+
+	original := big.NewInt(20000000000)
+	// Below the threshold.
+	for _, gasPrice := range []*big.Int{big.NewInt(20000000000), big.NewInt(21999999999)} {
+		require.Equal(t,
+			errors.ErrReplacementUnderpriced,
+			errp.Cause(eth.ValidateReplacementGasPrice(original, gasPrice, bumpPercent)))
+	}
+	// Adequate bumps.
+	for _, gasPrice := range []*big.Int{big.NewInt(22000000000), big.NewInt(40000000000)} {
+		require.NoError(t, eth.ValidateReplacementGasPrice(original, gasPrice, bumpPercent))
+	}
+}
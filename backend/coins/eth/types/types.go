@@ -29,9 +29,6 @@ import (
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
-// NumConfirmationsComplete indicates after how many confs the tx is considered complete.
-const NumConfirmationsComplete = 12
-
 // EthereumTransaction holds information specific to Ethereum.
 type EthereumTransaction interface {
 	// Gas returns the gas limit for pending tx, and the gas used for confirmed tx.
@@ -138,6 +135,7 @@ func (txh *TransactionWithMetadata) Gas() uint64 {
 func NewTransactionWithConfirmations(
 	tx *TransactionWithMetadata,
 	tipHeight uint64,
+	numConfirmationsComplete int,
 	erc20Token *erc20.Token) *TransactionWithConfirmations {
 	data := tx.Transaction.Data()
 	if erc20Token == nil && len(data) > 0 {
@@ -152,9 +150,10 @@ func NewTransactionWithConfirmations(
 		}
 	}
 	return &TransactionWithConfirmations{
-		TransactionWithMetadata: *tx,
-		tipHeight:               tipHeight,
-		erc20Token:              erc20Token,
+		TransactionWithMetadata:  *tx,
+		tipHeight:                tipHeight,
+		numConfirmationsComplete: numConfirmationsComplete,
+		erc20Token:               erc20Token,
 	}
 }
 
@@ -162,8 +161,9 @@ func NewTransactionWithConfirmations(
 // computed.
 type TransactionWithConfirmations struct {
 	TransactionWithMetadata
-	tipHeight  uint64
-	erc20Token *erc20.Token
+	tipHeight                uint64
+	numConfirmationsComplete int
+	erc20Token               *erc20.Token
 }
 
 // NumConfirmations implements accounts.Transaction.
@@ -177,7 +177,7 @@ func (txh *TransactionWithConfirmations) NumConfirmations() int {
 
 // NumConfirmationsComplete implements accounts.Transaction.
 func (txh *TransactionWithConfirmations) NumConfirmationsComplete() int {
-	return NumConfirmationsComplete
+	return txh.numConfirmationsComplete
 }
 
 // Status implements accounts.Transaction.
@@ -5,6 +5,8 @@ import (
 	"math/big"
 	"testing"
 
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+
 	ethtypes "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/types"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/jsonp"
 	"github.com/ethereum/go-ethereum/common"
@@ -33,3 +35,22 @@ func TestTransactionWithHeightJSON(t *testing.T) {
 	require.Equal(t, tx.Success, tx2.Success)
 	require.Equal(t, tx.Transaction.Hash(), tx2.Transaction.Hash())
 }
+
+func TestTransactionWithConfirmationsStatus(t *testing.T) {
+	tx := &ethtypes.TransactionWithMetadata{
+		Transaction: types.NewTransaction(
+			0, common.Address{}, big.NewInt(1), 21000, big.NewInt(1), nil),
+		Height:  100,
+		GasUsed: 21000,
+		Success: true,
+	}
+	// 12 confirmations.
+	require.Equal(t, accounts.TxStatusComplete,
+		ethtypes.NewTransactionWithConfirmations(tx, 111, 12, nil).Status())
+	// A higher threshold keeps the tx pending longer.
+	txWithConfirmations := ethtypes.NewTransactionWithConfirmations(tx, 111, 20, nil)
+	require.Equal(t, 20, txWithConfirmations.NumConfirmationsComplete())
+	require.Equal(t, accounts.TxStatusPending, txWithConfirmations.Status())
+	require.Equal(t, accounts.TxStatusComplete,
+		ethtypes.NewTransactionWithConfirmations(tx, 119, 20, nil).Status())
+}
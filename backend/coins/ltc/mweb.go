@@ -0,0 +1,96 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ltc
+
+import (
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil/bech32"
+)
+
+// mwebAddressHRPs are the bech32 human-readable parts of MWEB (MimbleWimble Extension Block)
+// addresses per network.
+var mwebAddressHRPs = map[wire.BitcoinNet]string{
+	MainNet:  "ltcmweb",
+	TestNet4: "tmweb",
+}
+
+const (
+	bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+	// mwebAddressPayloadSize is the size of the payload of an MWEB address: the compressed scan and
+	// spend public keys.
+	mwebAddressPayloadSize = 2 * 33
+)
+
+// IsMWEBAddress returns true if the address is a well-formed MWEB address of the given network.
+// MWEB addresses are bech32 encoded, but longer than the 90 characters allowed by BIP173, so
+// they can't be decoded by btcutil.DecodeAddress().
+func IsMWEBAddress(address string, net *chaincfg.Params) bool {
+	hrp, ok := mwebAddressHRPs[net.Net]
+	if !ok {
+		return false
+	}
+	if address != strings.ToLower(address) && address != strings.ToUpper(address) {
+		return false
+	}
+	address = strings.ToLower(address)
+	if !strings.HasPrefix(address, hrp+"1") {
+		return false
+	}
+	data := make([]byte, 0, len(address)-len(hrp)-1)
+	for _, char := range address[len(hrp)+1:] {
+		value := strings.IndexRune(bech32Charset, char)
+		if value < 0 {
+			return false
+		}
+		data = append(data, byte(value))
+	}
+	// At least the version and the 6 characters of the checksum.
+	if len(data) < 7 || !bech32VerifyChecksum(hrp, data) {
+		return false
+	}
+	version, program := data[0], data[1:len(data)-6]
+	if version != 0 {
+		return false
+	}
+	payload, err := bech32.ConvertBits(program, 5, 8, false)
+	return err == nil && len(payload) == mwebAddressPayloadSize
+}
+
+func bech32VerifyChecksum(hrp string, data []byte) bool {
+	values := make([]byte, 0, 2*len(hrp)+1+len(data))
+	for i := 0; i < len(hrp); i++ {
+		values = append(values, hrp[i]>>5)
+	}
+	values = append(values, 0)
+	for i := 0; i < len(hrp); i++ {
+		values = append(values, hrp[i]&31)
+	}
+	values = append(values, data...)
+	generator := []uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	checksum := uint32(1)
+	for _, value := range values {
+		top := checksum >> 25
+		checksum = (checksum&0x1ffffff)<<5 ^ uint32(value)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				checksum ^= generator[i]
+			}
+		}
+	}
+	return checksum == 1
+}
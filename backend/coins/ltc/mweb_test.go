@@ -0,0 +1,56 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ltc_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/ltc"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	mainnetMWEBAddress = "ltcmweb1qqgrsu9guyv4rzwplgex4gkmzd9c8wl593jfe4gdg47mtm3xt6tv7qqcdrgnngs2wtd58tq50nj5mds7smh40wpq3rc4ns32jtak8np5n5qyxnzv5"
+	testnetMWEBAddress = "tmweb1qqgrsu9guyv4rzwplgex4gkmzd9c8wl593jfe4gdg47mtm3xt6tv7qqcdrgnngs2wtd58tq50nj5mds7smh40wpq3rc4ns32jtak8np5n5q90j4sr"
+)
+
+func TestIsMWEBAddress(t *testing.T) {
+	require.True(t, ltc.IsMWEBAddress(mainnetMWEBAddress, &ltc.MainNetParams))
+	require.True(t, ltc.IsMWEBAddress(strings.ToUpper(mainnetMWEBAddress), &ltc.MainNetParams))
+	require.True(t, ltc.IsMWEBAddress(testnetMWEBAddress, &ltc.TestNet4Params))
+
+	invalidAddresses := []string{
+		"",
+		"ltcmweb1",
+		// Wrong checksum.
+		mainnetMWEBAddress[:len(mainnetMWEBAddress)-1] + "q",
+		// Mixed case.
+		"LTCMWEB1" + mainnetMWEBAddress[8:],
+		// Truncated.
+		"ltcmweb1qqgrsu9guyv4rzwplgex4gkmzd9c8wl593jfe4gdg47mtm3xt6tv7qqcdrgnngs2wtd58tq50nj5mds7smh40wpq3rc4ns32jtak8np5n5q",
+		// Regular segwit address.
+		"ltc1qzr0n0a4xs0404fy5l7pl7pj8yj8q34ml27rlcs",
+	}
+	for _, address := range invalidAddresses {
+		require.False(t, ltc.IsMWEBAddress(address, &ltc.MainNetParams), address)
+	}
+
+	// Wrong network.
+	require.False(t, ltc.IsMWEBAddress(mainnetMWEBAddress, &ltc.TestNet4Params))
+	require.False(t, ltc.IsMWEBAddress(testnetMWEBAddress, &ltc.MainNetParams))
+	require.False(t, ltc.IsMWEBAddress(mainnetMWEBAddress, &chaincfg.MainNetParams))
+}
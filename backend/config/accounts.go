@@ -22,16 +22,26 @@ type Account struct {
 	Name          string                 `json:"name"`
 	Code          string                 `json:"code"`
 	Configuration *signing.Configuration `json:"configuration"`
+	// Metadata holds optional key/value pairs attached to the account when it was created, e.g. by
+	// an integration adding the account. The app does not interpret them.
+	Metadata map[string]string `json:"metadata,omitempty"`
 }
 
+// accountsConfigVersion is the current version of the accounts config schema. It must be increased
+// whenever the schema changes in a way older versions of the app can't handle. Version 0 are
+// configs written before the version was introduced.
+const accountsConfigVersion = 1
+
 // AccountsConfig persists the list of accounts added to the app.
 type AccountsConfig struct {
+	Version  int       `json:"version"`
 	Accounts []Account `json:"accounts"`
 }
 
 // newDefaultAccountsonfig returns the default accounts config.
 func newDefaultAccountsonfig() AccountsConfig {
 	return AccountsConfig{
+		Version:  accountsConfigVersion,
 		Accounts: []Account{},
 	}
 }
@@ -19,12 +19,15 @@ import (
 	"fmt"
 	"io/ioutil"
 	"strings"
+	"time"
 
 	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/locker"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/logging"
 )
 
-const defaultProxyAddress = "127.0.0.1:9050"
+// DefaultProxyAddress is the address of the local Tor SOCKS5 proxy, used if none is configured.
+const DefaultProxyAddress = "127.0.0.1:9050"
 
 // ServerInfo holds information about the backend server(s).
 type ServerInfo struct {
@@ -33,6 +36,31 @@ type ServerInfo struct {
 	PEMCert string `json:"pemCert"`
 }
 
+// DefaultMinSpendConfirmations is the default number of confirmations an incoming output needs
+// before it can be spent. Unconfirmed outputs created by the account itself, e.g. change, can
+// always be spent.
+const DefaultMinSpendConfirmations = 1
+
+const (
+	// DefaultEthPollInterval is the default interval in which Ethereum accounts are refreshed.
+	DefaultEthPollInterval = 30 * time.Second
+	// MinEthPollInterval is the smallest allowed refresh interval of Ethereum accounts, so the
+	// backend is not flooded with requests.
+	MinEthPollInterval = 10 * time.Second
+)
+
+// DefaultEthConfirmationsComplete is the default number of confirmations after which an Ethereum
+// transaction is considered complete.
+const DefaultEthConfirmationsComplete = 12
+
+// DefaultBtcConfirmationsComplete is the default number of confirmations after which a Bitcoin or
+// Litecoin transaction is considered complete.
+const DefaultBtcConfirmationsComplete = 6
+
+// DefaultEthGasLimitMultiplier is the default factor by which the estimated gas of Ethereum
+// contract interactions is increased, see Backend.EthGasLimitMultiplier.
+const DefaultEthGasLimitMultiplier = 1.2
+
 // btcCoinConfig holds configurations specific to a btc-based coin.
 type btcCoinConfig struct {
 	ElectrumServers []*ServerInfo `json:"electrumServers"`
@@ -80,7 +108,7 @@ func (proxy proxyConfig) ProxyAddressOrDefault() string {
 	if proxy.ProxyAddress != "" {
 		return proxy.ProxyAddress
 	}
-	return defaultProxyAddress
+	return DefaultProxyAddress
 }
 
 type servicesConfig struct {
@@ -99,6 +127,52 @@ type Backend struct {
 	LitecoinP2WPKHActive     bool `json:"litecoinP2WPKHActive"`
 	EthereumActive           bool `json:"ethereumActive"`
 
+	// MinSpendConfirmations is the number of confirmations an incoming output of a Bitcoin or
+	// Litecoin account needs before it can be spent. Values below 1 mean
+	// DefaultMinSpendConfirmations.
+	MinSpendConfirmations int `json:"minSpendConfirmations"`
+	// AccountMinSpendConfirmations overrides MinSpendConfirmations for individual accounts, keyed
+	// by account code.
+	AccountMinSpendConfirmations map[string]int `json:"accountMinSpendConfirmations"`
+
+	// EthPollIntervalSeconds is the interval in seconds in which Ethereum accounts are
+	// refreshed. 0 means DefaultEthPollInterval. Values below MinEthPollInterval are raised to it.
+	EthPollIntervalSeconds int `json:"ethPollIntervalSeconds"`
+	// AccountEthPollIntervalSeconds overrides EthPollIntervalSeconds for individual accounts, keyed
+	// by account code.
+	AccountEthPollIntervalSeconds map[string]int `json:"accountEthPollIntervalSeconds"`
+
+	// EthConfirmationsComplete is the number of confirmations after which an Ethereum transaction
+	// is shown as complete. Values below 1 mean DefaultEthConfirmationsComplete.
+	EthConfirmationsComplete int `json:"ethConfirmationsComplete"`
+	// AccountEthConfirmationsComplete overrides EthConfirmationsComplete for individual accounts,
+	// keyed by account code.
+	AccountEthConfirmationsComplete map[string]int `json:"accountEthConfirmationsComplete"`
+
+	// FinalityDepths overrides, keyed by coin code, e.g. "ltc", the number of confirmations after
+	// which a transaction is considered safe from reorgs and shown as complete. Without an entry,
+	// Bitcoin and Litecoin use DefaultBtcConfirmationsComplete and Ethereum uses
+	// EthConfirmationsComplete. For Bitcoin and Litecoin, a configured depth is also the number of
+	// confirmations an incoming output needs before it can be spent, if it is higher than
+	// MinSpendConfirmations.
+	FinalityDepths map[string]int `json:"finalityDepths"`
+
+	// EthGasLimitMultiplier is the factor by which the estimated gas of Ethereum contract
+	// interactions, e.g. ERC20 transfers, is increased to avoid running out of gas if the contract
+	// state changes before the tx is mined. Plain ether transfers are not affected. Values below 1
+	// mean DefaultEthGasLimitMultiplier.
+	EthGasLimitMultiplier float64 `json:"ethGasLimitMultiplier"`
+
+	// EnabledCoins lists the codes of the coins for which accounts are loaded, e.g. "btc", "ltc",
+	// "eth". ERC20 tokens belong to their Ethereum network. If empty, all coins are enabled.
+	EnabledCoins []string `json:"enabledCoins"`
+
+	// HideSmallBalancesThreshold hides accounts whose balance is worth less than this amount, in
+	// the fiat currency selected in the frontend, from the account list. 0 disables hiding.
+	HideSmallBalancesThreshold float64 `json:"hideSmallBalancesThreshold"`
+	// HideSmallBalancesInTotals also excludes the hidden accounts from the portfolio totals.
+	HideSmallBalancesInTotals bool `json:"hideSmallBalancesInTotals"`
+
 	BTC  btcCoinConfig `json:"btc"`
 	TBTC btcCoinConfig `json:"tbtc"`
 	RBTC btcCoinConfig `json:"rbtc"`
@@ -129,6 +203,98 @@ func (backend Backend) AccountActive(code string) bool {
 	}
 }
 
+// accountOverride returns the value of the account with the given code in overrides, falling back
+// to global if the account has no entry. Values below 1 mean defaultValue.
+func accountOverride(overrides map[string]int, code string, global int, defaultValue int) int {
+	value := global
+	if accountValue, ok := overrides[code]; ok {
+		value = accountValue
+	}
+	if value < 1 {
+		return defaultValue
+	}
+	return value
+}
+
+// MinSpendConfirmationsForAccount returns the number of confirmations an incoming output of the
+// account needs before it can be spent. The account specific setting takes precedence over the
+// global one. A higher finality depth configured for the coin of the account raises the result.
+func (backend Backend) MinSpendConfirmationsForAccount(code string, coinCode string) int {
+	minConfirmations := accountOverride(backend.AccountMinSpendConfirmations, code,
+		backend.MinSpendConfirmations, DefaultMinSpendConfirmations)
+	if depth, ok := backend.finalityDepth(coinCode); ok && depth > minConfirmations {
+		return depth
+	}
+	return minConfirmations
+}
+
+// finalityDepth returns the finality depth configured for the coin, see FinalityDepths. The second
+// result is false if there is no valid entry for the coin.
+func (backend Backend) finalityDepth(coinCode string) (int, bool) {
+	depth, ok := backend.FinalityDepths[coinCode]
+	if !ok || depth < 1 {
+		return 0, false
+	}
+	return depth, true
+}
+
+// BtcConfirmationsCompleteForCoin returns the number of confirmations after which a transaction of
+// the Bitcoin-based coin is considered complete.
+func (backend Backend) BtcConfirmationsCompleteForCoin(coinCode string) int {
+	if depth, ok := backend.finalityDepth(coinCode); ok {
+		return depth
+	}
+	return DefaultBtcConfirmationsComplete
+}
+
+// EthPollIntervalForAccount returns the interval in which the Ethereum account is refreshed. The
+// account specific setting takes precedence over the global one.
+func (backend Backend) EthPollIntervalForAccount(code string) time.Duration {
+	seconds := accountOverride(backend.AccountEthPollIntervalSeconds, code,
+		backend.EthPollIntervalSeconds, int(DefaultEthPollInterval/time.Second))
+	interval := time.Duration(seconds) * time.Second
+	if interval < MinEthPollInterval {
+		return MinEthPollInterval
+	}
+	return interval
+}
+
+// EthConfirmationsCompleteForAccount returns the number of confirmations after which a transaction
+// of the Ethereum account is considered complete. coinCode is the code of the Ethereum network of
+// the account, e.g. "eth" for ERC20 tokens. The account specific setting takes precedence over the
+// finality depth of the network, which takes precedence over the global setting.
+func (backend Backend) EthConfirmationsCompleteForAccount(code string, coinCode string) int {
+	confirmations := backend.EthConfirmationsComplete
+	if depth, ok := backend.finalityDepth(coinCode); ok {
+		confirmations = depth
+	}
+	return accountOverride(backend.AccountEthConfirmationsComplete, code,
+		confirmations, DefaultEthConfirmationsComplete)
+}
+
+// CoinEnabled returns true if accounts of the coin with the given code are loaded, see
+// EnabledCoins.
+func (backend Backend) CoinEnabled(coinCode string) bool {
+	if len(backend.EnabledCoins) == 0 {
+		return true
+	}
+	for _, enabledCoinCode := range backend.EnabledCoins {
+		if enabledCoinCode == coinCode {
+			return true
+		}
+	}
+	return false
+}
+
+// EthGasLimitMultiplierOrDefault returns the configured EthGasLimitMultiplier, or
+// DefaultEthGasLimitMultiplier if it is not set or invalid.
+func (backend Backend) EthGasLimitMultiplierOrDefault() float64 {
+	if backend.EthGasLimitMultiplier < 1 {
+		return DefaultEthGasLimitMultiplier
+	}
+	return backend.EthGasLimitMultiplier
+}
+
 // AppConfig holds the whole app configuration.
 type AppConfig struct {
 	Backend  Backend     `json:"backend"`
@@ -179,7 +345,7 @@ func NewDefaultAppConfig() AppConfig {
 		Backend: Backend{
 			Proxy: proxyConfig{
 				UseProxy:     false,
-				ProxyAddress: defaultProxyAddress,
+				ProxyAddress: DefaultProxyAddress,
 			},
 			Services: servicesConfig{
 				Safello: true,
@@ -191,6 +357,10 @@ func NewDefaultAppConfig() AppConfig {
 			LitecoinP2WPKHActive:     true,
 			EthereumActive:           true,
 
+			MinSpendConfirmations:    DefaultMinSpendConfirmations,
+			EthConfirmationsComplete: DefaultEthConfirmationsComplete,
+			EthGasLimitMultiplier:    DefaultEthGasLimitMultiplier,
+
 			BTC: btcCoinConfig{
 				ElectrumServers: []*ServerInfo{
 					{
@@ -297,6 +467,9 @@ func NewConfig(appConfigFilename string, accountsConfigFilename string) (*Config
 		accountsConfig:         newDefaultAccountsonfig(),
 	}
 	config.load()
+	if err := config.loadAccountsConfig(); err != nil {
+		return nil, err
+	}
 	appConfig := config.migrateInfura(config.appConfig)
 	if err := config.SetAppConfig(appConfig); err != nil {
 		return nil, errp.WithStack(err)
@@ -345,13 +518,48 @@ func (config *Config) load() {
 	if err := json.Unmarshal(jsonBytes, &config.appConfig); err != nil {
 		return
 	}
-	jsonBytes, err = ioutil.ReadFile(config.accountsConfigFilename)
+}
+
+// loadAccountsConfig loads the accounts config. A config written by a newer version of the app is
+// refused, as persisting it again could drop data the newer version relies on. An older config is
+// migrated to the current version after writing a backup of the original file. An unreadable config
+// is backed up as well before starting over with the default config, as it is overwritten with the
+// next change.
+func (config *Config) loadAccountsConfig() error {
+	jsonBytes, err := ioutil.ReadFile(config.accountsConfigFilename)
 	if err != nil {
-		return
+		return nil
 	}
-	if err := json.Unmarshal(jsonBytes, &config.accountsConfig); err != nil {
-		return
+	accountsConfig := newDefaultAccountsonfig()
+	// Configs written before the version was introduced don't have it.
+	accountsConfig.Version = 0
+	if err := json.Unmarshal(jsonBytes, &accountsConfig); err != nil {
+		backupFilename := fmt.Sprintf("%s.corrupt.bak", config.accountsConfigFilename)
+		logging.Get().WithGroup("config").WithError(err).WithField("backup", backupFilename).
+			Error("Could not parse the accounts config, starting over")
+		if err := ioutil.WriteFile(backupFilename, jsonBytes, 0644); err != nil {
+			return errp.WithStack(err)
+		}
+		return nil
+	}
+	if accountsConfig.Version > accountsConfigVersion {
+		return errp.Newf(
+			"The accounts config %s was written by a newer version of the app (version %d, "+
+				"supported: %d). Please update the app, or back up and remove the file to start over.",
+			config.accountsConfigFilename, accountsConfig.Version, accountsConfigVersion)
+	}
+	if accountsConfig.Version < accountsConfigVersion {
+		backupFilename := fmt.Sprintf("%s.v%d.bak", config.accountsConfigFilename, accountsConfig.Version)
+		if err := ioutil.WriteFile(backupFilename, jsonBytes, 0644); err != nil {
+			return errp.WithStack(err)
+		}
+		accountsConfig.Version = accountsConfigVersion
+		if err := config.save(config.accountsConfigFilename, accountsConfig); err != nil {
+			return err
+		}
 	}
+	config.accountsConfig = accountsConfig
+	return nil
 }
 
 // AppConfig returns the app config.
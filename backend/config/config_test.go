@@ -0,0 +1,188 @@
+// Copyright 2018 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountsConfigVersion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config_test")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	appConfigFilename := path.Join(dir, "config.json")
+	accountsConfigFilename := path.Join(dir, "accounts.json")
+
+	// Current version.
+	require.NoError(t, ioutil.WriteFile(accountsConfigFilename,
+		[]byte(`{"version": 1, "accounts": [{"code": "test-account"}]}`), 0644))
+	config, err := NewConfig(appConfigFilename, accountsConfigFilename)
+	require.NoError(t, err)
+	require.Equal(t, accountsConfigVersion, config.AccountsConfig().Version)
+	require.Equal(t, "test-account", config.AccountsConfig().Accounts[0].Code)
+
+	// Written by a newer version of the app: refused and left untouched.
+	newerConfig := []byte(`{"version": 2, "accounts": [{"code": "test-account"}]}`)
+	require.NoError(t, ioutil.WriteFile(accountsConfigFilename, newerConfig, 0644))
+	_, err = NewConfig(appConfigFilename, accountsConfigFilename)
+	require.Error(t, err)
+	jsonBytes, err := ioutil.ReadFile(accountsConfigFilename)
+	require.NoError(t, err)
+	require.Equal(t, newerConfig, jsonBytes)
+
+	// Unversioned: migrated, with a backup of the original.
+	oldConfig := []byte(`{"accounts": [{"code": "test-account"}]}`)
+	require.NoError(t, ioutil.WriteFile(accountsConfigFilename, oldConfig, 0644))
+	config, err = NewConfig(appConfigFilename, accountsConfigFilename)
+	require.NoError(t, err)
+	require.Equal(t, accountsConfigVersion, config.AccountsConfig().Version)
+	require.Equal(t, "test-account", config.AccountsConfig().Accounts[0].Code)
+	jsonBytes, err = ioutil.ReadFile(accountsConfigFilename + ".v0.bak")
+	require.NoError(t, err)
+	require.Equal(t, oldConfig, jsonBytes)
+	config, err = NewConfig(appConfigFilename, accountsConfigFilename)
+	require.NoError(t, err)
+	require.Equal(t, accountsConfigVersion, config.AccountsConfig().Version)
+
+	// Unreadable: the defaults are used, with a backup of the original.
+	corruptConfig := []byte(`{"version": 1, "accounts": [{"code": "test-acc`)
+	require.NoError(t, ioutil.WriteFile(accountsConfigFilename, corruptConfig, 0644))
+	config, err = NewConfig(appConfigFilename, accountsConfigFilename)
+	require.NoError(t, err)
+	require.Empty(t, config.AccountsConfig().Accounts)
+	jsonBytes, err = ioutil.ReadFile(accountsConfigFilename + ".corrupt.bak")
+	require.NoError(t, err)
+	require.Equal(t, corruptConfig, jsonBytes)
+}
+
+func TestAccountMetadata(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config_test")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	appConfigFilename := path.Join(dir, "config.json")
+	accountsConfigFilename := path.Join(dir, "accounts.json")
+
+	// Metadata survives the migration of an unversioned config.
+	require.NoError(t, ioutil.WriteFile(accountsConfigFilename,
+		[]byte(`{"accounts": [{"code": "test-account", "metadata": {"key": "value"}}]}`), 0644))
+	config, err := NewConfig(appConfigFilename, accountsConfigFilename)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"key": "value"}, config.AccountsConfig().Accounts[0].Metadata)
+
+	// Round trip.
+	accountsConfig := config.AccountsConfig()
+	accountsConfig.Accounts = append(accountsConfig.Accounts,
+		Account{Code: "other-account", Metadata: map[string]string{"other": "data"}},
+		Account{Code: "no-metadata"},
+	)
+	require.NoError(t, config.SetAccountsConfig(accountsConfig))
+	config, err = NewConfig(appConfigFilename, accountsConfigFilename)
+	require.NoError(t, err)
+	accounts := config.AccountsConfig().Accounts
+	require.Len(t, accounts, 3)
+	require.Equal(t, map[string]string{"key": "value"}, accounts[0].Metadata)
+	require.Equal(t, map[string]string{"other": "data"}, accounts[1].Metadata)
+	require.Nil(t, accounts[2].Metadata)
+}
+
+func TestAccountOverride(t *testing.T) {
+	overrides := map[string]int{"btc-p2wpkh": 3, "btc-p2pkh": 0}
+	require.Equal(t, 3, accountOverride(overrides, "btc-p2wpkh", 2, 1))
+	require.Equal(t, 2, accountOverride(overrides, "btc-p2wpkh-p2sh", 2, 1))
+	// Values below 1 mean the default, also for an account entry.
+	require.Equal(t, 1, accountOverride(overrides, "btc-p2pkh", 2, 1))
+	require.Equal(t, 1, accountOverride(nil, "btc-p2wpkh", 0, 1))
+}
+
+func TestEthPollIntervalForAccount(t *testing.T) {
+	backend := Backend{}
+	require.Equal(t, DefaultEthPollInterval, backend.EthPollIntervalForAccount("eth"))
+
+	backend.EthPollIntervalSeconds = 120
+	require.Equal(t, 2*time.Minute, backend.EthPollIntervalForAccount("eth"))
+
+	// Too small intervals are raised to the minimum.
+	backend.EthPollIntervalSeconds = 1
+	require.Equal(t, MinEthPollInterval, backend.EthPollIntervalForAccount("eth"))
+
+	backend.AccountEthPollIntervalSeconds = map[string]int{"teth": 300}
+	require.Equal(t, 5*time.Minute, backend.EthPollIntervalForAccount("teth"))
+	require.Equal(t, MinEthPollInterval, backend.EthPollIntervalForAccount("eth"))
+}
+
+func TestEthConfirmationsCompleteForAccount(t *testing.T) {
+	backend := Backend{}
+	require.Equal(t, DefaultEthConfirmationsComplete, backend.EthConfirmationsCompleteForAccount("eth", "eth"))
+
+	backend.EthConfirmationsComplete = 30
+	require.Equal(t, 30, backend.EthConfirmationsCompleteForAccount("eth", "eth"))
+
+	backend.AccountEthConfirmationsComplete = map[string]int{"teth": 50, "reth": 0}
+	require.Equal(t, 50, backend.EthConfirmationsCompleteForAccount("teth", "teth"))
+	require.Equal(t, DefaultEthConfirmationsComplete, backend.EthConfirmationsCompleteForAccount("reth", "reth"))
+	require.Equal(t, 30, backend.EthConfirmationsCompleteForAccount("eth", "eth"))
+
+	// The finality depth of the network applies to all its accounts without an account setting.
+	backend.FinalityDepths = map[string]int{"eth": 40, "teth": 60}
+	require.Equal(t, 40, backend.EthConfirmationsCompleteForAccount("eth", "eth"))
+	require.Equal(t, 40, backend.EthConfirmationsCompleteForAccount("eth-erc20-usdt", "eth"))
+	require.Equal(t, 50, backend.EthConfirmationsCompleteForAccount("teth", "teth"))
+}
+
+func TestFinalityDepths(t *testing.T) {
+	backend := NewDefaultAppConfig().Backend
+	require.Equal(t, DefaultBtcConfirmationsComplete, backend.BtcConfirmationsCompleteForCoin("btc"))
+	require.Equal(t, DefaultBtcConfirmationsComplete, backend.BtcConfirmationsCompleteForCoin("ltc"))
+	require.Equal(t, DefaultMinSpendConfirmations, backend.MinSpendConfirmationsForAccount("ltc-p2wpkh", "ltc"))
+
+	backend.FinalityDepths = map[string]int{"ltc": 12, "tltc": 0}
+	require.Equal(t, 12, backend.BtcConfirmationsCompleteForCoin("ltc"))
+	require.Equal(t, DefaultBtcConfirmationsComplete, backend.BtcConfirmationsCompleteForCoin("tltc"))
+	require.Equal(t, DefaultBtcConfirmationsComplete, backend.BtcConfirmationsCompleteForCoin("btc"))
+
+	// The finality depth raises the number of confirmations needed to spend, but never lowers it.
+	require.Equal(t, 12, backend.MinSpendConfirmationsForAccount("ltc-p2wpkh", "ltc"))
+	require.Equal(t, DefaultMinSpendConfirmations, backend.MinSpendConfirmationsForAccount("btc-p2wpkh", "btc"))
+	backend.AccountMinSpendConfirmations = map[string]int{"ltc-p2wpkh": 20}
+	require.Equal(t, 20, backend.MinSpendConfirmationsForAccount("ltc-p2wpkh", "ltc"))
+}
+
+func TestEthGasLimitMultiplierOrDefault(t *testing.T) {
+	backend := Backend{}
+	require.Equal(t, DefaultEthGasLimitMultiplier, backend.EthGasLimitMultiplierOrDefault())
+	backend.EthGasLimitMultiplier = 0.5
+	require.Equal(t, DefaultEthGasLimitMultiplier, backend.EthGasLimitMultiplierOrDefault())
+	backend.EthGasLimitMultiplier = 1.5
+	require.Equal(t, 1.5, backend.EthGasLimitMultiplierOrDefault())
+	require.Equal(t, DefaultEthGasLimitMultiplier,
+		NewDefaultAppConfig().Backend.EthGasLimitMultiplierOrDefault())
+}
+
+func TestCoinEnabled(t *testing.T) {
+	backend := Backend{}
+	require.True(t, backend.CoinEnabled("btc"))
+	require.True(t, backend.CoinEnabled("ltc"))
+	backend.EnabledCoins = []string{"btc", "eth"}
+	require.True(t, backend.CoinEnabled("btc"))
+	require.True(t, backend.CoinEnabled("eth"))
+	require.False(t, backend.CoinEnabled("ltc"))
+	require.True(t, NewDefaultAppConfig().Backend.CoinEnabled("ltc"))
+}
@@ -17,19 +17,26 @@ package bitbox
 import (
 	"encoding/hex"
 	"encoding/json"
+	"math/big"
 	"os"
 	"reflect"
 	"strconv"
 	"strings"
 	"testing"
 
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/devices/bitbox/mocks"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/devices/bitbox/relay"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/devices/device/event"
+	keystorePkg "github.com/digitalbitbox/bitbox-wallet-app/backend/keystore"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/jsonp"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/logging"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/socksproxy"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/test"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -244,6 +251,33 @@ func (s *dbbTestSuite) mockDeviceInfo() {
 	).Return(map[string]interface{}{"device": deviceInfoMap}, nil).Once()
 }
 
+func (s *dbbTestSuite) TestSignETHTransactionAborted() {
+	require.NoError(s.T(), s.login())
+	keypath, err := signing.NewAbsoluteKeypath("m/44'/1'/0'/0/0")
+	require.NoError(s.T(), err)
+	keystore := &keystore{dbb: s.dbb, log: s.log}
+
+	for _, errorCode := range []float64{ErrTouchAbort, ErrTouchTimeout} {
+		s.mockDeviceInfo()
+		// The first call returns the echo, the second one the signatures.
+		s.mockCommunication.On("SendEncrypt", mock.Anything, pin).Return(nil, nil).Once()
+		s.mockCommunication.On(
+			"SendEncrypt",
+			jsonArgumentMatcher(map[string]interface{}{"sign": ""}),
+			pin,
+		).
+			Return(nil, NewError("aborted", errorCode)).
+			Once()
+		err := keystore.SignTransaction(&eth.TxProposal{
+			Tx: types.NewTransaction(
+				0, common.Address{}, big.NewInt(1), 21000, big.NewInt(1), nil),
+			Signer:  types.NewEIP155Signer(big.NewInt(3)),
+			Keypath: keypath,
+		})
+		require.Equal(s.T(), keystorePkg.ErrSigningAborted, errp.Cause(err))
+	}
+}
+
 func (s *dbbTestSuite) TestSignFifteen() {
 	require.NoError(s.T(), s.login())
 
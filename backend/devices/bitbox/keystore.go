@@ -195,3 +195,8 @@ func (keystore *keystore) SignTransaction(proposedTx interface{}) error {
 		panic("unknown proposal type")
 	}
 }
+
+// SignETHMessage implements keystore.Keystore.
+func (keystore *keystore) SignETHMessage(coin.Coin, signing.AbsoluteKeypath, []byte) ([]byte, error) {
+	return nil, errp.New("BitBox v1 does not support signing Ethereum messages")
+}
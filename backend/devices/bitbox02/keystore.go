@@ -52,16 +52,16 @@ func (keystore *keystore) CosignerIndex() int {
 	return keystore.cosignerIndex
 }
 
-// SupportsAccount implements keystore.Keystore.
-func (keystore *keystore) SupportsAccount(
-	coin coin.Coin, multisig bool, meta interface{}) bool {
+// supportsCoin returns true if the connected device can handle the coin. This depends on the
+// edition of the device, e.g. the btc-only edition only supports Bitcoin, and on its firmware
+// version.
+func (keystore *keystore) supportsCoin(coin coinpkg.Coin) bool {
 	switch specificCoin := coin.(type) {
 	case *btc.Coin:
-		if (coin.Code() == "ltc" || coin.Code() == "tltc") && !keystore.device.SupportsLTC() {
-			return false
+		if coin.Code() == "ltc" || coin.Code() == "tltc" {
+			return keystore.device.SupportsLTC()
 		}
-		scriptType := meta.(signing.ScriptType)
-		return !multisig && scriptType != signing.ScriptTypeP2PKH
+		return true
 	case *eth.Coin:
 		if specificCoin.ERC20Token() != nil {
 			return keystore.device.SupportsERC20(specificCoin.ERC20Token().ContractAddress().String())
@@ -72,18 +72,37 @@ func (keystore *keystore) SupportsAccount(
 	}
 }
 
+// SupportsAccount implements keystore.Keystore.
+func (keystore *keystore) SupportsAccount(
+	coin coin.Coin, multisig bool, meta interface{}) bool {
+	if !keystore.supportsCoin(coin) {
+		return false
+	}
+	switch coin.(type) {
+	case *btc.Coin:
+		scriptType := meta.(signing.ScriptType)
+		return !multisig && scriptType != signing.ScriptTypeP2PKH
+	default:
+		return true
+	}
+}
+
 // CanVerifyAddress implements keystore.Keystore.
 func (keystore *keystore) CanVerifyAddress(configuration *signing.Configuration, coin coinpkg.Coin) (bool, bool, error) {
 	optional := false
 	switch coin.(type) {
 	case *btc.Coin:
-		_, ok := btcMsgCoinMap[coin.Code()]
-		return ok, optional, nil
+		if _, ok := btcMsgCoinMap[coin.Code()]; !ok {
+			return false, optional, nil
+		}
 	case *eth.Coin:
-		_, ok := ethMsgCoinMap[coin.Code()]
-		return ok, optional, nil
+		if _, ok := ethMsgCoinMap[coin.Code()]; !ok {
+			return false, optional, nil
+		}
+	default:
+		return false, false, nil
 	}
-	return false, false, nil
+	return keystore.supportsCoin(coin), optional, nil
 }
 
 // VerifyAddress implements keystore.Keystore.
@@ -94,13 +113,14 @@ func (keystore *keystore) VerifyAddress(
 		return err
 	}
 	if !canVerifyAddress {
-		panic("CanVerifyAddress must be true")
+		return errp.Newf("cannot verify addresses of coin %s", coin.Code())
 	}
 	switch specificCoin := coin.(type) {
 	case *btc.Coin:
-		msgScriptType, ok := btcMsgScriptTypeMap[configuration.ScriptType()]
+		scriptType := configuration.ScriptType()
+		msgScriptType, ok := btcMsgScriptTypeMap[scriptType]
 		if !ok {
-			panic("unsupported scripttype")
+			return errp.Newf("Unsupported script type %s", scriptType)
 		}
 		_, err = keystore.device.BTCAddress(
 			btcMsgCoinMap[coin.Code()],
@@ -347,3 +367,21 @@ func (keystore *keystore) SignTransaction(proposedTx interface{}) error {
 		panic("unknown proposal type")
 	}
 }
+
+// SignETHMessage implements keystore.Keystore.
+func (keystore *keystore) SignETHMessage(
+	coin coinpkg.Coin, keypath signing.AbsoluteKeypath, message []byte) ([]byte, error) {
+	msgCoin, ok := ethMsgCoinMap[coin.Code()]
+	if !ok {
+		return nil, errp.New("unsupported coin")
+	}
+	// The device prefixes the message according to personal_sign before hashing.
+	signature, err := keystore.device.ETHSignMessage(msgCoin, keypath.ToUInt32(), message)
+	if firmware.IsErrorAbort(err) {
+		return nil, errp.WithStack(keystorePkg.ErrSigningAborted)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return signature, nil
+}
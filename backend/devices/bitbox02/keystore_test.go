@@ -0,0 +1,123 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bitbox02
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/erc20"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/logging"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/socksproxy"
+	bitbox02common "github.com/digitalbitbox/bitbox02-api-go/api/common"
+	"github.com/digitalbitbox/bitbox02-api-go/util/semver"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestConfiguration(t *testing.T, scriptType signing.ScriptType) *signing.Configuration {
+	t.Helper()
+	xprv, err := hdkeychain.NewMaster(make([]byte, hdkeychain.RecommendedSeedLen), &chaincfg.MainNetParams)
+	require.NoError(t, err)
+	xpub, err := xprv.Neuter()
+	require.NoError(t, err)
+	keypath, err := signing.NewAbsoluteKeypath("m/44'/0'/0'/0/0")
+	require.NoError(t, err)
+	return signing.NewSinglesigConfiguration(scriptType, keypath, xpub)
+}
+
+func newTestBTCCoin(code string) *btc.Coin {
+	return btc.NewCoin(code, "BTC", &chaincfg.MainNetParams, "", nil, "",
+		socksproxy.NewSocksProxy(false, ""))
+}
+
+func TestVerifyAddressUnsupportedScriptType(t *testing.T) {
+	keystore := &keystore{log: logging.Get().WithGroup("bitbox02_test")}
+	configuration := newTestConfiguration(t, signing.ScriptTypeP2PKH)
+	require.NotPanics(t, func() {
+		require.Error(t, keystore.VerifyAddress(configuration, newTestBTCCoin("btc")))
+	})
+}
+
+func TestVerifyAddressUnsupportedCoin(t *testing.T) {
+	keystore := &keystore{log: logging.Get().WithGroup("bitbox02_test")}
+	configuration := newTestConfiguration(t, signing.ScriptTypeP2WPKH)
+	require.NotPanics(t, func() {
+		require.Error(t, keystore.VerifyAddress(configuration, newTestBTCCoin("rbtc")))
+	})
+}
+
+// newTestKeystore returns a keystore of a device with the given edition and firmware version. The
+// device can't communicate, which is fine as long as only its capabilities are queried.
+func newTestKeystore(product bitbox02common.Product, version *semver.SemVer) *keystore {
+	return &keystore{
+		device: NewDevice("device-id", version, product, nil, nil),
+		log:    logging.Get().WithGroup("bitbox02_test"),
+	}
+}
+
+func TestDeviceEditions(t *testing.T) {
+	ethCoin := eth.NewCoin("eth", "ETH", "ETH", params.MainnetChainConfig, "", nil, "", nil,
+		socksproxy.NewSocksProxy(false, ""))
+	usdtCoin := eth.NewCoin("eth-erc20-usdt", "USDT", "ETH", params.MainnetChainConfig, "", nil, "",
+		erc20.NewToken("0xdAC17F958D2ee523a2206206994597C13D831ec7", 6),
+		socksproxy.NewSocksProxy(false, ""))
+	coins := []coin.Coin{newTestBTCCoin("btc"), newTestBTCCoin("ltc"), ethCoin, usdtCoin}
+	btcConfiguration := newTestConfiguration(t, signing.ScriptTypeP2WPKH)
+
+	tests := []struct {
+		name     string
+		product  bitbox02common.Product
+		version  *semver.SemVer
+		expected []bool
+	}{
+		{
+			name:     "multi",
+			product:  bitbox02common.ProductBitBox02Multi,
+			version:  semver.NewSemVer(9, 0, 0),
+			expected: []bool{true, true, true, true},
+		},
+		{
+			name:     "multi, before ethereum support",
+			product:  bitbox02common.ProductBitBox02Multi,
+			version:  semver.NewSemVer(3, 0, 0),
+			expected: []bool{true, true, false, false},
+		},
+		{
+			name:     "btc-only",
+			product:  bitbox02common.ProductBitBox02BTCOnly,
+			version:  semver.NewSemVer(9, 0, 0),
+			expected: []bool{true, false, false, false},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			keystore := newTestKeystore(test.product, test.version)
+			for i, coin := range coins {
+				require.Equal(t, test.expected[i],
+					keystore.SupportsAccount(coin, false, signing.ScriptTypeP2WPKH), coin.Code())
+				canVerify, _, err := keystore.CanVerifyAddress(btcConfiguration, coin)
+				require.NoError(t, err)
+				require.Equal(t, test.expected[i], canVerify, coin.Code())
+			}
+		})
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"io"
 	"os"
 	"regexp"
+	"sync"
 	"time"
 
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/devices/bitbox"
@@ -42,6 +43,13 @@ const (
 
 	bitboxCMD             = 0x80 + 0x40 + 0x01
 	bitbox02BootloaderCMD = 0x80 + 0x40 + 0x03
+
+	// defaultOpenAttempts is the default number of times opening an inserted device is attempted
+	// before giving up until the next poll.
+	defaultOpenAttempts = 3
+	// defaultOpenRetryDelay is the default delay before the first retry. It doubles after each
+	// failed attempt.
+	defaultOpenRetryDelay = 100 * time.Millisecond
 )
 
 // DeviceInfo contains the usb descriptor info and a way to open the device for reading and writing.
@@ -88,8 +96,19 @@ type Manager struct {
 
 	onlyOne bool
 
+	// openAttempts and openRetryDelay configure the retries when opening an inserted device fails,
+	// see openDevice().
+	openAttempts   int
+	openRetryDelay time.Duration
+
 	socksProxy socksproxy.SocksProxy
 
+	// quit is closed by Close() to stop listening for devices.
+	quit      chan struct{}
+	closeOnce sync.Once
+	// done is closed when the listen loop has exited.
+	done chan struct{}
+
 	log *logrus.Entry
 }
 
@@ -115,7 +134,11 @@ func NewManager(
 		onRegister:        onRegister,
 		onUnregister:      onUnregister,
 		onlyOne:           onlyOne,
+		openAttempts:      defaultOpenAttempts,
+		openRetryDelay:    defaultOpenRetryDelay,
 		socksProxy:        socksProxy,
+		quit:              make(chan struct{}),
+		done:              make(chan struct{}),
 
 		log: logging.Get().WithGroup("manager"),
 	}
@@ -146,6 +169,28 @@ func (manager *Manager) parseVersion(serial string) (*semver.SemVer, error) {
 	return version, err
 }
 
+// openDevice opens the device. Right after being plugged in, a device can be enumerated before it
+// can be opened, so failed attempts are retried with a short backoff.
+func (manager *Manager) openDevice(deviceInfo DeviceInfo) (io.ReadWriteCloser, error) {
+	delay := manager.openRetryDelay
+	for attempt := 1; ; attempt++ {
+		hidDevice, err := deviceInfo.Open()
+		if err == nil {
+			return hidDevice, nil
+		}
+		if attempt >= manager.openAttempts {
+			return nil, errp.WithMessage(err, "Failed to open device")
+		}
+		manager.log.WithError(err).WithField("attempt", attempt).Warning("Failed to open device, retrying")
+		select {
+		case <-manager.quit:
+			return nil, errp.WithMessage(err, "Failed to open device")
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
 func (manager *Manager) makeBitBox(deviceInfo DeviceInfo) (*bitbox.Device, error) {
 	deviceID := deviceInfo.Identifier()
 	manager.log.
@@ -157,9 +202,9 @@ func (manager *Manager) makeBitBox(deviceInfo DeviceInfo) (*bitbox.Device, error
 	if err != nil {
 		return nil, err
 	}
-	hidDevice, err := deviceInfo.Open()
+	hidDevice, err := manager.openDevice(deviceInfo)
 	if err != nil {
-		return nil, errp.WithMessage(err, "Failed to open device")
+		return nil, err
 	}
 	device, err := bitbox.NewDevice(
 		deviceID,
@@ -203,9 +248,9 @@ func (manager *Manager) makeBitBox02(deviceInfo DeviceInfo) (*bitbox02.Device, e
 	if err != nil {
 		return nil, err
 	}
-	hidDevice, err := deviceInfo.Open()
+	hidDevice, err := manager.openDevice(deviceInfo)
 	if err != nil {
-		return nil, errp.WithMessage(err, "Failed to open device")
+		return nil, err
 	}
 	return bitbox02.NewDevice(
 		deviceID,
@@ -231,9 +276,9 @@ func (manager *Manager) makeBitBox02Bootloader(deviceInfo DeviceInfo) (
 	if err != nil {
 		return nil, err
 	}
-	hidDevice, err := deviceInfo.Open()
+	hidDevice, err := manager.openDevice(deviceInfo)
 	if err != nil {
-		return nil, errp.WithMessage(err, "Failed to open device")
+		return nil, err
 	}
 	return bitbox02bootloader.NewDevice(
 		deviceID,
@@ -260,14 +305,12 @@ func (manager *Manager) checkIfRemoved(deviceID string) bool {
 }
 
 func (manager *Manager) listen() {
+	defer close(manager.done)
 	for {
 		for deviceID, device := range manager.devices {
 			// Check if device was removed.
 			if manager.checkIfRemoved(deviceID) {
-				device.Close()
-				delete(manager.devices, deviceID)
-				manager.onUnregister(deviceID)
-				manager.log.WithField("device-id", deviceID).Info("Unregistered device")
+				manager.unregister(deviceID, device)
 			}
 		}
 
@@ -314,11 +357,38 @@ func (manager *Manager) listen() {
 				manager.log.WithError(err).Error("Failed to execute on-register")
 			}
 		}
-		time.Sleep(time.Second)
+		select {
+		case <-manager.quit:
+			manager.unregisterAll()
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (manager *Manager) unregister(deviceID string, device device.Interface) {
+	device.Close()
+	delete(manager.devices, deviceID)
+	manager.onUnregister(deviceID)
+	manager.log.WithField("device-id", deviceID).Info("Unregistered device")
+}
+
+// unregisterAll closes and unregisters all registered devices.
+func (manager *Manager) unregisterAll() {
+	for deviceID, device := range manager.devices {
+		manager.unregister(deviceID, device)
 	}
 }
 
-// Start listens for inserted/removed devices forever.
+// Start listens for inserted/removed devices until Close() is called.
 func (manager *Manager) Start() {
 	go manager.listen()
 }
+
+// Close stops listening for devices, and closes and unregisters all registered devices. It blocks
+// until the listen loop has exited. Close must only be called after Start(). Calling it again has
+// no effect.
+func (manager *Manager) Close() {
+	manager.closeOnce.Do(func() { close(manager.quit) })
+	<-manager.done
+}
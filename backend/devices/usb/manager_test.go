@@ -0,0 +1,171 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package usb
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/devices/bitbox02bootloader"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/devices/device"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/locker"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/socksproxy"
+	bitbox02common "github.com/digitalbitbox/bitbox02-api-go/api/common"
+	"github.com/stretchr/testify/require"
+)
+
+type testDeviceInfo struct {
+	DeviceInfo
+	identifier string
+}
+
+func (info testDeviceInfo) Identifier() string {
+	return info.identifier
+}
+
+type closeRecordingDevice struct {
+	device.Interface
+	closed bool
+}
+
+func (d *closeRecordingDevice) Close() {
+	d.closed = true
+}
+
+// TestManagerClose checks that Close() stops the listen loop and unregisters all devices.
+func TestManagerClose(t *testing.T) {
+	unregistered := []string{}
+	manager := NewManager(
+		"", "",
+		socksproxy.NewSocksProxy(false, ""),
+		func() []DeviceInfo { return []DeviceInfo{testDeviceInfo{identifier: "plugged-in"}} },
+		func(device.Interface) error { return nil },
+		func(deviceID string) { unregistered = append(unregistered, deviceID) },
+		true,
+	)
+	pluggedInDevice := &closeRecordingDevice{}
+	removedDevice := &closeRecordingDevice{}
+	manager.devices["plugged-in"] = pluggedInDevice
+	manager.devices["removed"] = removedDevice
+	manager.Start()
+
+	closed := make(chan struct{})
+	go func() {
+		manager.Close()
+		// Closing again has no effect.
+		manager.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "Close() did not return")
+	}
+	require.True(t, pluggedInDevice.closed)
+	require.True(t, removedDevice.closed)
+	// The removed device is unregistered by the listen loop before it notices the shutdown.
+	require.Equal(t, []string{"removed", "plugged-in"}, unregistered)
+	require.Empty(t, manager.devices)
+}
+
+type nopReadWriteCloser struct{}
+
+func (nopReadWriteCloser) Read([]byte) (int, error)    { return 0, io.EOF }
+func (nopReadWriteCloser) Write(p []byte) (int, error) { return len(p), nil }
+func (nopReadWriteCloser) Close() error                { return nil }
+
+// slowDeviceInfo is a BitBox02 bootloader which can only be opened after failing to open a number
+// of times, like a device which was just plugged in.
+type slowDeviceInfo struct {
+	lock     locker.Locker
+	failures int
+	opened   int
+}
+
+func (info *slowDeviceInfo) VendorID() int      { return bitbox02VendorID }
+func (info *slowDeviceInfo) ProductID() int     { return bitbox02ProductID }
+func (info *slowDeviceInfo) UsagePage() int     { return 0xffff }
+func (info *slowDeviceInfo) Interface() int     { return 0 }
+func (info *slowDeviceInfo) Serial() string     { return "v1.0.0" }
+func (info *slowDeviceInfo) Identifier() string { return "slow" }
+func (info *slowDeviceInfo) Product() string {
+	return bitbox02common.BootloaderHIDProductStringStandard
+}
+
+func (info *slowDeviceInfo) Open() (io.ReadWriteCloser, error) {
+	defer info.lock.Lock()()
+	info.opened++
+	if info.opened <= info.failures {
+		return nil, errp.New("device not ready")
+	}
+	return nopReadWriteCloser{}, nil
+}
+
+func (info *slowDeviceInfo) openCount() int {
+	defer info.lock.Lock()()
+	return info.opened
+}
+
+func newTestManager(deviceInfo DeviceInfo, onRegister func(device.Interface) error) *Manager {
+	manager := NewManager(
+		"", "",
+		socksproxy.NewSocksProxy(false, ""),
+		func() []DeviceInfo { return []DeviceInfo{deviceInfo} },
+		onRegister,
+		func(string) {},
+		true,
+	)
+	manager.openRetryDelay = time.Millisecond
+	return manager
+}
+
+func TestManagerOpenDevice(t *testing.T) {
+	// Succeeds on the second attempt.
+	deviceInfo := &slowDeviceInfo{failures: 1}
+	manager := newTestManager(deviceInfo, nil)
+	_, err := manager.openDevice(deviceInfo)
+	require.NoError(t, err)
+	require.Equal(t, 2, deviceInfo.openCount())
+
+	// Gives up after the configured number of attempts.
+	deviceInfo = &slowDeviceInfo{failures: 10}
+	manager = newTestManager(deviceInfo, nil)
+	_, err = manager.openDevice(deviceInfo)
+	require.Error(t, err)
+	require.Equal(t, defaultOpenAttempts, deviceInfo.openCount())
+}
+
+// TestManagerRegisterSlowDevice checks that a device which can't be opened right away is registered
+// in the same poll.
+func TestManagerRegisterSlowDevice(t *testing.T) {
+	deviceInfo := &slowDeviceInfo{failures: 1}
+	registered := make(chan device.Interface, 1)
+	manager := newTestManager(deviceInfo, func(device device.Interface) error {
+		registered <- device
+		return nil
+	})
+	manager.Start()
+	defer manager.Close()
+	select {
+	case registeredDevice := <-registered:
+		require.Equal(t, "slow", registeredDevice.Identifier())
+		require.Equal(t, bitbox02bootloader.ProductName, registeredDevice.ProductName())
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "device was not registered")
+	}
+	require.Equal(t, 2, deviceInfo.openCount())
+}
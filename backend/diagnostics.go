@@ -0,0 +1,195 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"runtime"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/config"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/logging"
+)
+
+// diagnosticsLogTailSize is the number of bytes of the end of the log file which are included in
+// the diagnostics.
+const diagnosticsLogTailSize = 256 * 1024
+
+// xpubRegexp matches serialized extended public keys of all supported networks and script types
+// (xpub, ypub, zpub, tpub, upub, vpub, Ltub, Mtub).
+var xpubRegexp = regexp.MustCompile(`\b(?:[xyztuv]pub|[LM]tub)[1-9A-HJ-NP-Za-km-z]{100,}`)
+
+// redactXPubs replaces all extended public keys in the data, so the diagnostics do not allow
+// anyone to look up the user's transaction history.
+func redactXPubs(data []byte) []byte {
+	return xpubRegexp.ReplaceAll(data, []byte("<redacted xpub>"))
+}
+
+// redactedValue replaces sensitive config values in the diagnostics.
+const redactedValue = "<redacted>"
+
+// redactAppConfig returns a copy of the app config without the Ethereum node URLs, which often
+// contain an API key, and the proxy address. It also returns the removed values, so they can be
+// redacted from the log as well.
+func redactAppConfig(appConfig config.AppConfig) (config.AppConfig, []string) {
+	secrets := []string{}
+	redact := func(value *string) {
+		if *value == "" {
+			return
+		}
+		secrets = append(secrets, *value)
+		*value = redactedValue
+	}
+	redact(&appConfig.Backend.Proxy.ProxyAddress)
+	redact(&appConfig.Backend.ETH.NodeURL)
+	redact(&appConfig.Backend.TETH.NodeURL)
+	redact(&appConfig.Backend.RETH.NodeURL)
+	return appConfig, secrets
+}
+
+// redactSecrets replaces all occurrences of the given values in the data.
+func redactSecrets(data []byte, secrets []string) []byte {
+	for _, secret := range secrets {
+		data = bytes.ReplaceAll(data, []byte(secret), []byte(redactedValue))
+	}
+	return data
+}
+
+type diagnosticsAccount struct {
+	Code         string  `json:"code"`
+	CoinCode     string  `json:"coinCode"`
+	Name         string  `json:"name"`
+	Initialized  bool    `json:"initialized"`
+	SyncProgress float64 `json:"syncProgress"`
+	Offline      bool    `json:"offline"`
+	FatalError   bool    `json:"fatalError"`
+}
+
+type diagnosticsStatus struct {
+	OS              string   `json:"os"`
+	Arch            string   `json:"arch"`
+	Testing         bool     `json:"testing"`
+	Regtest         bool     `json:"regtest"`
+	Devices         []string `json:"devices"`
+	KeystoreCount   int      `json:"keystoreCount"`
+	RatesAvailable  bool     `json:"ratesAvailable"`
+	OfflineAccounts []string `json:"offlineAccounts"`
+}
+
+// logTail returns the end of the log file, or nil if the log is not written to a file.
+func logTail() ([]byte, error) {
+	file, ok := logging.Get().Out.(*os.File)
+	if !ok || file == os.Stdout || file == os.Stderr {
+		return nil, nil
+	}
+	logFile, err := os.Open(file.Name())
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	defer func() { _ = logFile.Close() }()
+	info, err := logFile.Stat()
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	if info.Size() > diagnosticsLogTailSize {
+		if _, err := logFile.Seek(-diagnosticsLogTailSize, io.SeekEnd); err != nil {
+			return nil, errp.WithStack(err)
+		}
+	}
+	tail, err := ioutil.ReadAll(logFile)
+	return tail, errp.WithStack(err)
+}
+
+// ExportDiagnostics returns a zip archive to be attached to support requests. It contains the app
+// config, a summary of the loaded accounts, the connectivity status and the end of the log file.
+// Extended public keys, the Ethereum node URLs and the proxy address are redacted in all of them.
+func (backend *Backend) ExportDiagnostics() ([]byte, error) {
+	redactedConfig, secrets := redactAppConfig(backend.config.AppConfig())
+	appConfig, err := json.MarshalIndent(redactedConfig, "", "  ")
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+
+	status := diagnosticsStatus{
+		OS:              runtime.GOOS,
+		Arch:            runtime.GOARCH,
+		Testing:         backend.Testing(),
+		Regtest:         backend.arguments.Regtest(),
+		Devices:         []string{},
+		KeystoreCount:   backend.keystores.Count(),
+		RatesAvailable:  len(backend.ratesUpdater.Last()) > 0,
+		OfflineAccounts: []string{},
+	}
+	for _, device := range backend.DevicesRegistered() {
+		status.Devices = append(status.Devices, device.ProductName())
+	}
+	diagnosticsAccounts := []*diagnosticsAccount{}
+	for _, account := range backend.Accounts() {
+		diagnosticsAccounts = append(diagnosticsAccounts, &diagnosticsAccount{
+			Code:         account.Code(),
+			CoinCode:     account.Coin().Code(),
+			Name:         account.Name(),
+			Initialized:  account.Initialized(),
+			SyncProgress: account.SyncProgress(),
+			Offline:      account.Offline(),
+			FatalError:   account.FatalError(),
+		})
+		if account.Offline() {
+			status.OfflineAccounts = append(status.OfflineAccounts, account.Code())
+		}
+	}
+	accountsJSON, err := json.MarshalIndent(diagnosticsAccounts, "", "  ")
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	statusJSON, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	log, err := logTail()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	zipWriter := zip.NewWriter(&buf)
+	for _, section := range []struct {
+		name string
+		data []byte
+	}{
+		{"config.json", appConfig},
+		{"accounts.json", accountsJSON},
+		{"status.json", statusJSON},
+		{"log.txt", log},
+	} {
+		writer, err := zipWriter.Create(section.name)
+		if err != nil {
+			return nil, errp.WithStack(err)
+		}
+		if _, err := writer.Write(redactXPubs(redactSecrets(section.data, secrets))); err != nil {
+			return nil, errp.WithStack(err)
+		}
+	}
+	if err := zipWriter.Close(); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,124 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/config"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/rates"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/socksproxy"
+	"github.com/stretchr/testify/require"
+)
+
+// Master xpub of BIP32 test vector 1.
+const diagnosticsTestXPub = "xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ29ESFjqJoCu1Rupje8YtGqsefD265TMg7usUDFdp6W1EGMcet8"
+
+const diagnosticsTestNodeURL = "https://mainnet.infura.io/v3/0123456789abcdef0123456789abcdef"
+
+type diagnosticsTestAccount struct {
+	*portfolioTestAccount
+	offline bool
+}
+
+func (account *diagnosticsTestAccount) Initialized() bool     { return true }
+func (account *diagnosticsTestAccount) SyncProgress() float64 { return 1 }
+func (account *diagnosticsTestAccount) Offline() bool         { return account.offline }
+func (account *diagnosticsTestAccount) Close()                {}
+
+func TestRedactXPubs(t *testing.T) {
+	require.Equal(t,
+		"xpub: <redacted xpub>, tpub: <redacted xpub>.",
+		string(redactXPubs([]byte(
+			"xpub: "+diagnosticsTestXPub+", tpub: tpubD6NzVbkrYhZ4XgiXtGrdW5XDAPFCL9h7we1vwNCpn8tGbBcgfVYjXyhWo4E1xkh56hjod1RhGjxbaTLV3X4FyWuejifB9jusQ46QzG87VKp.",
+		))))
+	require.Equal(t, "xpub", string(redactXPubs([]byte("xpub"))))
+}
+
+func TestExportDiagnostics(t *testing.T) {
+	backend := newTestBackend(t)
+	defer func() { require.NoError(t, backend.Close()) }()
+	backend.OnAccountUninit(func(accounts.Interface) {})
+	// No rates can be fetched.
+	backend.ratesUpdater.Stop()
+	backend.ratesUpdater = rates.NewRateUpdater(socksproxy.NewSocksProxy(true, "127.0.0.1:1"))
+
+	btcCoin, err := backend.Coin(coinTBTC)
+	require.NoError(t, err)
+	backend.accounts = append(backend.accounts,
+		&diagnosticsTestAccount{
+			portfolioTestAccount: &portfolioTestAccount{
+				coin: btcCoin, code: "tbtc-p2wpkh", name: "Bitcoin " + diagnosticsTestXPub,
+			},
+			offline: true,
+		},
+	)
+	backend.log.Info("xpub " + diagnosticsTestXPub)
+
+	appConfig := backend.config.AppConfig()
+	appConfig.Backend.ETH.NodeURL = diagnosticsTestNodeURL
+	appConfig.Backend.Proxy.ProxyAddress = "10.1.2.3:9050"
+	require.NoError(t, backend.config.SetAppConfig(appConfig))
+	backend.log.Info("connecting to " + diagnosticsTestNodeURL)
+
+	diagnostics, err := backend.ExportDiagnostics()
+	require.NoError(t, err)
+	zipReader, err := zip.NewReader(bytes.NewReader(diagnostics), int64(len(diagnostics)))
+	require.NoError(t, err)
+	sections := map[string][]byte{}
+	for _, file := range zipReader.File {
+		reader, err := file.Open()
+		require.NoError(t, err)
+		data, err := ioutil.ReadAll(reader)
+		require.NoError(t, err)
+		require.NoError(t, reader.Close())
+		sections[file.Name] = data
+		require.NotContains(t, string(data), diagnosticsTestXPub)
+		require.NotContains(t, string(data), "0123456789abcdef")
+		require.NotContains(t, string(data), "10.1.2.3")
+	}
+	require.Len(t, sections, 4)
+	require.Contains(t, sections, "config.json")
+	require.Contains(t, sections, "log.txt")
+
+	var configSection config.AppConfig
+	require.NoError(t, json.Unmarshal(sections["config.json"], &configSection))
+	require.Equal(t, redactedValue, configSection.Backend.ETH.NodeURL)
+	require.Equal(t, redactedValue, configSection.Backend.Proxy.ProxyAddress)
+	// The app config itself is not modified.
+	require.Equal(t, diagnosticsTestNodeURL, backend.config.AppConfig().Backend.ETH.NodeURL)
+
+	var accountsSection []*diagnosticsAccount
+	require.NoError(t, json.Unmarshal(sections["accounts.json"], &accountsSection))
+	require.Equal(t, []*diagnosticsAccount{{
+		Code:         "tbtc-p2wpkh",
+		CoinCode:     "tbtc",
+		Name:         "Bitcoin <redacted xpub>",
+		Initialized:  true,
+		SyncProgress: 1,
+		Offline:      true,
+	}}, accountsSection)
+
+	var status diagnosticsStatus
+	require.NoError(t, json.Unmarshal(sections["status.json"], &status))
+	require.True(t, status.Testing)
+	require.False(t, status.RatesAvailable)
+	require.Equal(t, []string{"tbtc-p2wpkh"}, status.OfflineAccounts)
+}
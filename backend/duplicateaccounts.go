@@ -0,0 +1,93 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/config"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// duplicateAccountKey identifies the persisted accounts which are the same account: same coin and
+// same signing configuration (xpubs, keypath, script type).
+func duplicateAccountKey(account config.Account) string {
+	return account.CoinCode + "-" + account.Configuration.Hash()
+}
+
+// FindDuplicateAccounts returns groups of persisted accounts of the same coin which share the same
+// signing configuration. Each group contains at least two accounts, in the order in which they were
+// added.
+func (backend *Backend) FindDuplicateAccounts() [][]config.Account {
+	groups := map[string][]config.Account{}
+	keys := []string{}
+	for _, account := range backend.config.AccountsConfig().Accounts {
+		key := duplicateAccountKey(account)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], account)
+	}
+	result := [][]config.Account{}
+	for _, key := range keys {
+		if len(groups[key]) > 1 {
+			result = append(result, groups[key])
+		}
+	}
+	return result
+}
+
+// MergeAccounts removes the persisted accounts with the given codes, which must be duplicates of
+// the primary account. The primary account keeps its code and name.
+func (backend *Backend) MergeAccounts(primaryCode string, otherCodes ...string) error {
+	accountsConfig := backend.config.AccountsConfig()
+	var primary *config.Account
+	for index := range accountsConfig.Accounts {
+		if accountsConfig.Accounts[index].Code == primaryCode {
+			primary = &accountsConfig.Accounts[index]
+			break
+		}
+	}
+	if primary == nil {
+		return errp.Newf("unknown account %s", primaryCode)
+	}
+	remove := map[string]struct{}{}
+	for _, code := range otherCodes {
+		if code == primaryCode {
+			return errp.Newf("cannot merge account %s into itself", code)
+		}
+		remove[code] = struct{}{}
+	}
+	accounts := []config.Account{}
+	for _, account := range accountsConfig.Accounts {
+		if _, ok := remove[account.Code]; !ok {
+			accounts = append(accounts, account)
+			continue
+		}
+		if duplicateAccountKey(account) != duplicateAccountKey(*primary) {
+			return errp.Newf("account %s is not a duplicate of %s", account.Code, primaryCode)
+		}
+		delete(remove, account.Code)
+	}
+	for code := range remove {
+		return errp.Newf("unknown account %s", code)
+	}
+	accountsConfig.Accounts = accounts
+	if err := backend.config.SetAccountsConfig(accountsConfig); err != nil {
+		return err
+	}
+	for _, code := range otherCodes {
+		backend.removeAccount(code)
+	}
+	return nil
+}
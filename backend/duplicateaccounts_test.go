@@ -0,0 +1,74 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/config"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
+	"github.com/stretchr/testify/require"
+)
+
+func testConfiguration(t *testing.T, seed byte) *signing.Configuration {
+	t.Helper()
+	xprv, err := hdkeychain.NewMaster([]byte{seed, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+		&chaincfg.MainNetParams)
+	require.NoError(t, err)
+	xpub, err := xprv.Neuter()
+	require.NoError(t, err)
+	keypath, err := signing.NewAbsoluteKeypath("m/84'/0'/0'")
+	require.NoError(t, err)
+	return signing.NewSinglesigConfiguration(signing.ScriptTypeP2WPKH, keypath, xpub)
+}
+
+func TestMergeAccounts(t *testing.T) {
+	backend := newTestBackend(t)
+	defer func() { require.NoError(t, backend.Close()) }()
+
+	// Mainnet accounts are not loaded in testing mode, so only the persisted config is affected.
+	account1 := config.Account{CoinCode: coinBTC, Code: "account-1", Name: "Account 1",
+		Configuration: testConfiguration(t, 1)}
+	account2 := config.Account{CoinCode: coinBTC, Code: "account-2", Name: "Account 2",
+		Configuration: testConfiguration(t, 2)}
+	account1Copy := config.Account{CoinCode: coinBTC, Code: "account-1-copy", Name: "Copy",
+		Configuration: testConfiguration(t, 1)}
+	// Same configuration, but a different coin.
+	account1LTC := config.Account{CoinCode: coinLTC, Code: "account-1-ltc", Name: "Litecoin",
+		Configuration: testConfiguration(t, 1)}
+	accountsConfig := backend.config.AccountsConfig()
+	accountsConfig.Accounts = []config.Account{account1, account2, account1Copy, account1LTC}
+	require.NoError(t, backend.config.SetAccountsConfig(accountsConfig))
+
+	require.Equal(t,
+		[][]config.Account{{account1, account1Copy}},
+		backend.FindDuplicateAccounts())
+
+	require.Error(t, backend.MergeAccounts("unknown", "account-1-copy"))
+	require.Error(t, backend.MergeAccounts("account-1", "unknown"))
+	require.Error(t, backend.MergeAccounts("account-1", "account-1"))
+	require.Error(t, backend.MergeAccounts("account-1", "account-2"))
+	require.Error(t, backend.MergeAccounts("account-1", "account-1-ltc"))
+	// Nothing was changed by the failed merges.
+	require.Len(t, backend.config.AccountsConfig().Accounts, 4)
+
+	require.NoError(t, backend.MergeAccounts("account-1", "account-1-copy"))
+	require.Equal(t,
+		[]config.Account{account1, account2, account1LTC},
+		backend.config.AccountsConfig().Accounts)
+	require.Empty(t, backend.FindDuplicateAccounts())
+}
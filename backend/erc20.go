@@ -14,7 +14,17 @@
 
 package backend
 
-import "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/erc20"
+import (
+	"sort"
+	"strings"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/erc20"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// erc20CodePrefix is the prefix of the coin codes of ERC20 tokens. The active tokens are stored in
+// the config without it.
+const erc20CodePrefix = "eth-erc20-"
 
 type erc20Token struct {
 	code  string
@@ -85,3 +95,45 @@ func erc20TokenByCode(code string) *erc20Token {
 	}
 	return nil
 }
+
+// SetTokensActive activates or deactivates ERC20 tokens, keyed by their coin code, e.g.
+// "eth-erc20-usdt". All changes are persisted at once and the accounts are reinitialized only once,
+// instead of once per token. Nothing is changed if one of the codes is unknown.
+func (backend *Backend) SetTokensActive(changes map[string]bool) error {
+	for code := range changes {
+		if erc20TokenByCode(code) == nil {
+			return errp.Newf("unknown token %s", code)
+		}
+	}
+	appConfig := backend.config.AppConfig()
+	changed := false
+	activeTokens := []string{}
+	for _, tokenCode := range appConfig.Backend.ETH.ActiveERC20Tokens {
+		if active, ok := changes[erc20CodePrefix+tokenCode]; ok && !active {
+			changed = true
+			continue
+		}
+		activeTokens = append(activeTokens, tokenCode)
+	}
+	codes := make([]string, 0, len(changes))
+	for code := range changes {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		tokenCode := strings.TrimPrefix(code, erc20CodePrefix)
+		if changes[code] && !appConfig.Backend.ETH.ERC20TokenActive(tokenCode) {
+			activeTokens = append(activeTokens, tokenCode)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	appConfig.Backend.ETH.ActiveERC20Tokens = activeTokens
+	if err := backend.config.SetAppConfig(appConfig); err != nil {
+		return err
+	}
+	backend.ReinitializeAccounts()
+	return nil
+}
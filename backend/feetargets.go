@@ -0,0 +1,83 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"math/big"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// FeeTarget is an estimated fee rate of a coin, see FeeTargets().
+type FeeTarget struct {
+	// Code identifies the fee target. It is empty for the custom fee rate option.
+	Code accounts.FeeTargetCode `json:"code"`
+	// Custom is true for the option to choose the fee rate manually. FeeRate is then the lowest
+	// rate which can be chosen.
+	Custom bool `json:"custom"`
+	// Blocks is the number of blocks in which a tx paying this fee rate is expected to be
+	// confirmed. Only set for BTC and LTC.
+	Blocks int `json:"blocks,omitempty"`
+	// FeeRate is in sat/vbyte for BTC and LTC, and the gas price in Gwei for ETH.
+	FeeRate float64 `json:"feeRate"`
+}
+
+// FeeTargets estimates the fee rates of the coin with the given code, sorted by ascending priority
+// and followed by the custom fee rate option. For BTC and LTC, the fee rates for several
+// confirmation targets are queried from the coin's blockchain backend. For ETH and ERC20 tokens,
+// the fee targets are gas price tiers. This blocks until the estimates are available.
+func (backend *Backend) FeeTargets(coinCode string) ([]FeeTarget, error) {
+	theCoin, err := backend.Coin(coinCode)
+	if err != nil {
+		return nil, err
+	}
+	theCoin.Initialize()
+	result := []FeeTarget{}
+	switch specificCoin := theCoin.(type) {
+	case *btc.Coin:
+		estimates, minFeeRatePerKb := btc.EstimateFeeRates(specificCoin.Blockchain())
+		for _, estimate := range estimates {
+			result = append(result, FeeTarget{
+				Code:    estimate.Code,
+				Blocks:  estimate.Blocks,
+				FeeRate: float64(estimate.FeeRatePerKb) / 1000,
+			})
+		}
+		result = append(result, FeeTarget{Custom: true, FeeRate: float64(minFeeRatePerKb) / 1000})
+	case *eth.Coin:
+		estimates, err := specificCoin.EstimateGasPrices()
+		if err != nil {
+			return nil, err
+		}
+		for _, estimate := range estimates {
+			result = append(result, FeeTarget{
+				Code:    estimate.Code,
+				FeeRate: weiToGwei(estimate.GasPrice),
+			})
+		}
+		result = append(result, FeeTarget{Custom: true, FeeRate: 0})
+	default:
+		return nil, errp.Newf("cannot estimate fees of coin %s", coinCode)
+	}
+	return result, nil
+}
+
+func weiToGwei(wei *big.Int) float64 {
+	gwei, _ := new(big.Float).Quo(new(big.Float).SetInt(wei), big.NewFloat(1e9)).Float64()
+	return gwei
+}
@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"math/big"
 	"net/http"
 	"os"
@@ -32,6 +33,7 @@ import (
 	"github.com/btcsuite/btcutil/hdkeychain"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/errors"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/banners"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/bitboxbase"
 	baseHandlers "github.com/digitalbitbox/bitbox-wallet-app/backend/bitboxbase/handlers"
@@ -72,8 +74,12 @@ type Backend interface {
 	DefaultAppConfig() config.AppConfig
 	Coin(string) (coin.Coin, error)
 	Testing() bool
+	SetTesting(testing bool) error
 	Accounts() []accounts.Interface
+	SupportedCoins() []string
+	CostBasisReport(accountCode string, fiat string, method string) (*accounts.CostBasisReport, error)
 	Keystores() *keystore.Keystores
+	KeystoreStatus() backend.KeystoreStatus
 	CreateAndAddAccount(
 		coin coin.Coin,
 		code string,
@@ -82,6 +88,10 @@ type Backend interface {
 		persist bool,
 		emitEvent bool,
 	) error
+	AccountMetadata(accountCode string) map[string]string
+	AccountHidden(account accounts.Interface) bool
+	UpdateAccountsHidden()
+	AccountInTotals(account accounts.Interface) bool
 	UserLanguage() language.Tag
 	OnAccountInit(f func(accounts.Interface))
 	OnAccountUninit(f func(accounts.Interface))
@@ -102,13 +112,23 @@ type Backend interface {
 	BitBoxBaseDeregister(bitboxBaseID string)
 	DownloadCert(string) (string, error)
 	CheckElectrumServer(*config.ServerInfo) error
+	CheckProxy(proxyAddress string) error
+	BroadcastRaw(coinCode string, rawTxHex string) (string, error)
+	AddressBalance(coinCode string, address string) (*big.Int, error)
 	RegisterTestKeystore(string)
+	ImportMnemonic(mnemonic string, passphrase string) error
 	NotifyUser(string)
 	SystemOpen(string) error
 	ReinitializeAccounts()
+	SetTokensActive(changes map[string]bool) error
+	FindDuplicateAccounts() [][]config.Account
+	MergeAccounts(primaryCode string, otherCodes ...string) error
 	CheckForUpdateIgnoringErrors() *backend.UpdateFile
 	Banners() *banners.Banners
 	Environment() backend.Environment
+	FeeTargets(coinCode string) ([]backend.FeeTarget, error)
+	ExportPortfolio(writer io.Writer, format string) error
+	ExportDiagnostics() ([]byte, error)
 }
 
 // Handlers provides a web api to the backend.
@@ -184,23 +204,37 @@ func NewHandlers(
 	getAPIRouter(apiRouter)("/using-mobile-data", handlers.getUsingMobileDataHandler).Methods("GET")
 	getAPIRouter(apiRouter)("/version", handlers.getVersionHandler).Methods("GET")
 	getAPIRouter(apiRouter)("/testing", handlers.getTestingHandler).Methods("GET")
+	getAPIRouter(apiRouter)("/testing", handlers.postTestingHandler).Methods("POST")
 	getAPIRouter(apiRouter)("/account-add", handlers.postAddAccountHandler).Methods("POST")
+	getAPIRouter(apiRouter)("/supported-coins", handlers.getSupportedCoinsHandler).Methods("GET")
 	getAPIRouter(apiRouter)("/keystores", handlers.getKeystoresHandler).Methods("GET")
+	getAPIRouter(apiRouter)("/keystore", handlers.getKeystoreStatusHandler).Methods("GET")
 	getAPIRouter(apiRouter)("/accounts", handlers.getAccountsHandler).Methods("GET")
 	getAPIRouter(apiRouter)("/accounts/reinitialize", handlers.postAccountsReinitializeHandler).Methods("POST")
+	getAPIRouter(apiRouter)("/accounts/duplicates", handlers.getDuplicateAccountsHandler).Methods("GET")
+	getAPIRouter(apiRouter)("/accounts/merge", handlers.postMergeAccountsHandler).Methods("POST")
+	getAPIRouter(apiRouter)("/tokens/set-active", handlers.postSetTokensActiveHandler).Methods("POST")
 	getAPIRouter(apiRouter)("/export-account-summary", handlers.postExportAccountSummary).Methods("POST")
+	getAPIRouter(apiRouter)("/export-portfolio", handlers.postExportPortfolio).Methods("POST")
+	getAPIRouter(apiRouter)("/export-diagnostics", handlers.postExportDiagnostics).Methods("POST")
 	getAPIRouter(apiRouter)("/account-summary", handlers.getAccountSummary).Methods("GET")
+	getAPIRouter(apiRouter)("/cost-basis", handlers.getCostBasisHandler).Methods("GET")
 	getAPIRouter(apiRouter)("/test/register", handlers.postRegisterTestKeystoreHandler).Methods("POST")
 	getAPIRouter(apiRouter)("/test/deregister", handlers.postDeregisterTestKeystoreHandler).Methods("POST")
+	getAPIRouter(apiRouter)("/test/import-mnemonic", handlers.postImportMnemonicHandler).Methods("POST")
 	getAPIRouter(apiRouter)("/rates", handlers.getRatesHandler).Methods("GET")
 	getAPIRouter(apiRouter)("/coins/convertToFiat", handlers.getConvertToFiatHandler).Methods("GET")
 	getAPIRouter(apiRouter)("/coins/convertFromFiat", handlers.getConvertFromFiatHandler).Methods("GET")
+	getAPIRouter(apiRouter)("/coins/{code}/fee-targets", handlers.getFeeTargetsHandler).Methods("GET")
 	getAPIRouter(apiRouter)("/coins/tltc/headers/status", handlers.getHeadersStatus("tltc")).Methods("GET")
 	getAPIRouter(apiRouter)("/coins/tbtc/headers/status", handlers.getHeadersStatus("tbtc")).Methods("GET")
 	getAPIRouter(apiRouter)("/coins/ltc/headers/status", handlers.getHeadersStatus("ltc")).Methods("GET")
 	getAPIRouter(apiRouter)("/coins/btc/headers/status", handlers.getHeadersStatus("btc")).Methods("GET")
 	getAPIRouter(apiRouter)("/certs/download", handlers.postCertsDownloadHandler).Methods("POST")
 	getAPIRouter(apiRouter)("/electrum/check", handlers.postElectrumCheckHandler).Methods("POST")
+	getAPIRouter(apiRouter)("/socksproxy/check", handlers.postSocksProxyCheckHandler).Methods("POST")
+	getAPIRouter(apiRouter)("/broadcast-raw", handlers.postBroadcastRawHandler).Methods("POST")
+	getAPIRouter(apiRouter)("/address-balance", handlers.getAddressBalanceHandler).Methods("GET")
 	getAPIRouter(apiRouter)("/bitboxbases/establish-connection", handlers.postEstablishConnectionHandler).Methods("POST")
 
 	devicesRouter := getAPIRouter(apiRouter.PathPrefix("/devices").Subrouter())
@@ -352,7 +386,12 @@ func (handlers *Handlers) postAppConfigHandler(r *http.Request) (interface{}, er
 	if err := json.NewDecoder(r.Body).Decode(&appConfig); err != nil {
 		return nil, errp.WithStack(err)
 	}
-	return nil, handlers.backend.Config().SetAppConfig(appConfig)
+	if err := handlers.backend.Config().SetAppConfig(appConfig); err != nil {
+		return nil, err
+	}
+	// The small balances threshold might have changed.
+	handlers.backend.UpdateAccountsHidden()
+	return nil, nil
 }
 
 func (handlers *Handlers) postNotifyHandler(r *http.Request) (interface{}, error) {
@@ -394,6 +433,26 @@ func (handlers *Handlers) getTestingHandler(_ *http.Request) (interface{}, error
 	return handlers.backend.Testing(), nil
 }
 
+func (handlers *Handlers) postTestingHandler(r *http.Request) (interface{}, error) {
+	var testing bool
+	if err := json.NewDecoder(r.Body).Decode(&testing); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	if err := handlers.backend.SetTesting(testing); err != nil {
+		return map[string]interface{}{
+			"success":      false,
+			"errorMessage": err.Error(),
+		}, nil
+	}
+	return map[string]interface{}{
+		"success": true,
+	}, nil
+}
+
+func (handlers *Handlers) getSupportedCoinsHandler(_ *http.Request) (interface{}, error) {
+	return handlers.backend.SupportedCoins(), nil
+}
+
 func (handlers *Handlers) postAddAccountHandler(r *http.Request) (interface{}, error) {
 	jsonBody := map[string]string{}
 	if err := json.NewDecoder(r.Body).Decode(&jsonBody); err != nil {
@@ -494,23 +553,20 @@ func (handlers *Handlers) getKeystoresHandler(_ *http.Request) (interface{}, err
 	return keystores, nil
 }
 
+func (handlers *Handlers) getKeystoreStatusHandler(_ *http.Request) (interface{}, error) {
+	return handlers.backend.KeystoreStatus(), nil
+}
+
 func (handlers *Handlers) getAccountsHandler(_ *http.Request) (interface{}, error) {
-	type accountJSON struct {
-		CoinCode              string `json:"coinCode"`
-		CoinUnit              string `json:"coinUnit"`
-		Code                  string `json:"code"`
-		Name                  string `json:"name"`
-		BlockExplorerTxPrefix string `json:"blockExplorerTxPrefix"`
-	}
-	accounts := []*accountJSON{}
+	// Accounts with a small balance are listed too, so their routes keep working, but are
+	// flagged as hidden.
+	accounts := []*backend.AccountJSON{}
 	for _, account := range handlers.backend.Accounts() {
-		accounts = append(accounts, &accountJSON{
-			CoinCode:              account.Coin().Code(),
-			CoinUnit:              account.Coin().Unit(false),
-			Code:                  account.Code(),
-			Name:                  account.Name(),
-			BlockExplorerTxPrefix: account.Coin().BlockExplorerTransactionURLPrefix(),
-		})
+		accounts = append(accounts, backend.NewAccountJSON(
+			account,
+			handlers.backend.AccountMetadata(account.Code()),
+			handlers.backend.AccountHidden(account),
+		))
 	}
 	return accounts, nil
 }
@@ -520,6 +576,54 @@ func (handlers *Handlers) postAccountsReinitializeHandler(_ *http.Request) (inte
 	return nil, nil
 }
 
+func (handlers *Handlers) postSetTokensActiveHandler(r *http.Request) (interface{}, error) {
+	var changes map[string]bool
+	if err := json.NewDecoder(r.Body).Decode(&changes); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return nil, handlers.backend.SetTokensActive(changes)
+}
+
+func (handlers *Handlers) getDuplicateAccountsHandler(_ *http.Request) (interface{}, error) {
+	type accountJSON struct {
+		CoinCode string `json:"coinCode"`
+		Code     string `json:"code"`
+		Name     string `json:"name"`
+	}
+	groups := [][]*accountJSON{}
+	for _, duplicates := range handlers.backend.FindDuplicateAccounts() {
+		group := []*accountJSON{}
+		for _, account := range duplicates {
+			group = append(group, &accountJSON{
+				CoinCode: account.CoinCode,
+				Code:     account.Code,
+				Name:     account.Name,
+			})
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+func (handlers *Handlers) postMergeAccountsHandler(r *http.Request) (interface{}, error) {
+	var jsonBody struct {
+		Primary string   `json:"primary"`
+		Others  []string `json:"others"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&jsonBody); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	if err := handlers.backend.MergeAccounts(jsonBody.Primary, jsonBody.Others...); err != nil {
+		return map[string]interface{}{
+			"success":      false,
+			"errorMessage": err.Error(),
+		}, nil
+	}
+	return map[string]interface{}{
+		"success": true,
+	}, nil
+}
+
 func (handlers *Handlers) getDevicesRegisteredHandler(_ *http.Request) (interface{}, error) {
 	jsonDevices := map[string]string{}
 	for deviceID, device := range handlers.backend.DevicesRegistered() {
@@ -557,6 +661,21 @@ func (handlers *Handlers) postRegisterTestKeystoreHandler(r *http.Request) (inte
 	return nil, nil
 }
 
+func (handlers *Handlers) postImportMnemonicHandler(r *http.Request) (interface{}, error) {
+	// Only for testing, as the keys are not protected by a device.
+	if !handlers.backend.Testing() {
+		return nil, errp.New("Mnemonic import not available")
+	}
+	jsonBody := map[string]string{}
+	if err := json.NewDecoder(r.Body).Decode(&jsonBody); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	if err := handlers.backend.ImportMnemonic(jsonBody["mnemonic"], jsonBody["passphrase"]); err != nil {
+		return map[string]interface{}{"success": false, "errorMessage": err.Error()}, nil
+	}
+	return map[string]interface{}{"success": true}, nil
+}
+
 func (handlers *Handlers) postDeregisterTestKeystoreHandler(_ *http.Request) (interface{}, error) {
 	handlers.backend.DeregisterKeystore()
 	return nil, nil
@@ -630,6 +749,10 @@ func (handlers *Handlers) getHeadersStatus(coinCode string) func(*http.Request)
 	}
 }
 
+func (handlers *Handlers) getFeeTargetsHandler(r *http.Request) (interface{}, error) {
+	return handlers.backend.FeeTargets(mux.Vars(r)["code"])
+}
+
 func (handlers *Handlers) postCertsDownloadHandler(r *http.Request) (interface{}, error) {
 	var server string
 	if err := json.NewDecoder(r.Body).Decode(&server); err != nil {
@@ -648,6 +771,69 @@ func (handlers *Handlers) postCertsDownloadHandler(r *http.Request) (interface{}
 	}, nil
 }
 
+func (handlers *Handlers) postBroadcastRawHandler(r *http.Request) (interface{}, error) {
+	var input struct {
+		CoinCode string `json:"coinCode"`
+		RawTx    string `json:"rawTx"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	txID, err := handlers.backend.BroadcastRaw(input.CoinCode, input.RawTx)
+	if err != nil {
+		if validationErr, ok := errp.Cause(err).(errors.TxValidationError); ok {
+			return map[string]interface{}{
+				"success":   false,
+				"errorCode": validationErr.Error(),
+			}, nil
+		}
+		return map[string]interface{}{
+			"success":      false,
+			"errorMessage": err.Error(),
+		}, nil
+	}
+	return map[string]interface{}{
+		"success": true,
+		"txID":    txID,
+	}, nil
+}
+
+func (handlers *Handlers) getAddressBalanceHandler(r *http.Request) (interface{}, error) {
+	coinCode := r.URL.Query().Get("coinCode")
+	coinInstance, err := handlers.backend.Coin(coinCode)
+	if err != nil {
+		return nil, err
+	}
+	balance, err := handlers.backend.AddressBalance(coinCode, r.URL.Query().Get("address"))
+	if errp.Cause(err) == errors.ErrInvalidAddress {
+		return map[string]interface{}{"success": false, "errorCode": "invalidAddress"}, nil
+	}
+	if err != nil {
+		return map[string]interface{}{"success": false, "errorMessage": err.Error()}, nil
+	}
+	return map[string]interface{}{
+		"success": true,
+		"balance": handlers.formatAmountAsJSON(coin.NewAmount(balance), coinInstance, false),
+	}, nil
+}
+
+func (handlers *Handlers) postSocksProxyCheckHandler(r *http.Request) (interface{}, error) {
+	var proxyAddress string
+	if err := json.NewDecoder(r.Body).Decode(&proxyAddress); err != nil {
+		return nil, errp.WithStack(err)
+	}
+
+	if err := handlers.backend.CheckProxy(proxyAddress); err != nil {
+		return map[string]interface{}{
+			"success":      false,
+			"errorMessage": err.Error(),
+		}, nil
+	}
+	return map[string]interface{}{
+		"success": true,
+	}, nil
+}
+
 func (handlers *Handlers) postElectrumCheckHandler(r *http.Request) (interface{}, error) {
 	var serverInfo config.ServerInfo
 	if err := json.NewDecoder(r.Body).Decode(&serverInfo); err != nil {
@@ -809,6 +995,9 @@ func (handlers *Handlers) getAccountSummary(_ *http.Request) (interface{}, error
 			},
 		})
 
+		if !handlers.backend.AccountInTotals(account) {
+			continue
+		}
 		_, ok := totals[account.Coin()]
 		if !ok {
 			totals[account.Coin()] = new(big.Int)
@@ -828,6 +1017,108 @@ func (handlers *Handlers) getAccountSummary(_ *http.Request) (interface{}, error
 	}, nil
 }
 
+func (handlers *Handlers) getCostBasisHandler(r *http.Request) (interface{}, error) {
+	accountCode := r.URL.Query().Get("accountCode")
+	fiat := r.URL.Query().Get("fiat")
+	method := r.URL.Query().Get("method")
+	if method == "" {
+		method = string(accounts.CostBasisFIFO)
+	}
+	report, err := handlers.backend.CostBasisReport(accountCode, fiat, method)
+	if err != nil {
+		return map[string]interface{}{"success": false, "errorMessage": err.Error()}, nil
+	}
+	var accountCoin coin.Coin
+	for _, account := range handlers.backend.Accounts() {
+		if account.Code() == accountCode {
+			accountCoin = account.Coin()
+		}
+	}
+	if accountCoin == nil {
+		return nil, errp.Newf("unknown account %s", accountCode)
+	}
+	type disposalJSON struct {
+		TxID      string                          `json:"txID"`
+		Time      string                          `json:"time"`
+		Amount    accountHandlers.FormattedAmount `json:"amount"`
+		Proceeds  float64                         `json:"proceeds"`
+		CostBasis float64                         `json:"costBasis"`
+		Gain      float64                         `json:"gain"`
+	}
+	disposals := []disposalJSON{}
+	for _, disposal := range report.Disposals {
+		disposals = append(disposals, disposalJSON{
+			TxID:      disposal.TxID,
+			Time:      disposal.Time.Format(time.RFC3339),
+			Amount:    handlers.formatAmountAsJSON(disposal.Amount, accountCoin, false),
+			Proceeds:  disposal.Proceeds,
+			CostBasis: disposal.CostBasis,
+			Gain:      disposal.Gain,
+		})
+	}
+	return map[string]interface{}{
+		"success":   true,
+		"fiat":      fiat,
+		"method":    report.Method,
+		"disposals": disposals,
+		"proceeds":  report.Proceeds,
+		"costBasis": report.CostBasis,
+		"gain":      report.Gain,
+	}, nil
+}
+
+func (handlers *Handlers) postExportPortfolio(r *http.Request) (interface{}, error) {
+	var format string
+	if err := json.NewDecoder(r.Body).Decode(&format); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	if format != backend.PortfolioFormatCSV && format != backend.PortfolioFormatJSON {
+		return nil, errp.Newf("unknown export format %s", format)
+	}
+	name := time.Now().Format("2006-01-02-at-15-04-05-") + "Portfolio." + format
+	downloadsDir, err := utilConfig.DownloadsDir()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(downloadsDir, name)
+	handlers.log.Infof("Export portfolio %s.", path)
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	exportErr := handlers.backend.ExportPortfolio(file, format)
+	if err := file.Close(); err != nil && exportErr == nil {
+		exportErr = errp.WithStack(err)
+	}
+	if exportErr != nil {
+		// Don't leave a partially written file behind.
+		if err := os.Remove(path); err != nil {
+			handlers.log.WithError(err).Error("Could not remove the incomplete portfolio file.")
+		}
+		return nil, exportErr
+	}
+	return path, nil
+}
+
+func (handlers *Handlers) postExportDiagnostics(_ *http.Request) (interface{}, error) {
+	diagnostics, err := handlers.backend.ExportDiagnostics()
+	if err != nil {
+		return nil, err
+	}
+	name := time.Now().Format("2006-01-02-at-15-04-05-") + "Diagnostics.zip"
+	downloadsDir, err := utilConfig.DownloadsDir()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(downloadsDir, name)
+	handlers.log.Infof("Export diagnostics %s.", path)
+	if err := ioutil.WriteFile(path, diagnostics, 0600); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return path, nil
+}
+
 func (handlers *Handlers) postExportAccountSummary(_ *http.Request) (interface{}, error) {
 	name := time.Now().Format("2006-01-02-at-15-04-05-") + "Accounts-Summary.csv"
 	downloadsDir, err := utilConfig.DownloadsDir()
@@ -36,6 +36,10 @@ const (
 // ErrSigningAborted is used when the user aborts a signing in process (e.g. abort on HW wallet).
 var ErrSigningAborted = errors.New("signing aborted by user")
 
+// ErrNoKeystore is used when signing is requested but no keystore is registered, e.g. for an
+// account loaded while no device is connected.
+var ErrNoKeystore = errors.New("no keystore to sign with")
+
 // Keystore supports hardened key derivation according to BIP32 and signing of transactions.
 type Keystore interface {
 	// Type denotes the type of the keystore.
@@ -74,4 +78,10 @@ type Keystore interface {
 	// SignTransaction signs the given transaction proposal. Returns ErrSigningAborted if the user
 	// aborts.
 	SignTransaction(interface{}) error
+
+	// SignETHMessage signs the message using the personal_sign scheme, i.e. the message is prefixed
+	// with "\x19Ethereum Signed Message:\n" + len(message) before hashing. The result is the 65 byte
+	// signature [R || S || V], where V is the recovery ID (0 or 1). Returns ErrSigningAborted if the
+	// user aborts.
+	SignETHMessage(coin coin.Coin, keypath signing.AbsoluteKeypath, message []byte) ([]byte, error)
 }
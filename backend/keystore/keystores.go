@@ -117,8 +117,11 @@ func (keystores *Keystores) CanVerifyExtendedPublicKeys() []int {
 }
 
 // SignTransaction signs the given proposed transaction on all keystores. Returns ErrSigningAborted
-// if the user aborts.
+// if the user aborts, and ErrNoKeystore if there are no keystores.
 func (keystores *Keystores) SignTransaction(proposedTransaction interface{}) error {
+	if len(keystores.keystores) == 0 {
+		return errp.WithStack(ErrNoKeystore)
+	}
 	for _, keystore := range keystores.keystores {
 		if err := keystore.SignTransaction(proposedTransaction); err != nil {
 			return err
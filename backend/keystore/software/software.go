@@ -17,6 +17,7 @@ package software
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"strings"
 
 	"github.com/btcsuite/btcd/btcec"
 	"github.com/btcsuite/btcd/chaincfg"
@@ -28,8 +29,12 @@ import (
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/logging"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/sirupsen/logrus"
+	"github.com/tyler-smith/go-bip39"
 	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/text/unicode/norm"
 )
 
 // Keystore implements a keystore in software.
@@ -66,6 +71,22 @@ func NewKeystoreFromPIN(cosignerIndex int, pin string) *Keystore {
 	return NewKeystore(cosignerIndex, master)
 }
 
+// NewKeystoreFromMnemonic creates a keystore from a BIP39 mnemonic and optional passphrase. An
+// error is returned if the mnemonic is not a valid English BIP39 mnemonic, e.g. if a word is
+// misspelled or the checksum does not match. The keys are kept in memory, unprotected.
+func NewKeystoreFromMnemonic(cosignerIndex int, mnemonic string, passphrase string) (*Keystore, error) {
+	mnemonic = strings.Join(strings.Fields(norm.NFKD.String(mnemonic)), " ")
+	if _, err := bip39.EntropyFromMnemonic(mnemonic); err != nil {
+		return nil, errp.WithMessage(err, "invalid mnemonic")
+	}
+	seed := bip39.NewSeed(mnemonic, norm.NFKD.String(passphrase))
+	master, err := hdkeychain.NewMaster(seed, &chaincfg.MainNetParams)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return NewKeystore(cosignerIndex, master), nil
+}
+
 // Type implements keystore.Keystore.
 func (keystore *Keystore) Type() keystorePkg.Type {
 	return keystorePkg.TypeSoftware
@@ -209,3 +230,17 @@ func (keystore *Keystore) SignTransaction(
 	}
 	return nil
 }
+
+// SignETHMessage implements keystore.Keystore.
+func (keystore *Keystore) SignETHMessage(
+	coin coin.Coin, keypath signing.AbsoluteKeypath, message []byte) ([]byte, error) {
+	xprv, err := keypath.Derive(keystore.master)
+	if err != nil {
+		return nil, err
+	}
+	prv, err := xprv.ECPrivKey()
+	if err != nil {
+		return nil, err
+	}
+	return crypto.Sign(accounts.TextHash(message), prv.ToECDSA())
+}
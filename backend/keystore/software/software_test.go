@@ -0,0 +1,106 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package software_test
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/keystore/software"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignETHMessage(t *testing.T) {
+	keystore := software.NewKeystoreFromPIN(0, "1234")
+	keypath, err := signing.NewAbsoluteKeypath("m/44'/60'/0'/0/0")
+	require.NoError(t, err)
+	xpub, err := keystore.ExtendedPublicKey(nil, keypath)
+	require.NoError(t, err)
+	publicKey, err := xpub.ECPubKey()
+	require.NoError(t, err)
+	expectedAddress := crypto.PubkeyToAddress(*publicKey.ToECDSA())
+
+	message := []byte("hello")
+	signature, err := keystore.SignETHMessage(nil, keypath, message)
+	require.NoError(t, err)
+	require.Len(t, signature, 65)
+
+	// The message must be prefixed according to personal_sign before hashing.
+	recoveredKey, err := crypto.SigToPub(accounts.TextHash(message), signature)
+	require.NoError(t, err)
+	require.Equal(t, expectedAddress, crypto.PubkeyToAddress(*recoveredKey))
+
+	recoveredKey, err = crypto.SigToPub(crypto.Keccak256(message), signature)
+	require.NoError(t, err)
+	require.NotEqual(t, expectedAddress, crypto.PubkeyToAddress(*recoveredKey))
+}
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon " +
+	"abandon abandon about"
+
+func TestNewKeystoreFromMnemonic(t *testing.T) {
+	// BIP39 test vector.
+	keystore, err := software.NewKeystoreFromMnemonic(0, testMnemonic, "TREZOR")
+	require.NoError(t, err)
+	// Compare a hardened child, as it depends on the private key.
+	childKeypath, err := signing.NewAbsoluteKeypath("m/0'")
+	require.NoError(t, err)
+	xpub, err := keystore.ExtendedPublicKey(nil, childKeypath)
+	require.NoError(t, err)
+	expectedMaster, err := hdkeychain.NewKeyFromString(
+		"xprv9s21ZrQH143K3h3fDYiay8mocZ3afhfULfb5GX8kCBdno77K4HiA15Tg23wpbeF1pLfs1c5SPmYHrEpTuuRhxMwvKDwqdKiGJS9XFKzUsAF")
+	require.NoError(t, err)
+	expectedChild, err := expectedMaster.Child(hdkeychain.HardenedKeyStart)
+	require.NoError(t, err)
+	expectedXPub, err := expectedChild.Neuter()
+	require.NoError(t, err)
+	require.Equal(t, expectedXPub.String(), xpub.String())
+
+	// BIP84 test vector: first receive address, without passphrase. Extra whitespace is ignored.
+	keystore, err = software.NewKeystoreFromMnemonic(0, "  "+testMnemonic+"\n", "")
+	require.NoError(t, err)
+	keypath, err := signing.NewAbsoluteKeypath("m/84'/0'/0'/0/0")
+	require.NoError(t, err)
+	xpub, err = keystore.ExtendedPublicKey(nil, keypath)
+	require.NoError(t, err)
+	publicKey, err := xpub.ECPubKey()
+	require.NoError(t, err)
+	address, err := btcutil.NewAddressWitnessPubKeyHash(
+		btcutil.Hash160(publicKey.SerializeCompressed()), &chaincfg.MainNetParams)
+	require.NoError(t, err)
+	require.Equal(t, "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu", address.EncodeAddress())
+}
+
+func TestNewKeystoreFromMnemonicInvalid(t *testing.T) {
+	for _, mnemonic := range []string{
+		"",
+		// Wrong checksum.
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon " +
+			"abandon",
+		// Not in the wordlist.
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon " +
+			"bitbox",
+		// Wrong number of words.
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+	} {
+		_, err := software.NewKeystoreFromMnemonic(0, mnemonic, "")
+		require.Error(t, err, mnemonic)
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/keystore"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/observable"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/observable/action"
+)
+
+// KeystoreStatus describes the connected keystore. It is sent in the `keystore` observable event
+// whenever a keystore connects or disconnects.
+type KeystoreStatus struct {
+	Connected bool `json:"connected"`
+	// Type is the type of the connected keystore.
+	Type keystore.Type `json:"type,omitempty"`
+	// ProductName is the product name of the device providing the keystore, e.g. "bitbox02". It
+	// is empty for software keystores.
+	ProductName string `json:"productName,omitempty"`
+	// DeviceID identifies the device providing the keystore. It is empty for software keystores.
+	DeviceID string `json:"deviceID,omitempty"`
+}
+
+// KeystoreStatus returns the status of the connected keystore.
+func (backend *Backend) KeystoreStatus() KeystoreStatus {
+	defer backend.keystoreStatusLock.RLock()()
+	return backend.keystoreStatus
+}
+
+// setKeystoreStatus updates the keystore status and notifies the frontend. Nothing is sent if the
+// status did not change, e.g. if the same device reports its keystore again.
+func (backend *Backend) setKeystoreStatus(status KeystoreStatus) {
+	changed := func() bool {
+		defer backend.keystoreStatusLock.Lock()()
+		if backend.keystoreStatus == status {
+			return false
+		}
+		backend.keystoreStatus = status
+		return true
+	}()
+	if !changed {
+		return
+	}
+	backend.log.WithField("status", status).Info("Keystore status changed")
+	backend.Notify(observable.Event{
+		Subject: "keystore",
+		Action:  action.Replace,
+		Object:  status,
+	})
+}
@@ -0,0 +1,108 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"testing"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/devices/device"
+	deviceevent "github.com/digitalbitbox/bitbox-wallet-app/backend/devices/device/event"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/keystore"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/observable"
+	"github.com/stretchr/testify/require"
+)
+
+// testKeystore is a hardware keystore which does not support any accounts.
+type testKeystore struct {
+	keystore.Keystore
+}
+
+func (testKeystore) Type() keystore.Type                               { return keystore.TypeHardware }
+func (testKeystore) SupportsAccount(coin.Coin, bool, interface{}) bool { return false }
+
+type testDevice struct {
+	observable.Implementation
+	onEvent func(deviceevent.Event, interface{})
+}
+
+func (*testDevice) Init(bool) error     { return nil }
+func (*testDevice) ProductName() string { return "bitbox02" }
+func (*testDevice) Identifier() string  { return "test-device" }
+func (*testDevice) Close()              {}
+func (device *testDevice) SetOnEvent(onEvent func(deviceevent.Event, interface{})) {
+	device.onEvent = onEvent
+}
+func (*testDevice) KeystoreForConfiguration(*signing.Configuration, int) keystore.Keystore {
+	return testKeystore{}
+}
+
+func TestKeystoreStatus(t *testing.T) {
+	backend := newTestBackend(t)
+	defer func() { require.NoError(t, backend.Close()) }()
+
+	var statuses []KeystoreStatus
+	backend.Observe(func(event observable.Event) {
+		if event.Subject == "keystore" {
+			statuses = append(statuses, event.Object.(KeystoreStatus))
+		}
+	})
+	require.Equal(t, KeystoreStatus{}, backend.KeystoreStatus())
+
+	backend.OnDeviceInit(func(device.Interface) {})
+	backend.OnDeviceUninit(func(string) {})
+	theDevice := &testDevice{}
+	require.NoError(t, backend.Register(theDevice))
+	require.Empty(t, statuses)
+
+	connected := KeystoreStatus{
+		Connected:   true,
+		Type:        keystore.TypeHardware,
+		ProductName: "bitbox02",
+		DeviceID:    "test-device",
+	}
+	theDevice.onEvent(deviceevent.EventKeystoreAvailable, nil)
+	require.Equal(t, connected, backend.KeystoreStatus())
+	require.Equal(t, []KeystoreStatus{connected}, statuses)
+
+	// The keystore is reported again without having been gone in between.
+	theDevice.onEvent(deviceevent.EventKeystoreAvailable, nil)
+	require.Equal(t, []KeystoreStatus{connected}, statuses)
+
+	backend.Deregister(theDevice.Identifier())
+	require.Equal(t, KeystoreStatus{}, backend.KeystoreStatus())
+	require.Equal(t, []KeystoreStatus{connected, {}}, statuses)
+}
+
+func TestImportMnemonic(t *testing.T) {
+	backend := newTestBackend(t)
+	defer func() { require.NoError(t, backend.Close()) }()
+	backend.OnAccountInit(func(accounts.Interface) {})
+	backend.OnAccountUninit(func(accounts.Interface) {})
+
+	require.Error(t, backend.ImportMnemonic("abandon abandon abandon", ""))
+	require.Equal(t, 0, backend.keystores.Count())
+	require.Equal(t, KeystoreStatus{}, backend.KeystoreStatus())
+
+	require.NoError(t, backend.ImportMnemonic(
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+		""))
+	require.Equal(t, 1, backend.keystores.Count())
+	require.Equal(t, keystore.TypeSoftware, backend.keystores.Keystores()[0].Type())
+	require.Equal(t,
+		KeystoreStatus{Connected: true, Type: keystore.TypeSoftware}, backend.KeystoreStatus())
+}
@@ -0,0 +1,92 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/observable"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/observable/action"
+)
+
+// lastSyncedFilename is the name of the file in the account files folder in which the time of the
+// last completed sync is persisted.
+const lastSyncedFilename = "last-synced"
+
+// AccountLastSynced returns the time the account with the given code has last completed syncing,
+// or nil if this is not known (yet). The time of the previous session is available as soon as the
+// account starts syncing.
+func (backend *Backend) AccountLastSynced(code string) *time.Time {
+	defer backend.lastSyncedLock.RLock()()
+	lastSynced, ok := backend.lastSynced[code]
+	if !ok {
+		return nil
+	}
+	return &lastSynced
+}
+
+func (backend *Backend) setAccountLastSynced(account accounts.Interface, lastSynced time.Time) {
+	func() {
+		defer backend.lastSyncedLock.Lock()()
+		backend.lastSynced[account.Code()] = lastSynced
+	}()
+	backend.Notify(observable.Event{
+		Subject: fmt.Sprintf("account/%s/last-synced", account.Code()),
+		Action:  action.Replace,
+		Object:  lastSynced,
+	})
+}
+
+// loadAccountLastSynced loads the persisted time of the last completed sync. Must be called after
+// the account has been initialized.
+func (backend *Backend) loadAccountLastSynced(account accounts.Interface) {
+	data, err := ioutil.ReadFile(filepath.Join(account.FilesFolder(), lastSyncedFilename))
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		backend.log.WithError(err).Error("Could not read the last synced time")
+		return
+	}
+	var lastSynced time.Time
+	if err := lastSynced.UnmarshalText(data); err != nil {
+		backend.log.WithError(err).Error("Could not parse the last synced time")
+		return
+	}
+	backend.setAccountLastSynced(account, lastSynced)
+}
+
+// storeAccountLastSynced sets and persists the time of the last completed sync. Must be called
+// after the account has been initialized.
+func (backend *Backend) storeAccountLastSynced(account accounts.Interface, lastSynced time.Time) error {
+	backend.setAccountLastSynced(account, lastSynced)
+	data, err := lastSynced.MarshalText()
+	if err != nil {
+		return errp.WithStack(err)
+	}
+	return errp.WithStack(ioutil.WriteFile(
+		filepath.Join(account.FilesFolder(), lastSyncedFilename), data, 0600))
+}
+
+func (backend *Backend) clearAccountLastSynced(code string) {
+	defer backend.lastSyncedLock.Lock()()
+	delete(backend.lastSynced, code)
+}
@@ -0,0 +1,224 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+const (
+	// PortfolioFormatCSV exports the portfolio as CSV, one row per balance and transaction.
+	PortfolioFormatCSV = "csv"
+	// PortfolioFormatJSON exports the portfolio as JSON.
+	PortfolioFormatJSON = "json"
+)
+
+type portfolioTransaction struct {
+	Time      string   `json:"time"`
+	Type      string   `json:"type"`
+	Amount    string   `json:"amount"`
+	Fee       string   `json:"fee"`
+	Addresses []string `json:"addresses"`
+	TxID      string   `json:"txID"`
+}
+
+type portfolioAccount struct {
+	CoinCode     string                  `json:"coinCode"`
+	Code         string                  `json:"code"`
+	Name         string                  `json:"name"`
+	Unit         string                  `json:"unit"`
+	Balance      string                  `json:"balance"`
+	BalanceFiat  string                  `json:"balanceFiat"`
+	Transactions []*portfolioTransaction `json:"transactions"`
+}
+
+// portfolioFiat returns the fiat currency selected in the frontend, USD by default.
+func (backend *Backend) portfolioFiat() string {
+	if frontend, ok := backend.config.AppConfig().Frontend.(map[string]interface{}); ok {
+		if fiatCode, ok := frontend["fiatCode"].(string); ok && fiatCode != "" {
+			return fiatCode
+		}
+	}
+	return "USD"
+}
+
+// ExportPortfolio writes the balances and transactions of all accounts to the writer, in the given
+// format (see the PortfolioFormat* constants). Balances are converted to the fiat currency
+// selected in the frontend. The accounts are written one by one, so the export does not have to be
+// kept in memory as a whole.
+func (backend *Backend) ExportPortfolio(writer io.Writer, format string) error {
+	// The accounts lock is not held during the export, as it waits for the accounts to be synced.
+	accountsList := func() []accounts.Interface {
+		defer backend.accountsLock.RLock()()
+		return append([]accounts.Interface{}, backend.accounts...)
+	}()
+	return exportPortfolio(
+		writer, format, accountsList, backend.portfolioFiat(), backend.ratesUpdater.Last())
+}
+
+func newPortfolioAccount(
+	account accounts.Interface, fiat string, rates map[string]map[string]float64,
+) (*portfolioAccount, error) {
+	if err := account.Initialize(); err != nil {
+		return nil, err
+	}
+	accountCoin := account.Coin()
+	balance, err := account.Balance()
+	if err != nil {
+		return nil, err
+	}
+	// The fiat balance is left empty if there is no exchange rate yet.
+	balanceFiat, _ := coin.ConvertToFiat(balance.Available(), accountCoin, false, fiat, rates)
+	result := &portfolioAccount{
+		CoinCode:     accountCoin.Code(),
+		Code:         account.Code(),
+		Name:         account.Name(),
+		Unit:         accountCoin.Unit(false),
+		Balance:      accountCoin.FormatAmount(balance.Available(), false),
+		BalanceFiat:  balanceFiat,
+		Transactions: []*portfolioTransaction{},
+	}
+	transactions, err := account.Transactions()
+	if err != nil {
+		return nil, err
+	}
+	for _, transaction := range transactions {
+		portfolioTx := &portfolioTransaction{
+			Type: map[accounts.TxType]string{
+				accounts.TxTypeReceive:  "received",
+				accounts.TxTypeSend:     "sent",
+				accounts.TxTypeSendSelf: "sent_to_yourself",
+			}[transaction.Type()],
+			Amount:    accountCoin.FormatAmount(transaction.Amount(), false),
+			Addresses: []string{},
+			TxID:      transaction.TxID(),
+		}
+		if timestamp := transaction.Timestamp(); timestamp != nil {
+			portfolioTx.Time = timestamp.Format(time.RFC3339)
+		}
+		if fee := transaction.Fee(); fee != nil {
+			portfolioTx.Fee = accountCoin.FormatAmount(*fee, true)
+		}
+		for _, addressAndAmount := range transaction.Addresses() {
+			portfolioTx.Addresses = append(portfolioTx.Addresses, addressAndAmount.Address)
+		}
+		result.Transactions = append(result.Transactions, portfolioTx)
+	}
+	return result, nil
+}
+
+func exportPortfolio(
+	writer io.Writer,
+	format string,
+	accountsList []accounts.Interface,
+	fiat string,
+	rates map[string]map[string]float64,
+) error {
+	switch format {
+	case PortfolioFormatCSV:
+		csvWriter := csv.NewWriter(writer)
+		err := csvWriter.Write([]string{
+			"Account",
+			"Coin",
+			"Record",
+			"Time",
+			"Type",
+			"Amount",
+			"Unit",
+			"Fiat Amount",
+			"Fiat",
+			"Fee",
+			"Address",
+			"Transaction ID",
+		})
+		if err != nil {
+			return errp.WithStack(err)
+		}
+		for _, account := range accountsList {
+			if account.FatalError() {
+				continue
+			}
+			portfolioAccount, err := newPortfolioAccount(account, fiat, rates)
+			if err != nil {
+				return err
+			}
+			err = csvWriter.Write([]string{
+				portfolioAccount.Name, portfolioAccount.CoinCode, "balance", "", "",
+				portfolioAccount.Balance, portfolioAccount.Unit, portfolioAccount.BalanceFiat, fiat,
+				"", "", "",
+			})
+			if err != nil {
+				return errp.WithStack(err)
+			}
+			for _, transaction := range portfolioAccount.Transactions {
+				err := csvWriter.Write([]string{
+					portfolioAccount.Name, portfolioAccount.CoinCode, "transaction", transaction.Time,
+					transaction.Type, transaction.Amount, portfolioAccount.Unit, "", "",
+					transaction.Fee, strings.Join(transaction.Addresses, "; "), transaction.TxID,
+				})
+				if err != nil {
+					return errp.WithStack(err)
+				}
+			}
+			csvWriter.Flush()
+			if err := csvWriter.Error(); err != nil {
+				return errp.WithStack(err)
+			}
+		}
+		csvWriter.Flush()
+		return errp.WithStack(csvWriter.Error())
+	case PortfolioFormatJSON:
+		fiatJSON, err := json.Marshal(fiat)
+		if err != nil {
+			return errp.WithStack(err)
+		}
+		if _, err := io.WriteString(writer, `{"fiat":`+string(fiatJSON)+`,"accounts":[`); err != nil {
+			return errp.WithStack(err)
+		}
+		first := true
+		for _, account := range accountsList {
+			if account.FatalError() {
+				continue
+			}
+			portfolioAccount, err := newPortfolioAccount(account, fiat, rates)
+			if err != nil {
+				return err
+			}
+			accountJSON, err := json.Marshal(portfolioAccount)
+			if err != nil {
+				return errp.WithStack(err)
+			}
+			if !first {
+				accountJSON = append([]byte(","), accountJSON...)
+			}
+			first = false
+			if _, err := writer.Write(accountJSON); err != nil {
+				return errp.WithStack(err)
+			}
+		}
+		_, err = io.WriteString(writer, "]}")
+		return errp.WithStack(err)
+	default:
+		return errp.Newf("unknown export format %s", format)
+	}
+}
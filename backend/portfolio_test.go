@@ -0,0 +1,232 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/stretchr/testify/require"
+)
+
+type portfolioTestTransaction struct {
+	accounts.Transaction
+	txID      string
+	txType    accounts.TxType
+	amount    int64
+	fee       int64
+	timestamp time.Time
+	address   string
+}
+
+func (tx *portfolioTestTransaction) TxID() string          { return tx.txID }
+func (tx *portfolioTestTransaction) Type() accounts.TxType { return tx.txType }
+func (tx *portfolioTestTransaction) Timestamp() *time.Time { return &tx.timestamp }
+func (tx *portfolioTestTransaction) Amount() coin.Amount   { return coin.NewAmountFromInt64(tx.amount) }
+func (tx *portfolioTestTransaction) Fee() *coin.Amount {
+	fee := coin.NewAmountFromInt64(tx.fee)
+	return &fee
+}
+func (tx *portfolioTestTransaction) Addresses() []accounts.AddressAndAmount {
+	return []accounts.AddressAndAmount{{Address: tx.address, Amount: coin.NewAmountFromInt64(tx.amount)}}
+}
+
+type portfolioTestAccount struct {
+	accounts.Interface
+	coin         coin.Coin
+	code         string
+	name         string
+	balance      int64
+	transactions []accounts.Transaction
+	fatalError   bool
+	// synced, if not nil, blocks Balance() until it is closed, like an account which is syncing.
+	// waiting is closed when Balance() starts to wait.
+	synced  chan struct{}
+	waiting chan struct{}
+}
+
+func (account *portfolioTestAccount) Coin() coin.Coin   { return account.coin }
+func (account *portfolioTestAccount) Code() string      { return account.code }
+func (account *portfolioTestAccount) Name() string      { return account.name }
+func (account *portfolioTestAccount) Initialize() error { return nil }
+func (account *portfolioTestAccount) FatalError() bool  { return account.fatalError }
+func (account *portfolioTestAccount) Balance() (*accounts.Balance, error) {
+	if account.synced != nil {
+		close(account.waiting)
+		<-account.synced
+	}
+	return accounts.NewBalance(coin.NewAmountFromInt64(account.balance), coin.NewAmount(big.NewInt(0))), nil
+}
+func (account *portfolioTestAccount) Transactions() ([]accounts.Transaction, error) {
+	return account.transactions, nil
+}
+
+func portfolioTestAccounts(t *testing.T, backend *Backend) []accounts.Interface {
+	t.Helper()
+	btcCoin, err := backend.Coin(coinTBTC)
+	require.NoError(t, err)
+	ltcCoin, err := backend.Coin(coinTLTC)
+	require.NoError(t, err)
+	timestamp := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	return []accounts.Interface{
+		&portfolioTestAccount{
+			coin:    btcCoin,
+			code:    "tbtc-p2wpkh",
+			name:    "Bitcoin",
+			balance: 150000000,
+			transactions: []accounts.Transaction{
+				&portfolioTestTransaction{
+					txID: "txid1", txType: accounts.TxTypeReceive, amount: 200000000,
+					timestamp: timestamp, address: "address1",
+				},
+				&portfolioTestTransaction{
+					txID: "txid2", txType: accounts.TxTypeSend, amount: 50000000, fee: 1000,
+					timestamp: timestamp.Add(time.Hour), address: "address2",
+				},
+			},
+		},
+		&portfolioTestAccount{coin: ltcCoin, code: "broken", name: "Broken", fatalError: true},
+		&portfolioTestAccount{
+			coin:    ltcCoin,
+			code:    "tltc-p2wpkh",
+			name:    "Litecoin",
+			balance: 100000000,
+			transactions: []accounts.Transaction{
+				&portfolioTestTransaction{
+					txID: "txid3", txType: accounts.TxTypeReceive, amount: 100000000,
+					timestamp: timestamp, address: "address3",
+				},
+			},
+		},
+	}
+}
+
+var portfolioTestRates = map[string]map[string]float64{
+	"BTC": {"USD": 10000, "CHF": 9000},
+	"LTC": {"USD": 50},
+}
+
+func TestExportPortfolioCSV(t *testing.T) {
+	backend := newTestBackend(t)
+	defer func() { require.NoError(t, backend.Close()) }()
+
+	var buf bytes.Buffer
+	require.NoError(t, exportPortfolio(
+		&buf, PortfolioFormatCSV, portfolioTestAccounts(t, backend), "CHF", portfolioTestRates))
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 6)
+	require.Equal(t, "Account", records[0][0])
+	require.Equal(t,
+		[]string{"Bitcoin", "tbtc", "balance", "", "", "1.5", "TBTC", "13'500.00", "CHF", "", "", ""},
+		records[1])
+	require.Equal(t,
+		[]string{"Bitcoin", "tbtc", "transaction", "2020-05-01T12:00:00Z", "received", "2", "TBTC",
+			"", "", "0", "address1", "txid1"},
+		records[2])
+	require.Equal(t,
+		[]string{"Bitcoin", "tbtc", "transaction", "2020-05-01T13:00:00Z", "sent", "0.5", "TBTC",
+			"", "", "0.00001", "address2", "txid2"},
+		records[3])
+	// No CHF rate for LTC.
+	require.Equal(t,
+		[]string{"Litecoin", "tltc", "balance", "", "", "1", "TLTC", "", "CHF", "", "", ""},
+		records[4])
+	require.Equal(t, "txid3", records[5][11])
+
+	require.Error(t, exportPortfolio(&buf, "xml", nil, "CHF", portfolioTestRates))
+}
+
+func TestExportPortfolioJSON(t *testing.T) {
+	backend := newTestBackend(t)
+	defer func() { require.NoError(t, backend.Close()) }()
+
+	var buf bytes.Buffer
+	require.NoError(t, exportPortfolio(
+		&buf, PortfolioFormatJSON, portfolioTestAccounts(t, backend), "USD", portfolioTestRates))
+	var result struct {
+		Fiat     string              `json:"fiat"`
+		Accounts []*portfolioAccount `json:"accounts"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &result))
+	require.Equal(t, "USD", result.Fiat)
+	require.Len(t, result.Accounts, 2)
+
+	btcAccount := result.Accounts[0]
+	require.Equal(t, "tbtc-p2wpkh", btcAccount.Code)
+	require.Equal(t, "1.5", btcAccount.Balance)
+	require.Equal(t, "15'000.00", btcAccount.BalanceFiat)
+	require.Len(t, btcAccount.Transactions, 2)
+	require.Equal(t, &portfolioTransaction{
+		Time:      "2020-05-01T13:00:00Z",
+		Type:      "sent",
+		Amount:    "0.5",
+		Fee:       "0.00001",
+		Addresses: []string{"address2"},
+		TxID:      "txid2",
+	}, btcAccount.Transactions[1])
+
+	ltcAccount := result.Accounts[1]
+	require.Equal(t, "tltc-p2wpkh", ltcAccount.Code)
+	require.Equal(t, "50.00", ltcAccount.BalanceFiat)
+	require.Len(t, ltcAccount.Transactions, 1)
+
+	buf.Reset()
+	require.NoError(t, exportPortfolio(&buf, PortfolioFormatJSON, nil, "USD", portfolioTestRates))
+	require.JSONEq(t, `{"fiat":"USD","accounts":[]}`, buf.String())
+}
+
+// TestExportPortfolioAccountsLock checks that the accounts can be changed while the export waits
+// for an account to be synced.
+func TestExportPortfolioAccountsLock(t *testing.T) {
+	backend := newTestBackend(t)
+	defer func() { require.NoError(t, backend.Close()) }()
+
+	btcCoin, err := backend.Coin(coinTBTC)
+	require.NoError(t, err)
+	account := &portfolioTestAccount{
+		coin: btcCoin, code: "tbtc-p2wpkh", name: "Bitcoin",
+		synced: make(chan struct{}), waiting: make(chan struct{}),
+	}
+	backend.accounts = []accounts.Interface{account}
+	defer func() { backend.accounts = []accounts.Interface{} }()
+
+	exported := make(chan error)
+	var buf bytes.Buffer
+	go func() { exported <- backend.ExportPortfolio(&buf, PortfolioFormatJSON) }()
+	<-account.waiting
+
+	locked := make(chan struct{})
+	go func() {
+		unlock := backend.accountsLock.Lock()
+		unlock()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+	case <-time.After(5 * time.Second):
+		close(account.synced)
+		require.FailNow(t, "the accounts lock is held during the export")
+	}
+	close(account.synced)
+	require.NoError(t, <-exported)
+	require.Contains(t, buf.String(), "tbtc-p2wpkh")
+}
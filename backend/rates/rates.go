@@ -21,9 +21,11 @@ import (
 	"io/ioutil"
 	"reflect"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/davecgh/go-spew/spew"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/logging"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/observable"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/observable/action"
@@ -36,6 +38,11 @@ var fiats = []string{"USD", "EUR", "CHF", "GBP", "JPY", "KRW", "CNY", "RUB", "CA
 
 const interval = time.Minute
 const cryptoCompareURL = "https://min-api.cryptocompare.com/data/pricemulti?fsyms=%s&tsyms=%s"
+const cryptoCompareHistoricalURL = "https://min-api.cryptocompare.com/data/pricehistorical?fsym=%s&tsyms=%s&ts=%d"
+
+// historicalRateTimeout is how long to wait for a historical rate, so a report which needs many of
+// them does not hang if the server does not respond.
+const historicalRateTimeout = 30 * time.Second
 
 // RateUpdater implements coin.RateUpdater.
 type RateUpdater struct {
@@ -43,6 +50,13 @@ type RateUpdater struct {
 	last       map[string]map[string]float64
 	log        *logrus.Entry
 	socksProxy socksproxy.SocksProxy
+
+	// historical caches the daily rates fetched by HistoricalRate(), keyed by unit, fiat and day.
+	historical     map[string]float64
+	historicalLock sync.Mutex
+
+	quit     chan struct{}
+	stopOnce sync.Once
 }
 
 // NewRateUpdater returns a new rates updater.
@@ -51,16 +65,72 @@ func NewRateUpdater(socksProxy socksproxy.SocksProxy) *RateUpdater {
 		last:       map[string]map[string]float64{},
 		log:        logging.Get().WithGroup("rates"),
 		socksProxy: socksProxy,
+		historical: map[string]float64{},
+		quit:       make(chan struct{}),
 	}
 	go ratesUpdater.start()
 	return ratesUpdater
 }
 
+// SupportedFiats returns the fiat currencies for which exchange rates are fetched.
+func SupportedFiats() []string {
+	return append([]string{}, fiats...)
+}
+
 // Last returns the last rates for a given coin and fiat or nil if not available.
 func (updater *RateUpdater) Last() map[string]map[string]float64 {
 	return updater.last
 }
 
+// HistoricalRate returns the exchange rate of a coin unit (e.g. "BTC") in the fiat currency at the
+// given time. The rates are daily closing rates. Fetched rates of past days are cached, as they
+// don't change anymore. The rate of the current day is not final yet and is fetched every time.
+func (updater *RateUpdater) HistoricalRate(unit string, fiat string, t time.Time) (float64, error) {
+	day := t.UTC().Truncate(24 * time.Hour)
+	key := fmt.Sprintf("%s/%s/%d", unit, fiat, day.Unix())
+	updater.historicalLock.Lock()
+	rate, ok := updater.historical[key]
+	updater.historicalLock.Unlock()
+	if ok {
+		return rate, nil
+	}
+
+	client, err := updater.socksProxy.GetHTTPClient()
+	if err != nil {
+		return 0, err
+	}
+	client.Timeout = historicalRateTimeout
+	response, err := client.Get(fmt.Sprintf(cryptoCompareHistoricalURL, unit, fiat, day.Unix()))
+	if err != nil {
+		return 0, errp.WithStack(err)
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	const max = 10240
+	responseBody, err := ioutil.ReadAll(io.LimitReader(response.Body, max+1))
+	if err != nil {
+		return 0, errp.WithStack(err)
+	}
+	if len(responseBody) > max {
+		return 0, errp.Newf("historical rates response too long (> %d bytes)", max)
+	}
+	var rates map[string]map[string]float64
+	if err := json.Unmarshal(responseBody, &rates); err != nil {
+		return 0, errp.Newf("could not parse historical rates response: %s", string(responseBody))
+	}
+	rate, ok = rates[unit][fiat]
+	if !ok {
+		return 0, errp.Newf("no historical rate for %s/%s at %s", unit, fiat, day.Format("2006-01-02"))
+	}
+	if day.Before(time.Now().UTC().Truncate(24 * time.Hour)) {
+		updater.historicalLock.Lock()
+		updater.historical[key] = rate
+		updater.historicalLock.Unlock()
+	}
+	return rate, nil
+}
+
 func (updater *RateUpdater) update() {
 	client, err := updater.socksProxy.GetHTTPClient()
 	if err != nil {
@@ -116,6 +186,15 @@ func (updater *RateUpdater) update() {
 func (updater *RateUpdater) start() {
 	for {
 		updater.update()
-		time.Sleep(interval)
+		select {
+		case <-updater.quit:
+			return
+		case <-time.After(interval):
+		}
 	}
 }
+
+// Stop stops fetching new rates. Calling it again has no effect.
+func (updater *RateUpdater) Stop() {
+	updater.stopOnce.Do(func() { close(updater.quit) })
+}
@@ -0,0 +1,115 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+)
+
+// hasSmallBalance returns true if the available balance of the account is worth less than the
+// threshold in the given fiat currency. Accounts which are not synced yet or for which there is no
+// exchange rate are never considered small, so that they are not hidden by mistake.
+func hasSmallBalance(
+	account accounts.Interface,
+	threshold float64,
+	fiat string,
+	rates map[string]map[string]float64,
+) bool {
+	if threshold <= 0 || !account.Initialized() || account.FatalError() || account.SyncProgress() < 1 {
+		return false
+	}
+	balance, err := account.Balance()
+	if err != nil {
+		return false
+	}
+	value, err := coin.FiatValue(balance.Available(), account.Coin(), false, fiat, rates)
+	if err != nil {
+		return false
+	}
+	return value < threshold
+}
+
+// AccountHidden returns true if the account is hidden from the sidebar because its balance is
+// below the configured threshold, see config.Backend.HideSmallBalancesThreshold. Hidden accounts
+// are still loaded and listed, and can be accessed by their code. The result is the state the
+// frontend was last notified about, see updateAccountsHidden().
+func (backend *Backend) AccountHidden(account accounts.Interface) bool {
+	defer backend.accountsLock.RLock()()
+	return backend.accountsHidden[account.Code()]
+}
+
+// UpdateAccountsHidden updates which accounts are hidden with the latest rates. Call it after the
+// app config changed.
+func (backend *Backend) UpdateAccountsHidden() {
+	backend.updateAccountsHidden(backend.ratesUpdater.Last())
+}
+
+// AccountInTotals returns true if the balance of the account counts towards the portfolio totals.
+// Hidden accounts are excluded only if config.Backend.HideSmallBalancesInTotals is set.
+func (backend *Backend) AccountInTotals(account accounts.Interface) bool {
+	return backend.accountInTotals(account, backend.ratesUpdater.Last())
+}
+
+func (backend *Backend) accountHidden(
+	account accounts.Interface, rates map[string]map[string]float64) bool {
+	return hasSmallBalance(
+		account,
+		backend.config.AppConfig().Backend.HideSmallBalancesThreshold,
+		backend.portfolioFiat(),
+		rates,
+	)
+}
+
+// updateAccountsHidden determines which accounts are hidden with the given rates. If this changed,
+// the frontend is told to reload the accounts. It is called whenever an account finished syncing
+// or the rates changed.
+func (backend *Backend) updateAccountsHidden(rates map[string]map[string]float64) {
+	var loadedAccounts []accounts.Interface
+	func() {
+		defer backend.accountsLock.RLock()()
+		loadedAccounts = append(loadedAccounts, backend.accounts...)
+	}()
+	// Computed without holding the lock, as fetching the balance can block.
+	hidden := make(map[string]bool, len(loadedAccounts))
+	for _, account := range loadedAccounts {
+		hidden[account.Code()] = backend.accountHidden(account, rates)
+	}
+	changed := false
+	func() {
+		defer backend.accountsLock.Lock()()
+		for code, accountHidden := range hidden {
+			// Skip accounts which were removed in the meantime.
+			if _, ok := backend.accountsMetadata[code]; !ok {
+				continue
+			}
+			if backend.accountsHidden[code] != accountHidden {
+				backend.accountsHidden[code] = accountHidden
+				changed = true
+			}
+		}
+	}()
+	if changed {
+		backend.emitAccountsStatusChanged()
+	}
+}
+
+func (backend *Backend) accountInTotals(
+	account accounts.Interface, rates map[string]map[string]float64) bool {
+	if !backend.config.AppConfig().Backend.HideSmallBalancesInTotals {
+		return true
+	}
+	return !backend.accountHidden(account, rates)
+}
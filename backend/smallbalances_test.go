@@ -0,0 +1,127 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"testing"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/observable"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/observable/action"
+	"github.com/stretchr/testify/require"
+)
+
+type smallBalanceTestAccount struct {
+	portfolioTestAccount
+	syncProgress float64
+}
+
+func (account *smallBalanceTestAccount) Initialized() bool     { return true }
+func (account *smallBalanceTestAccount) SyncProgress() float64 { return account.syncProgress }
+
+func TestSmallBalances(t *testing.T) {
+	backend := newTestBackend(t)
+	defer func() { require.NoError(t, backend.Close()) }()
+	ltcCoin, err := backend.Coin(coinTLTC)
+	require.NoError(t, err)
+
+	// 0.1 LTC, worth 5 USD.
+	dust := &smallBalanceTestAccount{
+		portfolioTestAccount: portfolioTestAccount{coin: ltcCoin, code: "dust", balance: 10000000},
+		syncProgress:         1,
+	}
+	// 1 LTC, worth 50 USD.
+	large := &smallBalanceTestAccount{
+		portfolioTestAccount: portfolioTestAccount{coin: ltcCoin, code: "large", balance: 100000000},
+		syncProgress:         1,
+	}
+	syncing := &smallBalanceTestAccount{
+		portfolioTestAccount: portfolioTestAccount{coin: ltcCoin, code: "syncing"},
+		syncProgress:         0.5,
+	}
+
+	// Nothing is hidden by default.
+	for _, account := range []*smallBalanceTestAccount{dust, large, syncing} {
+		require.False(t, backend.accountHidden(account, portfolioTestRates))
+		require.True(t, backend.accountInTotals(account, portfolioTestRates))
+	}
+
+	appConfig := backend.config.AppConfig()
+	appConfig.Backend.HideSmallBalancesThreshold = 10
+	require.NoError(t, backend.config.SetAppConfig(appConfig))
+	require.True(t, backend.accountHidden(dust, portfolioTestRates))
+	require.False(t, backend.accountHidden(large, portfolioTestRates))
+	// Not hidden while syncing or without an exchange rate.
+	require.False(t, backend.accountHidden(syncing, portfolioTestRates))
+	require.False(t, backend.accountHidden(dust, nil))
+	// Hidden accounts still count towards the totals unless configured otherwise.
+	require.True(t, backend.accountInTotals(dust, portfolioTestRates))
+
+	appConfig.Backend.HideSmallBalancesInTotals = true
+	require.NoError(t, backend.config.SetAppConfig(appConfig))
+	require.False(t, backend.accountInTotals(dust, portfolioTestRates))
+	require.True(t, backend.accountInTotals(large, portfolioTestRates))
+}
+
+func TestUpdateAccountsHidden(t *testing.T) {
+	backend := newTestBackend(t)
+	defer func() { require.NoError(t, backend.Close()) }()
+	backend.OnAccountInit(func(accounts.Interface) {})
+	ltcCoin, err := backend.Coin(coinTLTC)
+	require.NoError(t, err)
+
+	dust := &smallBalanceTestAccount{
+		portfolioTestAccount: portfolioTestAccount{coin: ltcCoin, code: "dust", balance: 10000000},
+		syncProgress:         1,
+	}
+	large := &smallBalanceTestAccount{
+		portfolioTestAccount: portfolioTestAccount{coin: ltcCoin, code: "large", balance: 100000000},
+		syncProgress:         1,
+	}
+	backend.addAccount(dust)
+	backend.addAccount(large)
+	defer func() { backend.accounts = []accounts.Interface{} }()
+
+	var events []observable.Event
+	backend.Observe(func(event observable.Event) {
+		if event.Subject == "accounts" {
+			events = append(events, event)
+		}
+	})
+
+	// Nothing is hidden by default.
+	backend.updateAccountsHidden(portfolioTestRates)
+	require.Empty(t, events)
+	require.False(t, backend.AccountHidden(dust))
+
+	appConfig := backend.config.AppConfig()
+	appConfig.Backend.HideSmallBalancesThreshold = 10
+	require.NoError(t, backend.config.SetAppConfig(appConfig))
+	backend.updateAccountsHidden(portfolioTestRates)
+	require.Len(t, events, 1)
+	require.Equal(t, action.Reload, events[0].Action)
+	require.True(t, backend.AccountHidden(dust))
+	require.False(t, backend.AccountHidden(large))
+
+	// No event if nothing changed.
+	events = nil
+	backend.updateAccountsHidden(portfolioTestRates)
+	require.Empty(t, events)
+
+	// Without rates, nothing is hidden.
+	backend.updateAccountsHidden(nil)
+	require.Len(t, events, 1)
+	require.False(t, backend.AccountHidden(dust))
+}
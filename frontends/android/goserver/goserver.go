@@ -19,6 +19,7 @@ import (
 	"log"
 	"sync"
 
+	"github.com/digitalbitbox/bitbox-wallet-app/backend"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/bridgecommon"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/devices/usb"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/config"
@@ -28,6 +29,10 @@ import (
 
 var (
 	once sync.Once
+
+	// buildCommit and buildDate are set at build time using `-ldflags -X`, see the Makefile.
+	buildCommit = "unknown"
+	buildDate   = "unknown"
 )
 
 // the Go*-named interfaces are implemented in Java for the mobile client. The "Go" prefix is so
@@ -107,6 +112,28 @@ func BackendCall(queryID int, jsonQuery string) {
 	bridgecommon.BackendCall(queryID, jsonQuery)
 }
 
+// Version returns the version of the backend as displayed to the user. It is the same version the
+// desktop app reports.
+func Version() string {
+	return backend.Version.String()
+}
+
+// Build holds information about the build of this library.
+type Build struct {
+	Version string
+	Commit  string
+	Date    string
+}
+
+// BuildInfo returns the version, as well as the commit and date this library was built from.
+func BuildInfo() *Build {
+	return &Build{
+		Version: Version(),
+		Commit:  buildCommit,
+		Date:    buildDate,
+	}
+}
+
 // UsingMobileDataChanged exposes `bridgecommon.UsingMobileDataChanged` to Java/Kotlin.
 func UsingMobileDataChanged() {
 	bridgecommon.UsingMobileDataChanged()
@@ -0,0 +1,33 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package goserver_test
+
+import (
+	"testing"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend"
+	"github.com/digitalbitbox/bitbox-wallet-app/frontends/android/goserver"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersion(t *testing.T) {
+	require.NotEmpty(t, goserver.Version())
+	require.Equal(t, backend.Version.String(), goserver.Version())
+
+	buildInfo := goserver.BuildInfo()
+	require.Equal(t, goserver.Version(), buildInfo.Version)
+	require.NotEmpty(t, buildInfo.Commit)
+	require.NotEmpty(t, buildInfo.Date)
+}
@@ -234,7 +234,7 @@ class Sidebar extends Component<Props> {
                             </Link>
                         </div>
                     }
-                    { accounts && accounts.map(this.getAccountLink) }
+                    { accounts && accounts.filter(account => !account.hidden).map(this.getAccountLink) }
                     <div className="sidebarHeaderContainer end">
                         <span className="sidebarHeader">{t('sidebar.settings')}</span>
                     </div>
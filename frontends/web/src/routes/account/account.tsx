@@ -38,6 +38,8 @@ export interface AccountInterface {
     code: string;
     name: string;
     blockExplorerTxPrefix: string;
+    // Accounts with a small balance are hidden from the sidebar, but can still be opened.
+    hidden: boolean;
 }
 
 interface AccountProps {
@@ -285,7 +285,8 @@ class Send extends Component<Props, State> {
                 const errorCode = result.errorCode;
                 switch (errorCode) {
                     case 'invalidAddress':
-                        this.setState({ addressError: this.props.t('send.error.invalidAddress') });
+                    case 'mwebNotSupported':
+                        this.setState({ addressError: this.props.t(`send.error.${errorCode}`) });
                         break;
                     case 'invalidAmount':
                     case 'insufficientFunds':
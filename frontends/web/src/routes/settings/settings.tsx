@@ -17,6 +17,7 @@
 import { Component, h, RenderableProps } from 'preact';
 import { Link, route } from 'preact-router';
 import { Badge } from '../../components/badge/badge';
+import { alertUser } from '../../components/alert/Alert';
 import { Dialog } from '../../components/dialog/dialog';
 import * as dialogStyle from '../../components/dialog/dialog.css';
 import { FiatSelection } from '../../components/fiat/fiat';
@@ -173,6 +174,21 @@ class Settings extends Component<Props, State> {
     }
 
     private setProxyConfig = proxyConfig => {
+        if (!proxyConfig.useProxy) {
+            this.saveProxyConfig(proxyConfig);
+            return;
+        }
+        // All connections go through the proxy once it is enabled, so make sure it can be reached.
+        apiPost('socksproxy/check', proxyConfig.proxyAddress).then(({ success, errorMessage }) => {
+            if (success) {
+                this.saveProxyConfig(proxyConfig);
+            } else {
+                alertUser(this.props.t('settings.expert.proxyCheckFailed') + ':\n' + errorMessage);
+            }
+        });
+    }
+
+    private saveProxyConfig = proxyConfig => {
         setConfig({
             backend: { proxy: proxyConfig },
         }).then(config => {
@@ -186,9 +202,7 @@ class Settings extends Component<Props, State> {
             return;
         }
         const target = (event.target as HTMLInputElement);
-        const proxy = config.backend.proxy;
-        proxy.useProxy = target.checked;
-        this.setProxyConfig(proxy);
+        this.setProxyConfig({ ...config.backend.proxy, useProxy: target.checked });
     }
 
     private setProxyAddress = () => {
@@ -196,9 +210,7 @@ class Settings extends Component<Props, State> {
         if (!config) {
             return;
         }
-        const proxy = config.backend.proxy;
-        proxy.proxyAddress = this.state.proxyAddress;
-        this.setProxyConfig(proxy);
+        this.setProxyConfig({ ...config.backend.proxy, proxyAddress: this.state.proxyAddress });
     }
 
     private showProxyDialog = () => {
@@ -15,10 +15,14 @@
 package socksproxy
 
 import (
+	"bytes"
+	"io"
 	"net"
 	"net/http"
 	"net/url"
+	"time"
 
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/logging"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/net/proxy"
@@ -89,3 +93,31 @@ func (socksProxy *SocksProxy) GetHTTPClient() (*http.Client, error) {
 	socksProxy.log.Info("Using an unproxied http connection")
 	return &http.Client{}, nil
 }
+
+// checkProxyTimeout is the timeout for connecting to and talking to a proxy in CheckProxy().
+const checkProxyTimeout = 10 * time.Second
+
+// CheckProxy checks that a SOCKS5 proxy is reachable at the given address and accepts connections
+// without authentication, as required by GetTCPProxyDialer() and GetHTTPClient().
+func CheckProxy(proxyAddress string) error {
+	conn, err := net.DialTimeout("tcp", proxyAddress, checkProxyTimeout)
+	if err != nil {
+		return errp.WithStack(err)
+	}
+	defer func() { _ = conn.Close() }()
+	if err := conn.SetDeadline(time.Now().Add(checkProxyTimeout)); err != nil {
+		return errp.WithStack(err)
+	}
+	// SOCKS5 greeting offering only the "no authentication" method, see RFC 1928.
+	if _, err := conn.Write([]byte{0x05, 0x01, 0x00}); err != nil {
+		return errp.WithStack(err)
+	}
+	response := make([]byte, 2)
+	if _, err := io.ReadFull(conn, response); err != nil {
+		return errp.WithMessage(err, "no response from the proxy")
+	}
+	if !bytes.Equal(response, []byte{0x05, 0x00}) {
+		return errp.Newf("%s is not a SOCKS5 proxy without authentication", proxyAddress)
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package socksproxy
+
+import (
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testProxy is a minimal SOCKS5 proxy without authentication, which only supports CONNECT.
+type testProxy struct {
+	listener    net.Listener
+	connections int32
+}
+
+func newTestProxy(t *testing.T) *testProxy {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	proxy := &testProxy{listener: listener}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(&proxy.connections, 1)
+			go proxy.serve(conn)
+		}
+	}()
+	return proxy
+}
+
+func (proxy *testProxy) serve(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return
+	}
+	if _, err := io.ReadFull(conn, make([]byte, header[1])); err != nil {
+		return
+	}
+	if _, err := conn.Write([]byte{0x05, 0x00}); err != nil {
+		return
+	}
+	request := make([]byte, 4)
+	if _, err := io.ReadFull(conn, request); err != nil {
+		return
+	}
+	var host string
+	switch request[3] {
+	case 0x01:
+		ip := make([]byte, net.IPv4len)
+		if _, err := io.ReadFull(conn, ip); err != nil {
+			return
+		}
+		host = net.IP(ip).String()
+	case 0x03:
+		length := make([]byte, 1)
+		if _, err := io.ReadFull(conn, length); err != nil {
+			return
+		}
+		name := make([]byte, length[0])
+		if _, err := io.ReadFull(conn, name); err != nil {
+			return
+		}
+		host = string(name)
+	default:
+		return
+	}
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(conn, port); err != nil {
+		return
+	}
+	target, err := net.Dial("tcp", net.JoinHostPort(
+		host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))))
+	if err != nil {
+		return
+	}
+	defer func() { _ = target.Close() }()
+	if _, err := conn.Write([]byte{0x05, 0x00, 0x00, 0x01, 0, 0, 0, 0, 0, 0}); err != nil {
+		return
+	}
+	go func() { _, _ = io.Copy(target, conn) }()
+	_, _ = io.Copy(conn, target)
+}
+
+func (proxy *testProxy) address() string {
+	return proxy.listener.Addr().String()
+}
+
+func (proxy *testProxy) close() {
+	_ = proxy.listener.Close()
+}
+
+func TestProxiedConnections(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+	proxy := newTestProxy(t)
+	defer proxy.close()
+
+	socksProxy := NewSocksProxy(true, proxy.address())
+	client, err := socksProxy.GetHTTPClient()
+	require.NoError(t, err)
+	response, err := client.Get(server.URL)
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(response.Body)
+	require.NoError(t, err)
+	require.NoError(t, response.Body.Close())
+	require.Equal(t, "hello", string(body))
+	require.Equal(t, int32(1), atomic.LoadInt32(&proxy.connections))
+
+	conn, err := socksProxy.GetTCPProxyDialer().Dial("tcp", server.Listener.Addr().String())
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+	require.Equal(t, int32(2), atomic.LoadInt32(&proxy.connections))
+
+	// Not proxied if disabled.
+	socksProxy = NewSocksProxy(false, proxy.address())
+	client, err = socksProxy.GetHTTPClient()
+	require.NoError(t, err)
+	response, err = client.Get(server.URL)
+	require.NoError(t, err)
+	require.NoError(t, response.Body.Close())
+	require.Equal(t, int32(2), atomic.LoadInt32(&proxy.connections))
+}
+
+func TestCheckProxy(t *testing.T) {
+	proxy := newTestProxy(t)
+	require.NoError(t, CheckProxy(proxy.address()))
+	proxy.close()
+	// Nothing listening anymore.
+	require.Error(t, CheckProxy(proxy.address()))
+
+	// Not a SOCKS5 proxy.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		_, _ = conn.Write([]byte("SSH-2.0-OpenSSH\r\n"))
+		_ = conn.Close()
+	}()
+	require.Error(t, CheckProxy(listener.Addr().String()))
+}